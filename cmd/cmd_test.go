@@ -1,17 +1,25 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestCmd(t *testing.T) {
+	modifiedMain := "package main\n\nimport \"fmt\"\n\n// Added in the editor.\nfunc main() {\n\tτ := 6.28\n\tfmt.Println(τ)\n}\n"
+
 	testCases := []struct {
 		name           string
 		dir            string
 		args           []string
+		stdin          string
+		env            map[string]string
 		expectedStdout string
 		expectedStderr string
 	}{
@@ -22,6 +30,93 @@ func TestCmd(t *testing.T) {
 			expectedStdout: "localVar localvar.go:6:2\n",
 			expectedStderr: "",
 		},
+		{
+			name:           "inspect utf16 columns",
+			dir:            "../pkg/inspect/testdata/testmodule020",
+			args:           []string{"inspect", "-f", "main.go", "-l", "7", "-c", "17", "--column-unit", "utf16", "-t", "{{range .Relations}}{{.Name}} {{.Line}}:{{.Column}}-{{.End.Line}}:{{.End.Column}}\n{{end}}"},
+			expectedStdout: "𝜋 6:5-6:7\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect rune columns",
+			dir:            "../pkg/inspect/testdata/testmodule020",
+			args:           []string{"inspect", "-f", "main.go", "-l", "7", "-c", "17", "--column-unit", "runes", "-t", "{{range .Relations}}{{.Name}} {{.Line}}:{{.Column}}-{{.End.Line}}:{{.End.Column}}\n{{end}}"},
+			expectedStdout: "𝜋 6:5-6:6\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect modified",
+			dir:            "../pkg/inspect/testdata/testmodule020",
+			args:           []string{"inspect", "-f", "main.go", "-l", "8", "-c", "14", "--modified"},
+			stdin:          fmt.Sprintf("main.go\n%d\n%s", len(modifiedMain), modifiedMain),
+			expectedStdout: "τ main.go:7:2\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect goos",
+			dir:            "../pkg/inspect/testdata/testmodule021",
+			args:           []string{"inspect", "-f", "main.go", "-l", "6", "-c", "14", "--goos", "windows"},
+			expectedStdout: "platformName platform_windows.go:3:6\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect platforms",
+			dir:            "../pkg/inspect/testdata/testmodule021",
+			args:           []string{"inspect", "-f", "main.go", "-l", "6", "-c", "14", "--platforms", "linux/amd64,windows/amd64,darwin/arm64"},
+			expectedStdout: "platformName platform_linux.go:3:6\nplatformName platform_other.go:5:6\nplatformName platform_windows.go:3:6\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect search deps",
+			dir:            "../pkg/inspect/testdata/testmodule023",
+			args:           []string{"inspect", "-f", "main.go", "-l", "9", "-c", "6", "-r", "interface", "--searchDeps"},
+			env:            map[string]string{"GOFLAGS": ""}, // Use the vendor directory even if the environment sets -mod.
+			expectedStdout: "Shape vendor/example.com/shapes/shapes.go:3:6 (example.com/shapes@v1.2.0)\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect universe",
+			dir:            "../pkg/inspect/testdata/testmodule024",
+			args:           []string{"inspect", "-f", "buffer.go", "-l", "3", "-c", "6", "-r", "interface", "--universe", "./iface"},
+			expectedStdout: "Resetter iface/iface.go:3:6 missing Reset()\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect symbol",
+			dir:            "../pkg/inspect/testdata/testmodule017",
+			args:           []string{"inspect", "--symbol", "main.Dog.Legs"},
+			expectedStdout: "Legs animals.go:7:2\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "inspect batch",
+			dir:            "../pkg/inspect/testdata/testmodule017",
+			args:           []string{"inspect", "--batch"},
+			stdin:          "animals.go:7:2\n{\"path\": \"ifaces.go\", \"line\": 9, \"column\": 2}\ninvalid\n",
+			expectedStdout: "Legs animals.go:7:2\nDescribe ifaces.go:9:2\n",
+			expectedStderr: "Error: Invalid location \"invalid\", expected the form path:line:column\n",
+		},
+		{
+			name:  "inspect batch json",
+			dir:   "../pkg/inspect/testdata/testmodule017",
+			args:  []string{"inspect", "--batch", "--format", "json"},
+			stdin: "invalid\n",
+			expectedStdout: `{
+  "schemaVersion": 1,
+  "results": [
+    {
+      "loc": {
+        "path": "",
+        "line": 0,
+        "column": 0
+      },
+      "error": "Invalid location \"invalid\", expected the form path:line:column"
+    }
+  ]
+}
+`,
+			expectedStderr: "",
+		},
 		{
 			name: "list",
 			dir:  "../pkg/list/testdata/testmodule001",
@@ -34,14 +129,132 @@ MyFunc defs.go:21:1
 `,
 			expectedStderr: "",
 		},
+		{
+			name:           "list kinds",
+			dir:            "../pkg/list/testdata/testmodule001",
+			args:           []string{"list", "--kinds", "func,const", "-t", "{{ range .Defs }}{{.Name}} {{.Kind}} {{.Signature}}\n{{end}}", "./..."},
+			expectedStdout: "MyConst const untyped int\nMyFunc func func() string\n",
+			expectedStderr: "",
+		},
+		{
+			name: "outline",
+			dir:  "../pkg/list/testdata/testmodule006",
+			args: []string{"outline", "-f", "outline.go", "-t", "{{ range .Flatten }}{{.Indent}}{{.Name}} {{.Decl.Line}}-{{.Decl.End.Line}}\n{{end}}"},
+			expectedStdout: `Stack 9-12
+  Stack.items 10-10
+  Stack.Name 11-11
+  Stack.Push 5-7
+  Stack.Size 25-27
+const 14-17
+  Small 15-15
+  Large 16-16
+Default 19-19
+Sizer 21-23
+  Sizer.Size 22-22
+Describe 29-40
+  summary 30-32
+    summary.size 31-31
+  format 34-37
+    prefix 35-35
+`,
+			expectedStderr: "",
+		},
+		{
+			name: "rename",
+			dir:  "../pkg/rename/testdata/testmodule001",
+			args: []string{"rename", "-f", "main.go", "-l", "17", "-c", "2", "--to", "length"},
+			expectedStdout: `--- a/main.go
++++ b/main.go
+@@ -14,7 +14,7 @@
+ }
+ 
+ func main() {
+-	size := 2.0
+-	sq := shapes.Square{Side: size}
++	length := 2.0
++	sq := shapes.Square{Side: length}
+ 	fmt.Println(describe(sq), count)
+ }
+`,
+			expectedStderr: "",
+		},
+		{
+			name:           "unused",
+			dir:            "../pkg/unused/testdata/testmodule001",
+			args:           []string{"unused", "./..."},
+			expectedStdout: "Square.Scale shapes/shapes.go:26:1\nLegacy shapes/shapes.go:30:1\n",
+			expectedStderr: "",
+		},
+		{
+			name:           "search",
+			dir:            "../pkg/list/testdata/testmodule001",
+			args:           []string{"search", "myfu"},
+			expectedStdout: "MyFunc defs.go:21:1\n",
+			expectedStderr: "",
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			stdout, stderr, err := ExecuteInTest(tc.args, tc.dir)
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+			t.Cleanup(func() { resetFlags(rootCmd) })
+			stdout, stderr, err := ExecuteInTest(tc.args, tc.dir, tc.stdin)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expectedStdout, stdout)
 			assert.Equal(t, tc.expectedStderr, stderr)
 		})
 	}
 }
+
+// resetSliceValue is a slice flag value that replaces its values the first time it's set after a reset.
+type resetSliceValue struct {
+	pflag.Value
+	pflag.SliceValue
+	reset bool
+}
+
+func (v *resetSliceValue) Set(s string) error {
+	if v.reset {
+		v.reset = false
+		if err := v.Replace(nil); err != nil {
+			return err
+		}
+	}
+	return v.Value.Set(s)
+}
+
+// resetFlags restores every flag to its default, so flags set by one test don't affect the next.
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+
+		if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+			// Setting a slice flag appends to it, so replace the values instead.
+			defValue := strings.Trim(f.DefValue, "[]")
+			if defValue == "" {
+				sliceValue.Replace(nil)
+			} else {
+				sliceValue.Replace(strings.Split(defValue, ","))
+			}
+
+			// Once a slice flag has been set, setting it again appends even after replacing the values,
+			// so make the next set replace the default like it would in a new process.
+			if resetValue, ok := f.Value.(*resetSliceValue); ok {
+				resetValue.reset = true
+			} else {
+				f.Value = &resetSliceValue{Value: f.Value, SliceValue: sliceValue, reset: true}
+			}
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+
+	for _, subCmd := range cmd.Commands() {
+		resetFlags(subCmd)
+	}
+}
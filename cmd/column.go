@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+	"github.com/wedaly/gospelunk/pkg/list"
+)
+
+// columnConverter converts columns between bytes, which every package uses internally, and the unit chosen by the user.
+// Columns are counted in the overlay contents for files in the overlay.
+func columnConverter(overlay file.Overlay) (*file.ColumnConverter, error) {
+	unit, err := file.ColumnUnitFromString(ColumnUnitArg)
+	if err != nil {
+		return nil, err
+	}
+	return file.NewColumnConverter(unit, overlay), nil
+}
+
+// convertLocsFromBytes converts each location in place from byte columns to the converter's unit.
+func convertLocsFromBytes(c *file.ColumnConverter, locs ...*file.Loc) error {
+	for _, loc := range locs {
+		converted, err := c.FromBytes(*loc)
+		if err != nil {
+			return err
+		}
+		*loc = converted
+	}
+	return nil
+}
+
+func convertDefsFromBytes(c *file.ColumnConverter, defs []list.Definition) error {
+	for i := range defs {
+		if err := convertLocsFromBytes(c, &defs[i].Loc, &defs[i].Decl, &defs[i].NameLoc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertOutlineNodesFromBytes(c *file.ColumnConverter, nodes []list.OutlineNode) error {
+	for i := range nodes {
+		if err := convertLocsFromBytes(c, &nodes[i].Loc, &nodes[i].Decl, &nodes[i].NameLoc); err != nil {
+			return err
+		}
+		if err := convertOutlineNodesFromBytes(c, nodes[i].Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertResultFromBytes converts the relations and near misses of an inspect result.
+func convertResultFromBytes(c *file.ColumnConverter, result *inspect.Result) error {
+	if err := convertRelationsFromBytes(c, result.Relations); err != nil {
+		return err
+	}
+	for i := range result.NearMisses {
+		if err := convertLocsFromBytes(c, &result.NearMisses[i].Loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertRelationsFromBytes(c *file.ColumnConverter, relations []inspect.Relation) error {
+	for i := range relations {
+		if err := convertLocsFromBytes(c, &relations[i].Loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/deps"
+	"github.com/wedaly/gospelunk/pkg/output"
+)
+
+var (
+	DepsSearchDirArg       string
+	DepsFormatArg          string
+	DepsIncludeExternalArg bool
+	DepsImportersOfArg     string
+	DepsRulesArg           string
+)
+
+var depsCmd = &cobra.Command{
+	Use:   "deps [flags]",
+	Short: "show the package import graph",
+	Long:  "show the import graph of the packages in every Go module in a directory, and check for import cycles and layering rule violations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := deps.FormatFromString(DepsFormatArg)
+		if err != nil {
+			return err
+		}
+
+		var rules []deps.Rule
+		if DepsRulesArg != "" {
+			rules, err = deps.ReadRules(DepsRulesArg)
+			if err != nil {
+				return err
+			}
+		}
+
+		idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+
+		result, err := deps.Deps(DepsSearchDirArg, deps.Options{
+			IncludeExternal: DepsIncludeExternalArg,
+			ImportersOf:     DepsImportersOfArg,
+			Rules:           rules,
+			Build:           buildConfig(),
+			Index:           idx,
+		})
+		if err != nil {
+			return err
+		}
+
+		switch format {
+		case deps.FormatDOT:
+			err = result.WriteDOT(cmd.OutOrStdout())
+		case deps.FormatMermaid:
+			err = result.WriteMermaid(cmd.OutOrStdout())
+		case deps.FormatJSON:
+			err = output.JSON(cmd.OutOrStdout(), result)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, cycle := range result.Cycles {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Import cycle: %s\n", strings.Join(cycle, " -> "))
+		}
+		for _, violation := range result.Violations {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Rule %q violated: %s\n", violation.Rule, strings.Join(violation.Path, " -> "))
+		}
+
+		if len(result.Cycles) > 0 || len(result.Violations) > 0 {
+			return fmt.Errorf("Found %d import cycles and %d rule violations", len(result.Cycles), len(result.Violations))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	depsCmd.Flags().StringVarP(&DepsSearchDirArg, "searchDir", "d", ".", "Path to directory with the Go modules to graph")
+	depsCmd.Flags().StringVar(&DepsFormatArg, "format", string(deps.FormatDOT), fmt.Sprintf("Output format. Allowed values: [%s]", strings.Join(deps.AllFormatStrings, ", ")))
+	depsCmd.Flags().BoolVar(&DepsIncludeExternalArg, "include-external", false, "Include imports of packages outside the search directory, like the standard library")
+	depsCmd.Flags().StringVar(&DepsImportersOfArg, "importers-of", "", "Show only packages that import a package matching this pattern, directly or transitively")
+	depsCmd.Flags().StringVar(&DepsRulesArg, "rules", "", "Path to a file with layering rules like \"pkg/list must not import pkg/inspect\", one per line")
+	rootCmd.AddCommand(depsCmd)
+}
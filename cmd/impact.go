@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/impact"
+	"github.com/wedaly/gospelunk/pkg/output"
+)
+
+var (
+	ImpactSearchDirArg string
+	ImpactTemplateArg  string
+	ImpactFormatArg    string
+)
+
+var impactCmd = &cobra.Command{
+	Use:   "impact [flags] <git-rev-range>",
+	Short: "find code affected by a git diff",
+	Long:  "find exported definitions changed by a git revision range, and the packages, files, and tests that reference them",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpl, err := output.Template(ImpactTemplateArg)
+		if err != nil {
+			return err
+		}
+
+		format, err := output.FormatFromString(ImpactFormatArg)
+		if err != nil {
+			return err
+		}
+
+		idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+
+		result, err := impact.Impact(args[0], ImpactSearchDirArg, impact.Options{Build: buildConfig(), Index: idx})
+		if err != nil {
+			return err
+		}
+
+		columns, err := columnConverter(nil)
+		if err != nil {
+			return err
+		}
+
+		for i := range result.Symbols {
+			sym := &result.Symbols[i]
+			if err := convertLocsFromBytes(columns, &sym.Loc, &sym.Decl, &sym.NameLoc); err != nil {
+				return err
+			}
+			if err := convertRelationsFromBytes(columns, sym.References); err != nil {
+				return err
+			}
+		}
+
+		for i := range result.Tests {
+			if err := convertLocsFromBytes(columns, &result.Tests[i].Loc); err != nil {
+				return err
+			}
+		}
+
+		switch format {
+		case output.FormatJSON:
+			return output.JSON(cmd.OutOrStdout(), result)
+		case output.FormatJSONLines:
+			return output.JSONLines(cmd.OutOrStdout(), result.Symbols)
+		}
+
+		err = tmpl.Execute(cmd.OutOrStdout(), result)
+		if err != nil {
+			return fmt.Errorf("template.Execute: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	impactCmd.Flags().StringVarP(&ImpactSearchDirArg, "searchDir", "d", ".", "Path to directory in the git repository to search for references")
+
+	defaultTpl := `{{range .Symbols}}changed {{.Name}} {{.Path|RelPath}}:{{.Line}}:{{.Column}}
+{{end}}{{range .Packages}}package {{.}}
+{{end}}{{range .Tests}}test {{.Pkg}} {{.Name}}
+{{end}}`
+	impactCmd.Flags().StringVarP(&ImpactTemplateArg, "template", "t", defaultTpl, "Go template for formatting result output")
+	impactCmd.Flags().StringVar(&ImpactFormatArg, "format", string(output.FormatTemplate), formatUsage)
+
+	rootCmd.AddCommand(impactCmd)
+}
@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"text/template"
 
 	"github.com/spf13/cobra"
 
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/daemon"
 	"github.com/wedaly/gospelunk/pkg/file"
 	"github.com/wedaly/gospelunk/pkg/inspect"
 	"github.com/wedaly/gospelunk/pkg/output"
@@ -13,11 +19,19 @@ import (
 
 var (
 	InspectFileArg          string
+	InspectSymbolArg        string
 	InspectLineArg          int
 	InspectColumnArg        int
 	InspectSearchDirArg     string
 	InspectTemplateArg      string
+	InspectFormatArg        string
 	InspectRelationKindsArg []string
+	InspectDocArg           bool
+	InspectBatchArg         bool
+	InspectRefFilterArg     []string
+	InspectPlatformsArg     []string
+	InspectSearchDepsArg    bool
+	InspectUniverseArg      []string
 )
 
 var inspectCmd = &cobra.Command{
@@ -30,26 +44,94 @@ var inspectCmd = &cobra.Command{
 			return err
 		}
 
+		format, err := output.FormatFromString(InspectFormatArg)
+		if err != nil {
+			return err
+		}
+
 		relKinds, err := inspect.RelationKindsFromStrings(InspectRelationKindsArg)
 		if err != nil {
 			return err
 		}
 
-		loc := file.Loc{
+		refAccesses, err := inspect.RefAccessesFromStrings(InspectRefFilterArg)
+		if err != nil {
+			return err
+		}
+
+		overlay, err := readOverlay(cmd)
+		if err != nil {
+			return err
+		}
+
+		columns, err := columnConverter(overlay)
+		if err != nil {
+			return err
+		}
+
+		builds, err := inspectBuildConfigs()
+		if err != nil {
+			return err
+		}
+
+		opts := inspect.Options{IncludeDoc: InspectDocArg, RefAccesses: refAccesses, SearchDeps: InspectSearchDepsArg, Universe: InspectUniverseArg, Overlay: overlay}
+		if InspectBatchArg {
+			return inspectBatch(cmd, relKinds, opts, builds, columns, format, tmpl)
+		}
+
+		inputLoc := file.Loc{
 			Path:   InspectFileArg,
 			Line:   InspectLineArg,
 			Column: InspectColumnArg,
 		}
-		result, err := inspect.Inspect(loc, InspectSearchDirArg, relKinds)
-		if err != nil {
-			return err
+		loc := inputLoc
+		if InspectSymbolArg == "" {
+			loc, err = columns.ToBytes(inputLoc)
+			if err != nil {
+				return err
+			}
 		}
 
+		req := daemon.InspectRequest{
+			Loc:           loc,
+			Symbol:        InspectSymbolArg,
+			SearchDir:     InspectSearchDirArg,
+			RelationKinds: relKinds,
+			Options:       opts,
+		}
+
+		// Inspect in each build configuration and merge the relations.
+		results := make([]*inspect.Result, 0, len(builds))
+		for _, build := range builds {
+			req.Options.Build = build
+			result, err := runInspect(req)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+
+		result := inspect.MergeResults(results)
 		if result == nil {
-			fmt.Fprintf(cmd.ErrOrStderr(), "No results found for %s", loc)
+			target := inputLoc.String()
+			if req.Symbol != "" {
+				target = req.Symbol
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "No results found for %s", target)
 			return nil
 		}
 
+		if err := convertResultFromBytes(columns, result); err != nil {
+			return err
+		}
+
+		switch format {
+		case output.FormatJSON:
+			return output.JSON(cmd.OutOrStdout(), result)
+		case output.FormatJSONLines:
+			return output.JSONLines(cmd.OutOrStdout(), []*inspect.Result{result})
+		}
+
 		err = tmpl.Execute(cmd.OutOrStdout(), result)
 		if err != nil {
 			return fmt.Errorf("template.Execute: %w", err)
@@ -59,24 +141,243 @@ var inspectCmd = &cobra.Command{
 	},
 }
 
+// inspectBuildConfigs returns the build configurations to inspect: one for each platform if the user set any, or otherwise the configuration from the build flags.
+func inspectBuildConfigs() ([]buildcfg.Config, error) {
+	if len(InspectPlatformsArg) == 0 {
+		return []buildcfg.Config{buildConfig()}, nil
+	}
+
+	if GOOSArg != "" || GOARCHArg != "" {
+		return nil, fmt.Errorf("Cannot use --platforms with --goos or --goarch")
+	}
+
+	builds := make([]buildcfg.Config, 0, len(InspectPlatformsArg))
+	for _, s := range InspectPlatformsArg {
+		platform, err := buildcfg.PlatformFromString(s)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, buildcfg.Config{Tags: TagsArg, GOOS: platform.GOOS, GOARCH: platform.GOARCH})
+	}
+	return builds, nil
+}
+
+func runInspect(req daemon.InspectRequest) (*inspect.Result, error) {
+	socketPath, ok := runningDaemonSocket()
+	if !ok {
+		idx, err := openIndex()
+		if err != nil {
+			return nil, err
+		}
+		req.Options.Index = idx
+
+		if req.Symbol != "" {
+			return inspect.InspectSymbol(req.Symbol, req.SearchDir, req.RelationKinds, req.Options)
+		}
+		return inspect.Inspect(req.Loc, req.SearchDir, req.RelationKinds, req.Options)
+	}
+
+	// The daemon may run in a different working directory, so send absolute paths.
+	if req.Symbol == "" {
+		absLocPath, err := filepath.Abs(req.Loc.Path)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Abs: %w", err)
+		}
+		req.Loc.Path = absLocPath
+	}
+
+	absSearchDir, err := filepath.Abs(req.SearchDir)
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Abs: %w", err)
+	}
+	req.SearchDir = absSearchDir
+
+	return daemon.Inspect(socketPath, req)
+}
+
+// inspectBatch reads locations from stdin, one per line, and writes one result for each line.
+// A line can be either "path:line:column" or a JSON-encoded location like {"path": "main.go", "line": 1, "column": 1}.
+func inspectBatch(cmd *cobra.Command, relKinds []inspect.RelationKind, opts inspect.Options, builds []buildcfg.Config, columns *file.ColumnConverter, format output.Format, tmpl *template.Template) error {
+	var results []inspect.BatchResult
+	var inputLocs, locs []file.Loc
+	var locResultIndices []int
+	scanner := bufio.NewScanner(cmd.InOrStdin())
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		loc, err := parseBatchLoc(line)
+		if err != nil {
+			results = append(results, inspect.BatchResult{Error: err.Error()})
+			continue
+		}
+
+		byteLoc, err := columns.ToBytes(loc)
+		if err != nil {
+			results = append(results, inspect.BatchResult{Loc: loc, Error: err.Error()})
+			continue
+		}
+
+		inputLocs = append(inputLocs, loc)
+		locs = append(locs, byteLoc)
+		locResultIndices = append(locResultIndices, len(results))
+		results = append(results, inspect.BatchResult{Loc: loc})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanner.Scan: %w", err)
+	}
+
+	// Inspect in each build configuration and merge the results for each location.
+	batchResultsByBuild := make([][]inspect.BatchResult, 0, len(builds))
+	for _, build := range builds {
+		opts.Build = build
+		batchResults, err := runInspectBatch(daemon.InspectBatchRequest{
+			Locs:          locs,
+			SearchDir:     InspectSearchDirArg,
+			RelationKinds: relKinds,
+			Options:       opts,
+		})
+		if err != nil {
+			return err
+		}
+		batchResultsByBuild = append(batchResultsByBuild, batchResults)
+	}
+	batchResults := mergeBatchResults(batchResultsByBuild)
+
+	for i, batchResult := range batchResults {
+		// Report the location as it was written in the input, not the absolute path sent to the daemon.
+		batchResult.Loc = inputLocs[i]
+		if batchResult.Result != nil {
+			if err := convertResultFromBytes(columns, batchResult.Result); err != nil {
+				return err
+			}
+		}
+		results[locResultIndices[i]] = batchResult
+	}
+
+	switch format {
+	case output.FormatJSON:
+		// The schema version is added to a JSON object, so wrap the results in one.
+		return output.JSON(cmd.OutOrStdout(), struct {
+			Results []inspect.BatchResult `json:"results"`
+		}{Results: results})
+	case output.FormatJSONLines:
+		return output.JSONLines(cmd.OutOrStdout(), results)
+	}
+
+	for _, batchResult := range results {
+		if batchResult.Error != "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %s\n", batchResult.Error)
+			continue
+		}
+
+		if err := tmpl.Execute(cmd.OutOrStdout(), batchResult.Result); err != nil {
+			return fmt.Errorf("template.Execute: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mergeBatchResults merges the results for each location across build configurations.
+// A location has an error only if every configuration failed, in which case the first error is reported.
+func mergeBatchResults(batchResultsByBuild [][]inspect.BatchResult) []inspect.BatchResult {
+	merged := batchResultsByBuild[0]
+	for i := range merged {
+		var results []*inspect.Result
+		for _, batchResults := range batchResultsByBuild {
+			if batchResults[i].Error == "" {
+				results = append(results, batchResults[i].Result)
+			}
+		}
+
+		if len(results) > 0 {
+			merged[i].Result = inspect.MergeResults(results)
+			merged[i].Error = ""
+		}
+	}
+	return merged
+}
+
+func parseBatchLoc(line string) (file.Loc, error) {
+	if !strings.HasPrefix(line, "{") {
+		return file.ParseLoc(line)
+	}
+
+	var loc file.Loc
+	if err := json.Unmarshal([]byte(line), &loc); err != nil {
+		return file.Loc{}, fmt.Errorf("Invalid location %q: %w", line, err)
+	}
+	return loc, nil
+}
+
+func runInspectBatch(req daemon.InspectBatchRequest) ([]inspect.BatchResult, error) {
+	socketPath, ok := runningDaemonSocket()
+	if !ok {
+		idx, err := openIndex()
+		if err != nil {
+			return nil, err
+		}
+		req.Options.Index = idx
+		return inspect.InspectBatch(req.Locs, req.SearchDir, req.RelationKinds, req.Options), nil
+	}
+
+	// The daemon may run in a different working directory, so send absolute paths.
+	absLocs := make([]file.Loc, len(req.Locs))
+	for i, loc := range req.Locs {
+		absLocPath, err := filepath.Abs(loc.Path)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Abs: %w", err)
+		}
+		loc.Path = absLocPath
+		absLocs[i] = loc
+	}
+	req.Locs = absLocs
+
+	absSearchDir, err := filepath.Abs(req.SearchDir)
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Abs: %w", err)
+	}
+	req.SearchDir = absSearchDir
+
+	return daemon.InspectBatch(socketPath, req)
+}
+
 func init() {
 	inspectCmd.Flags().StringVarP(&InspectFileArg, "file", "f", "", "Go source file")
-	inspectCmd.MarkFlagRequired("file")
-
 	inspectCmd.Flags().IntVarP(&InspectLineArg, "line", "l", 1, "Line number in Go source file")
-	inspectCmd.MarkFlagRequired("line")
-
 	inspectCmd.Flags().IntVarP(&InspectColumnArg, "column", "c", 1, "Column number in Go source file")
-	inspectCmd.MarkFlagRequired("column")
+	inspectCmd.Flags().StringVarP(&InspectSymbolArg, "symbol", "s", "", "Symbol to inspect instead of a file location, like pkg.Func, pkg.Type.Field, or pkg.Type.Method")
+	inspectCmd.Flags().BoolVar(&InspectBatchArg, "batch", false, "Read locations from stdin, one per line, as path:line:column or JSON, and output a result for each")
+	inspectCmd.MarkFlagsRequiredTogether("file", "line", "column")
+	inspectCmd.MarkFlagsOneRequired("file", "symbol", "batch")
+	inspectCmd.MarkFlagsMutuallyExclusive("file", "symbol", "batch")
+
+	addOverlayFlags(inspectCmd)
+	inspectCmd.MarkFlagsMutuallyExclusive("batch", "modified")
 
 	inspectCmd.Flags().StringVarP(&InspectSearchDirArg, "searchDir", "d", ".", "Path to directory to search for relations outside the current package")
+	inspectCmd.Flags().StringSliceVar(&InspectUniverseArg, "universe", nil, "Also check types against every exported interface in these packages, comma separated, like std or github.com/pkg/errors, and report near misses")
+	inspectCmd.Flags().BoolVar(&InspectSearchDepsArg, "searchDeps", false, "Also search the dependencies of modules in the search directory (vendored or in the module cache), except the standard library")
 
 	defaultRelationKinds := []string{"definition"}
 	relationKindsUsage := fmt.Sprintf("Kinds of relations to include, comma separated. Allowed values: [%s]", strings.Join(inspect.AllRelationKindStrings, ", "))
 	inspectCmd.Flags().StringSliceVarP(&InspectRelationKindsArg, "relationKinds", "r", defaultRelationKinds, relationKindsUsage)
 
-	defaultTpl := "{{range .Relations}}{{.Name}} {{.Path|RelPath}}:{{.Line}}:{{.Column}}\n{{end}}"
+	refFilterUsage := fmt.Sprintf("Include only references with these accesses, comma separated. Allowed values: [%s]", strings.Join(inspect.AllRefAccessStrings, ", "))
+	inspectCmd.Flags().StringSliceVar(&InspectRefFilterArg, "refFilter", nil, refFilterUsage)
+
+	inspectCmd.Flags().StringSliceVar(&InspectPlatformsArg, "platforms", nil, "Inspect in each of these platforms, as goos/goarch pairs separated by commas, and merge the relations")
+
+	inspectCmd.Flags().BoolVar(&InspectDocArg, "doc", false, "Include the doc comment, declaration, and package synopsis in the result")
+
+	defaultTpl := "{{range .Relations}}{{.Name}} {{.Path|RelPath}}:{{.Line}}:{{.Column}}{{if .Module}} ({{.Module}}{{if .Version}}@{{.Version}}{{end}}){{end}}\n{{end}}" +
+		"{{range .NearMisses}}{{.Name}} {{.Path|RelPath}}:{{.Line}}:{{.Column}} missing{{range $i, $m := .Missing}}{{if $i}},{{end}} {{$m}}{{end}}\n{{end}}"
 	inspectCmd.Flags().StringVarP(&InspectTemplateArg, "template", "t", defaultTpl, "Go template for formatting result output")
+	inspectCmd.Flags().StringVar(&InspectFormatArg, "format", string(output.FormatTemplate), formatUsage)
 
 	rootCmd.AddCommand(inspectCmd)
 }
@@ -2,20 +2,25 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/wedaly/gospelunk/pkg/daemon"
 	"github.com/wedaly/gospelunk/pkg/list"
 	"github.com/wedaly/gospelunk/pkg/output"
 )
 
 var (
 	ListTemplateArg                string
+	ListFormatArg                  string
 	ListIncludeStructFieldsArg     bool
 	ListIncludeInterfaceMethodsArg bool
 	ListIncludePrivateArg          bool
 	ListIncludeTestsArg            bool
 	ListOnlyImportsArg             bool
+	ListKindsArg                   []string
 )
 
 var listCmd = &cobra.Command{
@@ -28,6 +33,21 @@ var listCmd = &cobra.Command{
 			return err
 		}
 
+		format, err := output.FormatFromString(ListFormatArg)
+		if err != nil {
+			return err
+		}
+
+		kinds, err := list.DefKindsFromStrings(ListKindsArg)
+		if err != nil {
+			return err
+		}
+
+		overlay, err := readOverlay(cmd)
+		if err != nil {
+			return err
+		}
+
 		patterns := args // Passed to Go build system to locate packages.
 		opts := list.Options{
 			IncludeStructFields:     ListIncludeStructFieldsArg,
@@ -35,12 +55,31 @@ var listCmd = &cobra.Command{
 			IncludePrivate:          ListIncludePrivateArg,
 			IncludeTests:            ListIncludeTestsArg,
 			OnlyImports:             ListOnlyImportsArg,
+			Kinds:                   kinds,
+			Build:                   buildConfig(),
+			Overlay:                 overlay,
+		}
+		result, err := runList(patterns, opts)
+		if err != nil {
+			return err
 		}
-		result, err := list.List(patterns, opts)
+
+		columns, err := columnConverter(overlay)
 		if err != nil {
 			return err
 		}
 
+		if err := convertDefsFromBytes(columns, result.Defs); err != nil {
+			return err
+		}
+
+		switch format {
+		case output.FormatJSON:
+			return output.JSON(cmd.OutOrStdout(), result)
+		case output.FormatJSONLines:
+			return output.JSONLines(cmd.OutOrStdout(), result.Defs)
+		}
+
 		err = tmpl.Execute(cmd.OutOrStdout(), result)
 		if err != nil {
 			return fmt.Errorf("template.Execute: %w", err)
@@ -50,12 +89,41 @@ var listCmd = &cobra.Command{
 	},
 }
 
+func runList(patterns []string, opts list.Options) (list.Result, error) {
+	socketPath, ok := runningDaemonSocket()
+	if !ok {
+		idx, err := openIndex()
+		if err != nil {
+			return list.Result{}, err
+		}
+		opts.Index = idx
+		return list.List(patterns, opts)
+	}
+
+	// The daemon may run in a different working directory, so resolve patterns relative to ours.
+	cwd, err := os.Getwd()
+	if err != nil {
+		return list.Result{}, fmt.Errorf("os.Getwd: %w", err)
+	}
+	opts.Dir = cwd
+
+	return daemon.List(socketPath, daemon.ListRequest{
+		Patterns: patterns,
+		Options:  opts,
+	})
+}
+
 func init() {
 	listCmd.Flags().StringVarP(&ListTemplateArg, "template", "t", "{{ range .Defs }}{{.Name}} {{.Path|RelPath}}:{{.Line}}:{{.Column}}\n{{end}}", "Go template for formatting result output")
+	listCmd.Flags().StringVar(&ListFormatArg, "format", string(output.FormatTemplate), formatUsage)
 	listCmd.Flags().BoolVar(&ListIncludeStructFieldsArg, "include-struct-fields", false, "Include struct fields")
 	listCmd.Flags().BoolVar(&ListIncludeInterfaceMethodsArg, "include-interface-methods", false, "Include interface methods")
 	listCmd.Flags().BoolVarP(&ListIncludePrivateArg, "include-private", "p", false, "Include private definitions")
 	listCmd.Flags().BoolVar(&ListIncludeTestsArg, "include-tests", false, "Include definitions from tests")
 	listCmd.Flags().BoolVar(&ListOnlyImportsArg, "only-imports", false, "Search only imported packages")
+
+	kindsUsage := fmt.Sprintf("Kinds of definitions to include, comma separated. Allowed values: [%s]", strings.Join(list.AllDefKindStrings, ", "))
+	listCmd.Flags().StringSliceVarP(&ListKindsArg, "kinds", "k", nil, kindsUsage)
+	addOverlayFlags(listCmd)
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/lsp"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "run a language server",
+	Long:  "run a language server that speaks the language server protocol over stdin and stdout",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lsp.NewServer().Serve(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
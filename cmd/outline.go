@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/list"
+	"github.com/wedaly/gospelunk/pkg/output"
+)
+
+var (
+	OutlineFileArg     string
+	OutlineTemplateArg string
+	OutlineFormatArg   string
+)
+
+var outlineCmd = &cobra.Command{
+	Use:   "outline [flags]",
+	Short: "show the definitions in a Go file as a tree",
+	Long:  "show the definitions in a Go file as a tree, with fields and methods grouped under their types and local definitions under their funcs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpl, err := output.Template(OutlineTemplateArg)
+		if err != nil {
+			return err
+		}
+
+		format, err := output.FormatFromString(OutlineFormatArg)
+		if err != nil {
+			return err
+		}
+
+		overlay, err := readOverlay(cmd)
+		if err != nil {
+			return err
+		}
+
+		result, err := list.Outline(OutlineFileArg, list.Options{Build: buildConfig(), Overlay: overlay})
+		if err != nil {
+			return err
+		}
+
+		columns, err := columnConverter(overlay)
+		if err != nil {
+			return err
+		}
+
+		if err := convertOutlineNodesFromBytes(columns, result.Nodes); err != nil {
+			return err
+		}
+
+		switch format {
+		case output.FormatJSON:
+			return output.JSON(cmd.OutOrStdout(), result)
+		case output.FormatJSONLines:
+			return output.JSONLines(cmd.OutOrStdout(), result.Nodes)
+		}
+
+		err = tmpl.Execute(cmd.OutOrStdout(), result)
+		if err != nil {
+			return fmt.Errorf("template.Execute: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	outlineCmd.Flags().StringVarP(&OutlineFileArg, "file", "f", "", "Path to the Go file")
+	outlineCmd.MarkFlagRequired("file")
+	outlineCmd.Flags().StringVarP(&OutlineTemplateArg, "template", "t", "{{ range .Flatten }}{{.Indent}}{{.Name}} {{.Kind}} {{.Decl.Line}}-{{.Decl.End.Line}}\n{{end}}", "Go template for formatting result output")
+	outlineCmd.Flags().StringVar(&OutlineFormatArg, "format", string(output.FormatTemplate), formatUsage)
+	addOverlayFlags(outlineCmd)
+	rootCmd.AddCommand(outlineCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+var (
+	OverlayArg  string
+	ModifiedArg bool
+)
+
+// addOverlayFlags adds the flags for replacing files on disk with unsaved editor buffers.
+func addOverlayFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&OverlayArg, "overlay", "", "JSON file that replaces files on disk, in the same format as \"go build -overlay\"")
+	cmd.Flags().BoolVar(&ModifiedArg, "modified", false, "Read the contents of modified files from stdin, as an archive of file name, size in bytes, and contents for each file")
+}
+
+// readOverlay reads the overlay from the file or stdin, as set by the overlay flags.
+// If neither flag is set, this returns a nil overlay.
+func readOverlay(cmd *cobra.Command) (file.Overlay, error) {
+	overlay := make(file.Overlay)
+
+	if OverlayArg != "" {
+		jsonOverlay, err := file.ReadOverlayJSON(OverlayArg)
+		if err != nil {
+			return nil, err
+		}
+		for path, contents := range jsonOverlay {
+			overlay[path] = contents
+		}
+	}
+
+	if ModifiedArg {
+		archiveOverlay, err := file.ReadOverlayArchive(cmd.InOrStdin())
+		if err != nil {
+			return nil, err
+		}
+		for path, contents := range archiveOverlay {
+			overlay[path] = contents
+		}
+	}
+
+	if len(overlay) == 0 {
+		return nil, nil
+	}
+	return overlay, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/output"
+	"github.com/wedaly/gospelunk/pkg/rename"
+)
+
+var (
+	RenameFileArg      string
+	RenameLineArg      int
+	RenameColumnArg    int
+	RenameToArg        string
+	RenameSearchDirArg string
+	RenameWriteArg     bool
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename",
+	Short: "rename a Go identifier",
+	Long:  "rename an identifier along with its definition and every reference to it, printing a unified diff or writing the changes to disk",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+
+		columns, err := columnConverter(nil)
+		if err != nil {
+			return err
+		}
+
+		loc, err := columns.ToBytes(file.Loc{
+			Path:   RenameFileArg,
+			Line:   RenameLineArg,
+			Column: RenameColumnArg,
+		})
+		if err != nil {
+			return err
+		}
+
+		result, err := rename.Rename(loc, RenameSearchDirArg, RenameToArg, rename.Options{Build: buildConfig(), Index: idx})
+		if err != nil {
+			return err
+		}
+
+		relPath := output.RelPathTplFunc()
+
+		if len(result.Conflicts) > 0 {
+			for _, c := range result.Conflicts {
+				if err := convertLocsFromBytes(columns, &c.Loc); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s:%d:%d: %s\n", relPath(c.Path), c.Line, c.Column, c.Reason)
+			}
+			return fmt.Errorf("Could not rename to %q because of %d conflicts", RenameToArg, len(result.Conflicts))
+		}
+
+		if RenameWriteArg {
+			return result.Apply()
+		}
+
+		return result.Diff(cmd.OutOrStdout(), relPath)
+	},
+}
+
+func init() {
+	renameCmd.Flags().StringVarP(&RenameFileArg, "file", "f", "", "Go source file")
+	renameCmd.MarkFlagRequired("file")
+
+	renameCmd.Flags().IntVarP(&RenameLineArg, "line", "l", 1, "Line number in Go source file")
+	renameCmd.MarkFlagRequired("line")
+
+	renameCmd.Flags().IntVarP(&RenameColumnArg, "column", "c", 1, "Column number in Go source file")
+	renameCmd.MarkFlagRequired("column")
+
+	renameCmd.Flags().StringVar(&RenameToArg, "to", "", "New name for the identifier")
+	renameCmd.MarkFlagRequired("to")
+
+	renameCmd.Flags().StringVarP(&RenameSearchDirArg, "searchDir", "d", ".", "Path to directory to search for references outside the current package")
+	renameCmd.Flags().BoolVarP(&RenameWriteArg, "write", "w", false, "Write the changes to disk instead of printing a diff")
+
+	rootCmd.AddCommand(renameCmd)
+}
@@ -4,8 +4,27 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/daemon"
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
+	"github.com/wedaly/gospelunk/pkg/output"
+)
+
+var formatUsage = fmt.Sprintf("Output format. Allowed values: [%s]", strings.Join(output.AllFormatStrings, ", "))
+
+var (
+	DaemonSocketArg string
+	NoDaemonArg     bool
+	IndexArg        bool
+	ColumnUnitArg   string
+	TagsArg         []string
+	GOOSArg         string
+	GOARCHArg       string
 )
 
 var rootCmd = &cobra.Command{
@@ -19,13 +38,52 @@ func init() {
 		cmd.SilenceUsage = true
 		cmd.SilenceErrors = true
 	}
+
+	rootCmd.PersistentFlags().StringVar(&DaemonSocketArg, "socket", daemon.DefaultSocketPath(), "Unix socket of the gospelunk daemon")
+	rootCmd.PersistentFlags().BoolVar(&NoDaemonArg, "no-daemon", false, "Load packages in this process even if a daemon is running")
+	rootCmd.PersistentFlags().BoolVar(&IndexArg, "index", false, "Store package metadata in an on-disk index so later commands can skip unchanged packages")
+
+	columnUnitUsage := fmt.Sprintf("Unit for counting columns in input and output locations. Allowed values: [%s]", strings.Join(file.AllColumnUnitStrings, ", "))
+	rootCmd.PersistentFlags().StringVar(&ColumnUnitArg, "column-unit", string(file.ColumnUnitBytes), columnUnitUsage)
+
+	rootCmd.PersistentFlags().StringSliceVar(&TagsArg, "tags", nil, "Build tags to use when loading packages, comma separated")
+	rootCmd.PersistentFlags().StringVar(&GOOSArg, "goos", "", "Target operating system to use when loading packages, instead of $GOOS")
+	rootCmd.PersistentFlags().StringVar(&GOARCHArg, "goarch", "", "Target architecture to use when loading packages, instead of $GOARCH")
+}
+
+// runningDaemonSocket returns the socket path of a running daemon that should handle requests.
+// If no daemon is running (or the user disabled it), this returns false.
+func runningDaemonSocket() (string, bool) {
+	if NoDaemonArg || !daemon.IsRunning(DaemonSocketArg) {
+		return "", false
+	}
+	return DaemonSocketArg, true
+}
+
+// openIndex opens the on-disk index if the user enabled it, or returns nil otherwise.
+func openIndex() (*index.Index, error) {
+	if !IndexArg {
+		return nil, nil
+	}
+
+	dir, err := index.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return index.Open(dir)
+}
+
+// buildConfig returns the build configuration set by the user.
+func buildConfig() buildcfg.Config {
+	return buildcfg.Config{Tags: TagsArg, GOOS: GOOSArg, GOARCH: GOARCHArg}
 }
 
 func Execute() error {
 	return rootCmd.Execute()
 }
 
-func ExecuteInTest(args []string, cwd string) (stdout string, stderr string, err error) {
+func ExecuteInTest(args []string, cwd string, stdin string) (stdout string, stderr string, err error) {
 	// Set the current working directory and restore on exit.
 	oldWd, err := os.Getwd()
 	if err != nil {
@@ -38,13 +96,16 @@ func ExecuteInTest(args []string, cwd string) (stdout string, stderr string, err
 		return "", "", fmt.Errorf("os.Chdir: %w", err)
 	}
 
-	// Configure the cmd to capture stdout and stderr and use test-provided args.
+	// Configure the cmd to capture stdout and stderr and use test-provided args and stdin.
+	// Tests always run in this process, so a daemon that happens to be running doesn't answer them.
 	var stdoutBuf, stderrBuf bytes.Buffer
-	rootCmd.SetArgs(args)
+	rootCmd.SetArgs(append([]string{"--no-daemon"}, args...))
+	rootCmd.SetIn(strings.NewReader(stdin))
 	rootCmd.SetOut(&stdoutBuf)
 	rootCmd.SetErr(&stderrBuf)
 	defer func() {
 		rootCmd.SetArgs(nil)
+		rootCmd.SetIn(nil)
 		rootCmd.SetOut(nil)
 		rootCmd.SetErr(nil)
 	}()
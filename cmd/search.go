@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/list"
+	"github.com/wedaly/gospelunk/pkg/output"
+	"github.com/wedaly/gospelunk/pkg/search"
+)
+
+var (
+	SearchTemplateArg       string
+	SearchFormatArg         string
+	SearchKindsArg          []string
+	SearchPkgArg            string
+	SearchLimitArg          int
+	SearchIncludePrivateArg bool
+	SearchIncludeTestsArg   bool
+	SearchOnlyImportsArg    bool
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [flags] <query> [packages]",
+	Short: "fuzzy search for definitions in Go packages",
+	Long:  "search for definitions in Go packages with names that fuzzy match a query, ranked by how closely they match",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpl, err := output.Template(SearchTemplateArg)
+		if err != nil {
+			return err
+		}
+
+		format, err := output.FormatFromString(SearchFormatArg)
+		if err != nil {
+			return err
+		}
+
+		kinds, err := list.DefKindsFromStrings(SearchKindsArg)
+		if err != nil {
+			return err
+		}
+
+		query := args[0]
+		patterns := args[1:] // Passed to Go build system to locate packages.
+		if len(patterns) == 0 {
+			patterns = []string{"./..."}
+		}
+
+		overlay, err := readOverlay(cmd)
+		if err != nil {
+			return err
+		}
+
+		listOpts := list.Options{
+			IncludeStructFields:     true,
+			IncludeInterfaceMethods: true,
+			IncludePrivate:          SearchIncludePrivateArg,
+			IncludeTests:            SearchIncludeTestsArg,
+			OnlyImports:             SearchOnlyImportsArg,
+			Build:                   buildConfig(),
+			Overlay:                 overlay,
+		}
+		listResult, err := runList(patterns, listOpts)
+		if err != nil {
+			return err
+		}
+
+		result := search.Search(query, listResult.Defs, search.Options{
+			Kinds: kinds,
+			Pkg:   SearchPkgArg,
+			Limit: SearchLimitArg,
+		})
+
+		columns, err := columnConverter(overlay)
+		if err != nil {
+			return err
+		}
+
+		for i := range result.Matches {
+			if err := convertLocsFromBytes(columns, &result.Matches[i].Loc, &result.Matches[i].Decl, &result.Matches[i].NameLoc); err != nil {
+				return err
+			}
+		}
+
+		switch format {
+		case output.FormatJSON:
+			return output.JSON(cmd.OutOrStdout(), result)
+		case output.FormatJSONLines:
+			return output.JSONLines(cmd.OutOrStdout(), result.Matches)
+		}
+
+		err = tmpl.Execute(cmd.OutOrStdout(), result)
+		if err != nil {
+			return fmt.Errorf("template.Execute: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	searchCmd.Flags().StringVarP(&SearchTemplateArg, "template", "t", "{{ range .Matches }}{{.Name}} {{.Path|RelPath}}:{{.Line}}:{{.Column}}\n{{end}}", "Go template for formatting result output")
+	searchCmd.Flags().StringVar(&SearchFormatArg, "format", string(output.FormatTemplate), formatUsage)
+
+	kindsUsage := fmt.Sprintf("Kinds of definitions to include, comma separated. Allowed values: [%s]", strings.Join(list.AllDefKindStrings, ", "))
+	searchCmd.Flags().StringSliceVarP(&SearchKindsArg, "kinds", "k", nil, kindsUsage)
+
+	searchCmd.Flags().StringVar(&SearchPkgArg, "pkg", "", "Include only definitions from the package with this name or import path")
+	searchCmd.Flags().IntVarP(&SearchLimitArg, "limit", "n", 20, "Maximum number of results, or zero for no limit")
+	searchCmd.Flags().BoolVarP(&SearchIncludePrivateArg, "include-private", "p", false, "Include private definitions")
+	searchCmd.Flags().BoolVar(&SearchIncludeTestsArg, "include-tests", false, "Include definitions from tests")
+	searchCmd.Flags().BoolVar(&SearchOnlyImportsArg, "only-imports", false, "Search only imported packages")
+	addOverlayFlags(searchCmd)
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/daemon"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "run a daemon that keeps loaded packages in memory",
+	Long:  "run a daemon that keeps loaded Go packages in memory, so inspect and list commands can reuse them instead of loading packages from scratch",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.ErrOrStderr(), "Listening on %s\n", DaemonSocketArg)
+		return daemon.ListenAndServe(DaemonSocketArg, idx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
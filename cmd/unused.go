@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wedaly/gospelunk/pkg/output"
+	"github.com/wedaly/gospelunk/pkg/unused"
+)
+
+var (
+	UnusedSearchDirArg           string
+	UnusedTemplateArg            string
+	UnusedFormatArg              string
+	UnusedAllowlistArg           string
+	UnusedIncludeStructFieldsArg bool
+	UnusedIncludePrivateArg      bool
+	UnusedExcludeTestHelpersArg  bool
+)
+
+var unusedCmd = &cobra.Command{
+	Use:   "unused [flags] [packages]",
+	Short: "find definitions without references",
+	Long:  "find definitions in Go packages that aren't referenced by any Go module in the search directory",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpl, err := output.Template(UnusedTemplateArg)
+		if err != nil {
+			return err
+		}
+
+		format, err := output.FormatFromString(UnusedFormatArg)
+		if err != nil {
+			return err
+		}
+
+		var allowlist []string
+		if UnusedAllowlistArg != "" {
+			allowlist, err = unused.ReadAllowlist(UnusedAllowlistArg)
+			if err != nil {
+				return err
+			}
+		}
+
+		idx, err := openIndex()
+		if err != nil {
+			return err
+		}
+
+		patterns := args // Passed to Go build system to locate packages.
+		result, err := unused.Unused(patterns, UnusedSearchDirArg, unused.Options{
+			IncludeStructFields: UnusedIncludeStructFieldsArg,
+			IncludePrivate:      UnusedIncludePrivateArg,
+			ExcludeTestHelpers:  UnusedExcludeTestHelpersArg,
+			Allowlist:           allowlist,
+			Build:               buildConfig(),
+			Index:               idx,
+		})
+		if err != nil {
+			return err
+		}
+
+		columns, err := columnConverter(nil)
+		if err != nil {
+			return err
+		}
+
+		if err := convertDefsFromBytes(columns, result.Defs); err != nil {
+			return err
+		}
+
+		switch format {
+		case output.FormatJSON:
+			return output.JSON(cmd.OutOrStdout(), result)
+		case output.FormatJSONLines:
+			return output.JSONLines(cmd.OutOrStdout(), result.Defs)
+		}
+
+		err = tmpl.Execute(cmd.OutOrStdout(), result)
+		if err != nil {
+			return fmt.Errorf("template.Execute: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	unusedCmd.Flags().StringVarP(&UnusedSearchDirArg, "searchDir", "d", ".", "Path to directory to search for references")
+	unusedCmd.Flags().StringVarP(&UnusedTemplateArg, "template", "t", "{{ range .Defs }}{{.Name}} {{.Path|RelPath}}:{{.Line}}:{{.Column}}\n{{end}}", "Go template for formatting result output")
+	unusedCmd.Flags().StringVar(&UnusedFormatArg, "format", string(output.FormatTemplate), formatUsage)
+	unusedCmd.Flags().StringVar(&UnusedAllowlistArg, "allowlist", "", "Path to a file with patterns for definitions to skip, one per line")
+	unusedCmd.Flags().BoolVar(&UnusedIncludeStructFieldsArg, "include-struct-fields", false, "Include struct fields")
+	unusedCmd.Flags().BoolVarP(&UnusedIncludePrivateArg, "include-private", "p", false, "Include private definitions")
+	unusedCmd.Flags().BoolVar(&UnusedExcludeTestHelpersArg, "exclude-test-helpers", false, "Skip definitions in test files")
+	rootCmd.AddCommand(unusedCmd)
+}
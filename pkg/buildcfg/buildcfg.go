@@ -0,0 +1,267 @@
+package buildcfg
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+// Config is a build configuration: the build tags and target platform used to choose which files are in a package.
+// Empty fields use the defaults of the go command, including any set in the environment.
+type Config struct {
+	Tags   []string
+	GOOS   string
+	GOARCH string
+}
+
+// Platform is a GOOS/GOARCH pair.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+}
+
+func (p Platform) String() string {
+	return fmt.Sprintf("%s/%s", p.GOOS, p.GOARCH)
+}
+
+// PlatformFromString parses a platform in the form "goos/goarch".
+func PlatformFromString(s string) (Platform, error) {
+	goos, goarch, ok := strings.Cut(s, "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return Platform{}, fmt.Errorf("Invalid platform %q, expected the form goos/goarch", s)
+	}
+	return Platform{GOOS: goos, GOARCH: goarch}, nil
+}
+
+// knownPlatforms are the platforms tried, in order, when looking for a configuration that includes a file.
+// This is most of the output of `go tool dist list`, with the most common platforms first.
+var knownPlatforms = []Platform{
+	{"linux", "amd64"}, {"linux", "arm64"}, {"darwin", "arm64"}, {"darwin", "amd64"},
+	{"windows", "amd64"}, {"windows", "arm64"}, {"linux", "386"}, {"linux", "arm"}, {"windows", "386"},
+	{"freebsd", "amd64"}, {"freebsd", "arm64"}, {"netbsd", "amd64"}, {"openbsd", "amd64"},
+	{"dragonfly", "amd64"}, {"solaris", "amd64"}, {"illumos", "amd64"}, {"aix", "ppc64"},
+	{"android", "arm64"}, {"ios", "arm64"}, {"plan9", "amd64"}, {"js", "wasm"}, {"wasip1", "wasm"},
+	{"linux", "ppc64"}, {"linux", "ppc64le"}, {"linux", "s390x"}, {"linux", "riscv64"}, {"linux", "loong64"},
+	{"linux", "mips"}, {"linux", "mipsle"}, {"linux", "mips64"}, {"linux", "mips64le"},
+}
+
+// IsZero returns whether the config uses the defaults for everything.
+func (c Config) IsZero() bool {
+	return len(c.Tags) == 0 && c.GOOS == "" && c.GOARCH == ""
+}
+
+// Platform returns the target platform, using the default GOOS and GOARCH for fields that aren't set.
+func (c Config) Platform() Platform {
+	p := Platform{GOOS: build.Default.GOOS, GOARCH: build.Default.GOARCH}
+	if c.GOOS != "" {
+		p.GOOS = c.GOOS
+	}
+	if c.GOARCH != "" {
+		p.GOARCH = c.GOARCH
+	}
+	return p
+}
+
+func (c Config) String() string {
+	s := c.Platform().String()
+	if len(c.Tags) > 0 {
+		s += " tags=" + strings.Join(c.Tags, ",")
+	}
+	return s
+}
+
+// Env returns the environment for the go command with this config, suitable for packages.Config.Env.
+// Tags are passed in GOFLAGS, so the on-disk index (which is salted by GOFLAGS) keeps records for each config separate.
+// This returns nil for the zero config, so the go command uses the current environment.
+func (c Config) Env() []string {
+	if c.IsZero() {
+		return nil
+	}
+
+	env := os.Environ()
+	if c.GOOS != "" {
+		env = append(env, "GOOS="+c.GOOS)
+	}
+	if c.GOARCH != "" {
+		env = append(env, "GOARCH="+c.GOARCH)
+	}
+	if len(c.Tags) > 0 {
+		goflags := strings.TrimSpace(os.Getenv("GOFLAGS") + " -tags=" + strings.Join(c.Tags, ","))
+		env = append(env, "GOFLAGS="+goflags)
+	}
+	return env
+}
+
+// MatchFile returns whether the build constraints and file name of a Go file include it in this config.
+func (c Config) MatchFile(path string, overlay file.Overlay) (bool, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, fmt.Errorf("filepath.Abs: %w", err)
+	}
+
+	ctxt := c.buildContext(overlay)
+	match, err := ctxt.MatchFile(filepath.Dir(absPath), filepath.Base(absPath))
+	if err != nil {
+		return false, fmt.Errorf("build.Context.MatchFile: %w", err)
+	}
+	return match, nil
+}
+
+func (c Config) buildContext(overlay file.Overlay) build.Context {
+	ctxt := build.Default
+	platform := c.Platform()
+	if platform.GOOS != build.Default.GOOS || platform.GOARCH != build.Default.GOARCH {
+		// Like the go command, cgo is disabled when cross-compiling unless it's explicitly enabled.
+		ctxt.CgoEnabled = os.Getenv("CGO_ENABLED") == "1"
+	}
+	ctxt.GOOS, ctxt.GOARCH = platform.GOOS, platform.GOARCH
+	ctxt.BuildTags = append(append([]string(nil), ctxt.BuildTags...), c.Tags...)
+	ctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+		data, err := overlay.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return ctxt
+}
+
+// ConfigForFile returns a config that includes a Go file.
+// If base includes the file, this returns base. Otherwise, it tries other platforms (starting with those
+// closest to the base platform), adding any custom tags from the file's build constraint, and returns the first config that includes the file.
+func ConfigForFile(path string, base Config, overlay file.Overlay) (Config, error) {
+	if !strings.HasSuffix(path, ".go") {
+		return base, nil
+	}
+
+	if match, err := base.MatchFile(path, overlay); err != nil || match {
+		// If the file can't be read, let the package loader report the error.
+		return base, nil
+	}
+
+	tagOptions := [][]string{base.Tags}
+	if customTags := customTagsForFile(path, overlay); len(customTags) > 0 {
+		tagOptions = append(tagOptions, append(append([]string(nil), base.Tags...), customTags...))
+	}
+
+	for _, platform := range candidatePlatforms(base) {
+		for _, tags := range tagOptions {
+			config := Config{Tags: tags, GOOS: platform.GOOS, GOARCH: platform.GOARCH}
+			if match, _ := config.MatchFile(path, overlay); match {
+				return config, nil
+			}
+		}
+	}
+
+	return base, fmt.Errorf("Could not find a build configuration that includes %q, try setting the build tags, GOOS, and GOARCH", path)
+}
+
+// candidatePlatforms returns the platforms to try, starting with the base platform,
+// then platforms with the same GOOS, then platforms with the same GOARCH, then everything else.
+func candidatePlatforms(base Config) []Platform {
+	basePlatform := base.Platform()
+	platforms := []Platform{basePlatform}
+	seen := map[Platform]struct{}{basePlatform: {}}
+	add := func(f func(Platform) bool) {
+		for _, p := range knownPlatforms {
+			if _, ok := seen[p]; !ok && f(p) {
+				platforms = append(platforms, p)
+				seen[p] = struct{}{}
+			}
+		}
+	}
+
+	sameGOOS := func(p Platform) bool { return p.GOOS == basePlatform.GOOS }
+	sameGOARCH := func(p Platform) bool { return p.GOARCH == basePlatform.GOARCH }
+	if base.GOARCH != "" && base.GOOS == "" {
+		// Keep the GOARCH the user chose, if possible.
+		add(sameGOARCH)
+		add(sameGOOS)
+	} else {
+		add(sameGOOS)
+		add(sameGOARCH)
+	}
+	add(func(p Platform) bool { return true })
+	return platforms
+}
+
+// customTagsForFile returns the tags in a file's build constraint that aren't platforms, compilers, or Go versions.
+func customTagsForFile(path string, overlay file.Overlay) []string {
+	src, err := overlay.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	astFile, err := parser.ParseFile(token.NewFileSet(), path, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	var tags []string
+	for _, group := range astFile.Comments {
+		if group.Pos() > astFile.Package {
+			break
+		}
+
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+
+			tags = appendCustomTags(tags, expr, false)
+		}
+	}
+	return tags
+}
+
+// appendCustomTags appends the custom tags in an expression that need to be set for the expression to be true.
+func appendCustomTags(tags []string, expr constraint.Expr, negated bool) []string {
+	switch e := expr.(type) {
+	case *constraint.TagExpr:
+		if !negated && isCustomTag(e.Tag) && !slices.Contains(tags, e.Tag) {
+			tags = append(tags, e.Tag)
+		}
+	case *constraint.NotExpr:
+		tags = appendCustomTags(tags, e.X, !negated)
+	case *constraint.AndExpr:
+		tags = appendCustomTags(tags, e.X, negated)
+		tags = appendCustomTags(tags, e.Y, negated)
+	case *constraint.OrExpr:
+		tags = appendCustomTags(tags, e.X, negated)
+		tags = appendCustomTags(tags, e.Y, negated)
+	}
+	return tags
+}
+
+func isCustomTag(tag string) bool {
+	switch tag {
+	case "cgo", "gc", "gccgo", "unix", "ignore":
+		return false
+	}
+
+	if strings.HasPrefix(tag, "go1.") {
+		return false
+	}
+
+	for _, p := range knownPlatforms {
+		if tag == p.GOOS || tag == p.GOARCH {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package buildcfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+func TestConfigForFile(t *testing.T) {
+	testCases := []struct {
+		name        string
+		filename    string
+		src         string
+		base        Config
+		expected    Config
+		expectedErr string
+	}{
+		{
+			name:     "base includes file",
+			filename: "main.go",
+			src:      "package main\n",
+			base:     Config{GOOS: "linux", GOARCH: "amd64"},
+			expected: Config{GOOS: "linux", GOARCH: "amd64"},
+		},
+		{
+			name:     "goos from file name",
+			filename: "main_windows.go",
+			src:      "package main\n",
+			base:     Config{GOOS: "linux", GOARCH: "amd64"},
+			expected: Config{GOOS: "windows", GOARCH: "amd64"},
+		},
+		{
+			name:     "goarch from file name keeps goos",
+			filename: "main_arm64.go",
+			src:      "package main\n",
+			base:     Config{GOOS: "linux", GOARCH: "amd64"},
+			expected: Config{GOOS: "linux", GOARCH: "arm64"},
+		},
+		{
+			name:     "goos from build constraint",
+			filename: "main.go",
+			src:      "//go:build darwin\n\npackage main\n",
+			base:     Config{GOOS: "linux", GOARCH: "arm64"},
+			expected: Config{GOOS: "darwin", GOARCH: "arm64"},
+		},
+		{
+			name:     "custom tag from build constraint",
+			filename: "main.go",
+			src:      "//go:build integration && !race\n\npackage main\n",
+			base:     Config{Tags: []string{"foo"}, GOOS: "linux", GOARCH: "amd64"},
+			expected: Config{Tags: []string{"foo", "integration"}, GOOS: "linux", GOARCH: "amd64"},
+		},
+		{
+			name:     "custom tag and goos from build constraint",
+			filename: "main.go",
+			src:      "//go:build windows && (e2e || integration)\n\npackage main\n",
+			base:     Config{GOOS: "linux", GOARCH: "amd64"},
+			expected: Config{Tags: []string{"e2e", "integration"}, GOOS: "windows", GOARCH: "amd64"},
+		},
+		{
+			name:        "no config includes file",
+			filename:    "main.go",
+			src:         "//go:build ignore\n\npackage main\n",
+			base:        Config{GOOS: "linux", GOARCH: "amd64"},
+			expectedErr: "Could not find a build configuration that includes",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.filename)
+			require.NoError(t, os.WriteFile(path, []byte(tc.src), 0644))
+
+			config, err := ConfigForFile(path, tc.base, nil)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, config)
+		})
+	}
+}
+
+func TestConfigForFileWithOverlay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0644))
+	overlay := file.Overlay{path: []byte("//go:build windows\n\npackage main\n")}
+
+	config, err := ConfigForFile(path, Config{GOOS: "linux", GOARCH: "amd64"}, overlay)
+	require.NoError(t, err)
+	assert.Equal(t, Config{GOOS: "windows", GOARCH: "amd64"}, config)
+}
+
+func TestConfigEnv(t *testing.T) {
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	assert.Nil(t, Config{}.Env())
+
+	env := Config{Tags: []string{"a", "b"}, GOOS: "windows", GOARCH: "arm64"}.Env()
+	assert.Equal(t, []string{"GOOS=windows", "GOARCH=arm64", "GOFLAGS=-mod=mod -tags=a,b"}, env[len(env)-3:])
+}
+
+func TestPlatformFromString(t *testing.T) {
+	platform, err := PlatformFromString("windows/amd64")
+	require.NoError(t, err)
+	assert.Equal(t, Platform{GOOS: "windows", GOARCH: "amd64"}, platform)
+
+	for _, s := range []string{"windows", "windows/", "/amd64", "windows/amd64/v2"} {
+		_, err := PlatformFromString(s)
+		assert.EqualError(t, err, `Invalid platform "`+s+`", expected the form goos/goarch`)
+	}
+}
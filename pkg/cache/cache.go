@@ -0,0 +1,414 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/index"
+)
+
+// MaxEntries is the number of package loads a Cache keeps by default.
+// Each load can hold a complete typed package graph, so the cache is bounded to keep a long-running daemon's memory in check.
+const MaxEntries = 16
+
+// Cache keeps loaded Go packages in memory so they can be reused across requests.
+// Loads are keyed by their config and patterns. When there are more than maxEntries loads,
+// the least recently used one is evicted.
+// A nil *Cache is valid and always loads packages from scratch.
+type Cache struct {
+	mu         sync.Mutex
+	entries    map[string]*entry
+	goEnvs     map[string]goEnv
+	maxEntries int
+	clock      uint64
+}
+
+type entry struct {
+	pkgs []*packages.Package
+
+	// lastUsed is the value of the cache's clock when the entry was last loaded or returned.
+	lastUsed uint64
+
+	// pkgStamps records the state of the files in each package in the graph, by package ID.
+	// Packages in GOROOT and the module cache aren't stamped, since they don't change for a given Go version and go.mod.
+	pkgStamps map[string]map[string]fileStamp
+
+	// layoutStamps records the state of module files, and dirListings records the names in each directory with package files.
+	// If one of these changes, files or packages may have been added or removed, so the patterns need to be loaded again.
+	layoutStamps map[string]fileStamp
+	dirListings  map[string]string
+}
+
+// fileStamp records the state of a file when packages were loaded.
+type fileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// goEnv contains the directories of files that never change for a given Go version and go.mod.
+type goEnv struct {
+	GOROOT     string
+	GOMODCACHE string
+}
+
+func New() *Cache {
+	return &Cache{entries: make(map[string]*entry), goEnvs: make(map[string]goEnv), maxEntries: MaxEntries}
+}
+
+// Load behaves like packages.Load, except that it reuses previously loaded packages whose files haven't changed.
+// If files in some packages changed, only those packages and the packages that import them (transitively) are loaded again.
+func (c *Cache) Load(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+	if c == nil || cfg.ParseFile != nil || len(cfg.Overlay) > 0 {
+		// ParseFile and Overlay can't be compared, so we can't tell whether a cached result is still valid.
+		return packages.Load(cfg, patterns...)
+	}
+
+	// We need the file paths for every package to check whether the cached result is still valid.
+	cfgWithFiles := *cfg
+	cfgWithFiles.Mode |= packages.NeedFiles
+	cfg = &cfgWithFiles
+
+	key := cacheKey(cfg, patterns)
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+
+	var pkgs []*packages.Package
+	if ok && e.isLayoutFresh() {
+		stalePkgIDs := e.stalePkgIDs()
+		if len(stalePkgIDs) == 0 {
+			c.mu.Lock()
+			c.touch(e)
+			c.mu.Unlock()
+			return e.pkgs, nil
+		}
+
+		reloadedPkgs, err := reloadPkgs(cfg, e.pkgs, stalePkgIDs)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = reloadedPkgs
+	} else {
+		loadedPkgs, err := packages.Load(cfg, patterns...)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = loadedPkgs
+	}
+
+	env := c.goEnv(cfg)
+	e = &entry{
+		pkgs:         pkgs,
+		pkgStamps:    pkgStampsForPkgs(pkgs, env),
+		layoutStamps: layoutStampsForDir(cfg.Dir, env),
+		dirListings:  dirListingsForPkgs(pkgs, env),
+	}
+	c.mu.Lock()
+	c.touch(e)
+	c.entries[key] = e
+	c.evict()
+	c.mu.Unlock()
+
+	return pkgs, nil
+}
+
+// touch marks an entry as the most recently used. The caller must hold c.mu.
+func (c *Cache) touch(e *entry) {
+	c.clock++
+	e.lastUsed = c.clock
+}
+
+// evict removes the least recently used entries until there are at most maxEntries. The caller must hold c.mu.
+func (c *Cache) evict() {
+	for len(c.entries) > c.maxEntries {
+		var oldestKey string
+		var oldest *entry
+		for key, e := range c.entries {
+			if oldest == nil || e.lastUsed < oldest.lastUsed {
+				oldestKey, oldest = key, e
+			}
+		}
+		delete(c.entries, oldestKey)
+	}
+}
+
+// Len returns the number of cached package loads.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func cacheKey(cfg *packages.Config, patterns []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "dir=%s\n", cfg.Dir)
+	fmt.Fprintf(&sb, "mode=%d\n", cfg.Mode)
+	fmt.Fprintf(&sb, "tests=%t\n", cfg.Tests)
+	fmt.Fprintf(&sb, "env=%q\n", cfg.Env)
+	fmt.Fprintf(&sb, "buildFlags=%q\n", cfg.BuildFlags)
+	fmt.Fprintf(&sb, "patterns=%q\n", patterns)
+	return sb.String()
+}
+
+// goEnv returns the GOROOT and GOMODCACHE used by the go command for a config, memoized by environment.
+func (c *Cache) goEnv(cfg *packages.Config) goEnv {
+	key := fmt.Sprintf("%q", cfg.Env)
+	c.mu.Lock()
+	env, ok := c.goEnvs[key]
+	c.mu.Unlock()
+	if ok {
+		return env
+	}
+
+	var stdoutBuf bytes.Buffer
+	cmd := exec.Command("go", "env", "GOROOT", "GOMODCACHE")
+	cmd.Dir = cfg.Dir
+	cmd.Env = cfg.Env
+	cmd.Stdout = &stdoutBuf
+	if err := cmd.Run(); err == nil {
+		lines := strings.Split(strings.TrimSpace(stdoutBuf.String()), "\n")
+		if len(lines) == 2 {
+			env = goEnv{GOROOT: lines[0], GOMODCACHE: lines[1]}
+		}
+	}
+	// If the go command fails, the zero value stamps every file, which is slower but still correct.
+
+	c.mu.Lock()
+	c.goEnvs[key] = env
+	c.mu.Unlock()
+	return env
+}
+
+// isImmutable checks whether a file is in GOROOT or the module cache.
+// Their contents are identified by the Go version and go.mod, which are stamped separately.
+func (env goEnv) isImmutable(path string) bool {
+	for _, dir := range []string{env.GOROOT, env.GOMODCACHE} {
+		if dir != "" && strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *entry) isLayoutFresh() bool {
+	if !stampsAreFresh(e.layoutStamps) {
+		return false
+	}
+
+	// Directory listings are compared instead of modification times, since editors often save
+	// by renaming a new file over the old one, which changes the directory without adding or removing files.
+	for dir, listing := range e.dirListings {
+		if listDir(dir) != listing {
+			return false
+		}
+	}
+	return true
+}
+
+// stalePkgIDs returns the IDs of packages with files that changed since they were loaded.
+func (e *entry) stalePkgIDs() map[string]struct{} {
+	stale := make(map[string]struct{})
+	for id, stamps := range e.pkgStamps {
+		if !stampsAreFresh(stamps) {
+			stale[id] = struct{}{}
+		}
+	}
+	return stale
+}
+
+func stampsAreFresh(stamps map[string]fileStamp) bool {
+	for path, stamp := range stamps {
+		if statFile(path) != stamp {
+			return false
+		}
+	}
+	return true
+}
+
+// reloadPkgs loads the stale packages and the packages that import them again, reusing every other package.
+// Reloaded packages have their own copies of their dependencies, so types from reloaded and reused
+// packages should only be compared through the imports of the same package.
+func reloadPkgs(cfg *packages.Config, pkgs []*packages.Package, stalePkgIDs map[string]struct{}) ([]*packages.Package, error) {
+	// Find every package that imports a stale package, directly or transitively.
+	importers := make(map[string][]string)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, importedPkg := range pkg.Imports {
+			importers[importedPkg.ID] = append(importers[importedPkg.ID], pkg.ID)
+		}
+	})
+
+	affected := make(map[string]struct{}, len(stalePkgIDs))
+	queue := make([]string, 0, len(stalePkgIDs))
+	for id := range stalePkgIDs {
+		affected[id] = struct{}{}
+		queue = append(queue, id)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, importerID := range importers[id] {
+			if _, ok := affected[importerID]; !ok {
+				affected[importerID] = struct{}{}
+				queue = append(queue, importerID)
+			}
+		}
+	}
+
+	// Every package in the graph is imported by a root package, so reloading the affected roots reloads every affected package.
+	var reloadPatterns []string
+	seenPatterns := make(map[string]struct{})
+	for _, pkg := range pkgs {
+		if _, ok := affected[pkg.ID]; !ok {
+			continue
+		}
+
+		pattern := index.PatternForPkg(pkg)
+		if _, ok := seenPatterns[pattern]; !ok {
+			seenPatterns[pattern] = struct{}{}
+			reloadPatterns = append(reloadPatterns, pattern)
+		}
+	}
+
+	reloadedPkgs, err := packages.Load(cfg, reloadPatterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	reloadedByID := make(map[string]*packages.Package, len(reloadedPkgs))
+	for _, pkg := range reloadedPkgs {
+		reloadedByID[pkg.ID] = pkg
+	}
+
+	result := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if _, ok := affected[pkg.ID]; !ok {
+			result = append(result, pkg)
+		} else if reloadedPkg, ok := reloadedByID[pkg.ID]; ok {
+			result = append(result, reloadedPkg)
+		}
+	}
+	return result, nil
+}
+
+// pkgStampsForPkgs records the state of the files in every package in the graph, including dependencies,
+// since a change to a dependency can change the type information of the packages that import it.
+func pkgStampsForPkgs(pkgs []*packages.Package, env goEnv) map[string]map[string]fileStamp {
+	pkgStamps := make(map[string]map[string]fileStamp)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		stamps, ok := pkgStamps[pkg.ID]
+		if !ok {
+			stamps = make(map[string]fileStamp)
+		}
+
+		for _, path := range filesForPkg(pkg) {
+			if !env.isImmutable(path) {
+				stamps[path] = statFile(path)
+			}
+		}
+
+		if len(stamps) > 0 {
+			pkgStamps[pkg.ID] = stamps
+		}
+	})
+	return pkgStamps
+}
+
+// layoutStampsForDir records the state of go.mod and the Go version.
+func layoutStampsForDir(dir string, env goEnv) map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+
+	// Changes to go.mod can change which packages and versions are loaded.
+	if goModPath := findGoModForDir(dir); goModPath != "" {
+		stamps[goModPath] = statFile(goModPath)
+	}
+
+	// The standard library only changes when the Go version does.
+	if env.GOROOT != "" {
+		versionPath := filepath.Join(env.GOROOT, "VERSION")
+		stamps[versionPath] = statFile(versionPath)
+	}
+
+	return stamps
+}
+
+// dirListingsForPkgs lists the directories containing package files, except in GOROOT and the module cache.
+func dirListingsForPkgs(pkgs []*packages.Package, env goEnv) map[string]string {
+	listings := make(map[string]string)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, path := range filesForPkg(pkg) {
+			dir := filepath.Dir(path)
+			if _, ok := listings[dir]; !ok && !env.isImmutable(path) {
+				listings[dir] = listDir(dir)
+			}
+		}
+	})
+	return listings
+}
+
+// listDir returns the names in a directory, one per line, with a trailing slash for subdirectories.
+// It returns an empty string if the directory can't be read.
+func listDir(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(e.Name())
+		if e.IsDir() {
+			sb.WriteString("/")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func filesForPkg(pkg *packages.Package) []string {
+	var paths []string
+	for _, files := range [][]string{pkg.GoFiles, pkg.OtherFiles, pkg.IgnoredFiles, pkg.EmbedFiles} {
+		paths = append(paths, files...)
+	}
+	return paths
+}
+
+func findGoModForDir(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := filepath.Join(absDir, "go.mod")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+
+		parentDir := filepath.Dir(absDir)
+		if parentDir == absDir {
+			return ""
+		}
+		absDir = parentDir
+	}
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		// Missing files have a zero stamp, so creating the file later invalidates the entry.
+		return fileStamp{}
+	}
+	return fileStamp{ModTime: info.ModTime(), Size: info.Size()}
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestCacheReusesPackagesUntilFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/testmodule\n\ngo 1.19\n")
+	goFilePath := filepath.Join(dir, "main.go")
+	writeFile(t, goFilePath, "package testmodule\n\nfunc First() {}\n")
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes,
+		Dir:  dir,
+	}
+
+	c := New()
+	firstPkgs, err := c.Load(cfg, ".")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(firstPkgs))
+	assert.NotNil(t, firstPkgs[0].Types.Scope().Lookup("First"))
+
+	secondPkgs, err := c.Load(cfg, ".")
+	require.NoError(t, err)
+	assert.Same(t, firstPkgs[0], secondPkgs[0])
+	assert.Equal(t, 1, c.Len())
+
+	writeFile(t, goFilePath, "package testmodule\n\nfunc Second() {}\n")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(goFilePath, future, future))
+
+	thirdPkgs, err := c.Load(cfg, ".")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(thirdPkgs))
+	assert.NotSame(t, firstPkgs[0], thirdPkgs[0])
+	assert.Nil(t, thirdPkgs[0].Types.Scope().Lookup("First"))
+	assert.NotNil(t, thirdPkgs[0].Types.Scope().Lookup("Second"))
+}
+
+func TestCacheReloadsOnlyStalePackagesAndImporters(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/testmodule\n\ngo 1.19\n")
+	for _, pkgName := range []string{"a", "b", "c"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, pkgName), 0755))
+	}
+	writeFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\nfunc A() {}\n")
+	bGoFilePath := filepath.Join(dir, "b", "b.go")
+	writeFile(t, bGoFilePath, "package b\n\nfunc B() {}\n")
+	writeFile(t, filepath.Join(dir, "c", "c.go"), "package c\n\nimport \"example.com/testmodule/b\"\n\nfunc C() { b.B() }\n")
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes,
+		Dir:  dir,
+	}
+
+	c := New()
+	firstPkgs, err := c.Load(cfg, "./...")
+	require.NoError(t, err)
+	firstByPath := pkgsByPath(firstPkgs)
+	require.Equal(t, 3, len(firstByPath))
+
+	// Save the file by renaming a new file over it, like many editors do.
+	newGoFilePath := filepath.Join(t.TempDir(), "b.go")
+	writeFile(t, newGoFilePath, "package b\n\nfunc B() {}\n\nfunc NewB() {}\n")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(newGoFilePath, future, future))
+	require.NoError(t, os.Rename(newGoFilePath, bGoFilePath))
+
+	secondPkgs, err := c.Load(cfg, "./...")
+	require.NoError(t, err)
+	secondByPath := pkgsByPath(secondPkgs)
+	require.Equal(t, 3, len(secondByPath))
+
+	// The unrelated package is reused, and the changed package and its importer are loaded again.
+	assert.Same(t, firstByPath["example.com/testmodule/a"], secondByPath["example.com/testmodule/a"])
+	assert.NotSame(t, firstByPath["example.com/testmodule/b"], secondByPath["example.com/testmodule/b"])
+	assert.NotSame(t, firstByPath["example.com/testmodule/c"], secondByPath["example.com/testmodule/c"])
+	assert.NotNil(t, secondByPath["example.com/testmodule/b"].Types.Scope().Lookup("NewB"))
+	importedB := secondByPath["example.com/testmodule/c"].Imports["example.com/testmodule/b"]
+	require.NotNil(t, importedB)
+	assert.NotNil(t, importedB.Types.Scope().Lookup("NewB"))
+
+	// Nothing changed since the reload, so everything is reused.
+	thirdPkgs, err := c.Load(cfg, "./...")
+	require.NoError(t, err)
+	for i := range secondPkgs {
+		assert.Same(t, secondPkgs[i], thirdPkgs[i])
+	}
+}
+
+func TestCacheDoesNotStampImmutableFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/testmodule\n\ngo 1.19\n")
+	writeFile(t, filepath.Join(dir, "main.go"), "package testmodule\n\nimport \"fmt\"\n\nfunc Print() { fmt.Println() }\n")
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes,
+		Dir:  dir,
+	}
+
+	c := New()
+	_, err := c.Load(cfg, ".")
+	require.NoError(t, err)
+
+	env := c.goEnv(cfg)
+	require.NotEmpty(t, env.GOROOT)
+	require.Equal(t, 1, len(c.entries))
+	for _, e := range c.entries {
+		assert.Equal(t, 1, len(e.pkgStamps))
+		for path := range e.layoutStamps {
+			if env.isImmutable(path) {
+				assert.Equal(t, filepath.Join(env.GOROOT, "VERSION"), path)
+			}
+		}
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsedEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/testmodule\n\ngo 1.19\n")
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0755))
+		writeFile(t, filepath.Join(dir, name, name+".go"), "package "+name+"\n")
+	}
+
+	// Load always adds NeedFiles, so include it to compute the same keys.
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedFiles, Dir: dir}
+
+	c := New()
+	c.maxEntries = 2
+
+	firstPkgs, err := c.Load(cfg, "./a")
+	require.NoError(t, err)
+	_, err = c.Load(cfg, "./b")
+	require.NoError(t, err)
+
+	// Using the first entry again makes the second one the least recently used.
+	pkgs, err := c.Load(cfg, "./a")
+	require.NoError(t, err)
+	assert.Same(t, firstPkgs[0], pkgs[0])
+
+	_, err = c.Load(cfg, "./c")
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+
+	pkgs, err = c.Load(cfg, "./a")
+	require.NoError(t, err)
+	assert.Same(t, firstPkgs[0], pkgs[0])
+
+	c.mu.Lock()
+	_, hasB := c.entries[cacheKey(cfg, []string{"./b"})]
+	c.mu.Unlock()
+	assert.False(t, hasB)
+}
+
+func TestNilCacheLoadsPackages(t *testing.T) {
+	var c *Cache
+	pkgs, err := c.Load(&packages.Config{Mode: packages.NeedName}, "fmt")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(pkgs))
+	assert.Equal(t, "fmt", pkgs[0].PkgPath)
+	assert.Equal(t, 0, c.Len())
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	err := os.WriteFile(path, []byte(content), 0644)
+	require.NoError(t, err)
+}
+
+func pkgsByPath(pkgs []*packages.Package) map[string]*packages.Package {
+	result := make(map[string]*packages.Package, len(pkgs))
+	for _, pkg := range pkgs {
+		result[pkg.PkgPath] = pkg
+	}
+	return result
+}
@@ -0,0 +1,233 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/wedaly/gospelunk/pkg/cache"
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+	"github.com/wedaly/gospelunk/pkg/list"
+)
+
+// Request is sent by a client to the daemon. Exactly one of Inspect, InspectBatch, or List must be set.
+// Paths in a request must be absolute, since the daemon's working directory may differ from the client's.
+type Request struct {
+	Inspect      *InspectRequest
+	InspectBatch *InspectBatchRequest
+	List         *ListRequest
+}
+
+type InspectRequest struct {
+	Loc file.Loc
+
+	// Symbol locates the identifier by name instead of Loc, if set. See inspect.ResolveSymbol for the format.
+	Symbol string
+
+	SearchDir     string
+	RelationKinds []inspect.RelationKind
+	Options       inspect.Options
+}
+
+type InspectBatchRequest struct {
+	Locs          []file.Loc
+	SearchDir     string
+	RelationKinds []inspect.RelationKind
+	Options       inspect.Options
+}
+
+type ListRequest struct {
+	Patterns []string
+	Options  list.Options
+}
+
+// Response is sent by the daemon to the client.
+// If the request failed, Error describes the failure and the results are empty.
+type Response struct {
+	Inspect      *inspect.Result
+	InspectBatch []inspect.BatchResult
+	List         *list.Result
+	Error        string
+}
+
+// DefaultSocketPath returns the path of the Unix socket used when the user doesn't specify one.
+func DefaultSocketPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "gospelunk.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("gospelunk-%d.sock", os.Getuid()))
+}
+
+// Server answers inspect and list requests, keeping loaded packages in memory between requests.
+type Server struct {
+	cache *cache.Cache
+	index *index.Index
+}
+
+// NewServer returns a server that stores package metadata in idx, which may be nil to disable the on-disk index.
+func NewServer(idx *index.Index) *Server {
+	return &Server{cache: cache.New(), index: idx}
+}
+
+// Serve accepts connections on the listener until it is closed.
+// Each connection carries a single JSON-encoded request followed by a single JSON-encoded response.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("listener.Accept: %w", err)
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("json.Decode: %s", err)})
+		return
+	}
+
+	resp := s.handleRequest(req)
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) handleRequest(req Request) Response {
+	var resp Response
+	switch {
+	case req.Inspect != nil:
+		opts := req.Inspect.Options
+		opts.Cache = s.cache
+		opts.Index = s.index
+		var result *inspect.Result
+		var err error
+		if req.Inspect.Symbol != "" {
+			result, err = inspect.InspectSymbol(req.Inspect.Symbol, req.Inspect.SearchDir, req.Inspect.RelationKinds, opts)
+		} else {
+			result, err = inspect.Inspect(req.Inspect.Loc, req.Inspect.SearchDir, req.Inspect.RelationKinds, opts)
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Inspect = result
+
+	case req.InspectBatch != nil:
+		opts := req.InspectBatch.Options
+		opts.Cache = s.cache
+		opts.Index = s.index
+		resp.InspectBatch = inspect.InspectBatch(req.InspectBatch.Locs, req.InspectBatch.SearchDir, req.InspectBatch.RelationKinds, opts)
+
+	case req.List != nil:
+		opts := req.List.Options
+		opts.Cache = s.cache
+		opts.Index = s.index
+		result, err := list.List(req.List.Patterns, opts)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.List = &result
+		}
+
+	default:
+		resp.Error = "Request must include inspect, inspect batch, or list"
+	}
+	return resp
+}
+
+// ListenAndServe listens on a Unix socket at socketPath and serves requests until the listener fails.
+// It replaces a stale socket file left behind by a previous daemon, but fails if another daemon is still running.
+func ListenAndServe(socketPath string, idx *index.Index) error {
+	if IsRunning(socketPath) {
+		return fmt.Errorf("Daemon already running on socket %q", socketPath)
+	}
+
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("os.Remove: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("net.Listen: %w", err)
+	}
+	defer listener.Close()
+
+	return NewServer(idx).Serve(listener)
+}
+
+// IsRunning checks whether a daemon is accepting connections on socketPath.
+func IsRunning(socketPath string) bool {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Inspect forwards an inspect request to the daemon listening on socketPath.
+func Inspect(socketPath string, req InspectRequest) (*inspect.Result, error) {
+	resp, err := roundTrip(socketPath, Request{Inspect: &req})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Inspect, nil
+}
+
+// InspectBatch forwards an inspect batch request to the daemon listening on socketPath.
+func InspectBatch(socketPath string, req InspectBatchRequest) ([]inspect.BatchResult, error) {
+	resp, err := roundTrip(socketPath, Request{InspectBatch: &req})
+	if err != nil {
+		return nil, err
+	}
+	return resp.InspectBatch, nil
+}
+
+// List forwards a list request to the daemon listening on socketPath.
+func List(socketPath string, req ListRequest) (list.Result, error) {
+	resp, err := roundTrip(socketPath, Request{List: &req})
+	if err != nil {
+		return list.Result{}, err
+	}
+	if resp.List == nil {
+		return list.Result{}, nil
+	}
+	return *resp.List, nil
+}
+
+func roundTrip(socketPath string, req Request) (Response, error) {
+	var resp Response
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return resp, fmt.Errorf("net.Dial: %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, fmt.Errorf("json.Encode: %w", err)
+	}
+
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		if errors.Is(err, io.EOF) {
+			return resp, fmt.Errorf("Daemon closed the connection without a response")
+		}
+		return resp, fmt.Errorf("json.Decode: %w", err)
+	}
+
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+	"github.com/wedaly/gospelunk/pkg/list"
+)
+
+func TestDaemonInspect(t *testing.T) {
+	socketPath := startTestServer(t)
+
+	loc := file.Loc{
+		Path:   absPath(t, "../inspect/testdata/testmodule001/localvar.go"),
+		Line:   7,
+		Column: 32,
+	}
+	searchDir := absPath(t, "../inspect/testdata/testmodule001")
+	relKinds := []inspect.RelationKind{inspect.RelationKindDef}
+
+	expected, err := inspect.Inspect(loc, searchDir, relKinds, inspect.Options{})
+	require.NoError(t, err)
+
+	// Send the request twice to check that the second response (from cached packages) matches.
+	for i := 0; i < 2; i++ {
+		result, err := Inspect(socketPath, InspectRequest{
+			Loc:           loc,
+			SearchDir:     searchDir,
+			RelationKinds: relKinds,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expected, result)
+	}
+}
+
+func TestDaemonInspectBatch(t *testing.T) {
+	socketPath := startTestServer(t)
+
+	locs := []file.Loc{
+		{Path: absPath(t, "../inspect/testdata/testmodule001/localvar.go"), Line: 7, Column: 32},
+		{Path: absPath(t, "testdata/doesnotexist.go"), Line: 1, Column: 1},
+	}
+	searchDir := absPath(t, "../inspect/testdata/testmodule001")
+	relKinds := []inspect.RelationKind{inspect.RelationKindDef}
+
+	expected := inspect.InspectBatch(locs, searchDir, relKinds, inspect.Options{})
+	require.NotEmpty(t, expected[1].Error)
+
+	result, err := InspectBatch(socketPath, InspectBatchRequest{
+		Locs:          locs,
+		SearchDir:     searchDir,
+		RelationKinds: relKinds,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestDaemonList(t *testing.T) {
+	socketPath := startTestServer(t)
+
+	opts := list.Options{Dir: absPath(t, "../list/testdata/testmodule001")}
+	expected, err := list.List([]string{"./..."}, opts)
+	require.NoError(t, err)
+
+	result, err := List(socketPath, ListRequest{
+		Patterns: []string{"./..."},
+		Options:  opts,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestDaemonError(t *testing.T) {
+	socketPath := startTestServer(t)
+
+	_, err := Inspect(socketPath, InspectRequest{
+		Loc:       file.Loc{Path: absPath(t, "testdata/doesnotexist.go"), Line: 1, Column: 1},
+		SearchDir: absPath(t, "."),
+	})
+	assert.Error(t, err)
+}
+
+func startTestServer(t *testing.T) string {
+	// Unix socket paths have a short maximum length, so avoid the long paths from t.TempDir().
+	dir, err := os.MkdirTemp("", "gospelunk")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socketPath := filepath.Join(dir, "test.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go NewServer(nil).Serve(listener)
+	require.True(t, IsRunning(socketPath))
+	return socketPath
+}
+
+func absPath(t *testing.T, path string) string {
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+	return absPath
+}
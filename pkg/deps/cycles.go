@@ -0,0 +1,105 @@
+package deps
+
+// cycles returns one import cycle for each strongly connected component of the graph that contains a cycle.
+// Each cycle starts and ends with the package in the component that sorts first,
+// and follows the shortest path back to it.
+func (g *graph) cycles() [][]string {
+	var result [][]string
+	for _, component := range g.stronglyConnectedComponents() {
+		inComponent := make(map[string]struct{}, len(component))
+		for _, pkgPath := range component {
+			inComponent[pkgPath] = struct{}{}
+		}
+
+		start := component[0]
+		for _, pkgPath := range component {
+			if pkgPath < start {
+				start = pkgPath
+			}
+		}
+
+		if cycle := g.shortestCycle(start, inComponent); cycle != nil {
+			result = append(result, cycle)
+		}
+	}
+	return result
+}
+
+// shortestCycle returns the shortest path of imports from start back to itself,
+// through packages in a strongly connected component.
+func (g *graph) shortestCycle(start string, inComponent map[string]struct{}) []string {
+	prev := make(map[string]string)
+	queue := []string{start}
+	for i := 0; i < len(queue); i++ {
+		for _, importPath := range g.imports[queue[i]] {
+			if _, ok := inComponent[importPath]; !ok {
+				continue
+			}
+
+			if importPath == start {
+				path := []string{start}
+				for cur := queue[i]; cur != start; cur = prev[cur] {
+					path = append([]string{cur}, path...)
+				}
+				return append([]string{start}, path...)
+			}
+
+			if _, ok := prev[importPath]; !ok {
+				prev[importPath] = queue[i]
+				queue = append(queue, importPath)
+			}
+		}
+	}
+	return nil
+}
+
+// stronglyConnectedComponents uses Tarjan's algorithm to find the strongly connected components of the graph.
+// Components are returned in the order they're completed, which is deterministic because packages and imports are sorted.
+func (g *graph) stronglyConnectedComponents() [][]string {
+	var (
+		result  [][]string
+		stack   []string
+		onStack = make(map[string]bool)
+		indexes = make(map[string]int)
+		lowLink = make(map[string]int)
+	)
+
+	var visit func(pkgPath string)
+	visit = func(pkgPath string) {
+		indexes[pkgPath] = len(indexes)
+		lowLink[pkgPath] = indexes[pkgPath]
+		stack = append(stack, pkgPath)
+		onStack[pkgPath] = true
+
+		for _, importPath := range g.imports[pkgPath] {
+			if _, ok := indexes[importPath]; !ok {
+				visit(importPath)
+				lowLink[pkgPath] = min(lowLink[pkgPath], lowLink[importPath])
+			} else if onStack[importPath] {
+				lowLink[pkgPath] = min(lowLink[pkgPath], indexes[importPath])
+			}
+		}
+
+		if lowLink[pkgPath] == indexes[pkgPath] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == pkgPath {
+					break
+				}
+			}
+			result = append(result, component)
+		}
+	}
+
+	for _, pkgPath := range g.pkgPaths {
+		if _, ok := indexes[pkgPath]; !ok {
+			visit(pkgPath)
+		}
+	}
+
+	return result
+}
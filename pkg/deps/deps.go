@@ -0,0 +1,187 @@
+package deps
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/index"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+)
+
+type Options struct {
+	// IncludeExternal includes imports of packages outside the Go modules in the search directory,
+	// like the standard library.
+	IncludeExternal bool
+
+	// ImportersOf restricts the graph to packages matching this pattern and the packages that import them,
+	// directly or transitively. See Rule for the pattern syntax.
+	ImportersOf string
+
+	// Rules are checked against the full import graph, even if it's restricted by other options.
+	Rules []Rule
+
+	// Build is the build configuration used to list packages, since build constraints can change what a package imports.
+	Build buildcfg.Config
+
+	// Index stores package metadata on disk, so unchanged modules don't need to be listed again.
+	// If nil, every call runs `go list`.
+	Index *index.Index `json:"-"`
+}
+
+type Result struct {
+	Packages []Package `json:"packages"`
+
+	// Cycles are import cycles, each starting and ending with the same package.
+	Cycles [][]string `json:"cycles"`
+
+	Violations []Violation `json:"violations"`
+}
+
+type Package struct {
+	ImportPath string   `json:"importPath"`
+	Name       string   `json:"name"`
+	Imports    []string `json:"imports"`
+}
+
+// Deps builds the import graph of the packages in every Go module in searchDir,
+// finds import cycles, and checks layering rules.
+func Deps(searchDir string, opts Options) (Result, error) {
+	var result Result
+
+	searchPkgs, err := inspect.ListSearchPkgs(searchDir, inspect.Options{Build: opts.Build, Index: opts.Index})
+	if err != nil {
+		return result, err
+	}
+
+	g := newGraph(searchPkgs)
+	result.Cycles = g.cycles()
+	result.Violations = g.checkRules(opts.Rules)
+
+	includePkgs := g.internal
+	if opts.ImportersOf != "" {
+		includePkgs = g.importersOf(opts.ImportersOf)
+	}
+
+	for _, pkgPath := range g.pkgPaths {
+		if _, ok := includePkgs[pkgPath]; !ok {
+			continue
+		}
+
+		pkg := Package{ImportPath: pkgPath, Name: g.names[pkgPath], Imports: []string{}}
+		for _, importPath := range g.imports[pkgPath] {
+			_, isIncluded := includePkgs[importPath]
+			_, isInternal := g.internal[importPath]
+			if isIncluded || (opts.IncludeExternal && !isInternal && opts.ImportersOf == "") {
+				pkg.Imports = append(pkg.Imports, importPath)
+			}
+		}
+		result.Packages = append(result.Packages, pkg)
+	}
+
+	return result, nil
+}
+
+// graph is the import graph of the packages in a search directory.
+// External packages have no imports, since only packages in the search directory are listed.
+type graph struct {
+	pkgPaths []string
+	names    map[string]string
+	imports  map[string][]string
+	internal map[string]struct{}
+}
+
+func newGraph(searchPkgs []inspect.SearchPkg) *graph {
+	g := &graph{
+		names:    make(map[string]string),
+		imports:  make(map[string][]string),
+		internal: make(map[string]struct{}),
+	}
+
+	for _, searchPkg := range searchPkgs {
+		g.pkgPaths = append(g.pkgPaths, searchPkg.ImportPath)
+		g.names[searchPkg.ImportPath] = searchPkg.Name
+		g.internal[searchPkg.ImportPath] = struct{}{}
+
+		imports := append([]string(nil), searchPkg.Imports...)
+		sort.Strings(imports)
+		g.imports[searchPkg.ImportPath] = imports
+	}
+
+	sort.Strings(g.pkgPaths)
+	return g
+}
+
+// importersOf returns the packages in the search directory that match a pattern,
+// and every package that imports them directly or transitively.
+func (g *graph) importersOf(pattern string) map[string]struct{} {
+	importers := make(map[string][]string)
+	for _, pkgPath := range g.pkgPaths {
+		for _, importPath := range g.imports[pkgPath] {
+			importers[importPath] = append(importers[importPath], pkgPath)
+		}
+	}
+
+	result := make(map[string]struct{})
+	var queue []string
+	for _, pkgPath := range g.pkgPaths {
+		if matchPkgPattern(pattern, pkgPath) {
+			result[pkgPath] = struct{}{}
+			queue = append(queue, pkgPath)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		for _, importer := range importers[queue[i]] {
+			if _, ok := result[importer]; !ok {
+				result[importer] = struct{}{}
+				queue = append(queue, importer)
+			}
+		}
+	}
+
+	return result
+}
+
+// shortestImportPath returns the shortest chain of imports from a package to a package matching a pattern,
+// or nil if the package doesn't import a matching package directly or transitively.
+func (g *graph) shortestImportPath(from string, toPattern string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for i := 0; i < len(queue); i++ {
+		for _, importPath := range g.imports[queue[i]] {
+			if _, ok := prev[importPath]; ok {
+				continue
+			}
+			prev[importPath] = queue[i]
+
+			if matchPkgPattern(toPattern, importPath) {
+				var path []string
+				for cur := importPath; cur != ""; cur = prev[cur] {
+					path = append([]string{cur}, path...)
+				}
+				return path
+			}
+
+			queue = append(queue, importPath)
+		}
+	}
+	return nil
+}
+
+// matchPkgPattern checks whether an import path matches a pattern.
+// The pattern matches import paths that end with it, starting at a path element,
+// so "pkg/list" matches "github.com/wedaly/gospelunk/pkg/list".
+// A pattern ending in "/..." also matches packages in subdirectories, like in `go list`.
+func matchPkgPattern(pattern string, importPath string) bool {
+	prefix, hasWildcard := strings.CutSuffix(pattern, "/...")
+	expr := "(^|/)" + regexp.QuoteMeta(prefix)
+	if hasWildcard {
+		expr += "(/.*)?$"
+	} else {
+		expr += "$"
+	}
+	ok, _ := regexp.MatchString(expr, importPath)
+	return ok
+}
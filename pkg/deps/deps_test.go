@@ -0,0 +1,156 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testModulePath = "github.com/wedaly/gospelunk/pkg/deps/testdata/testmodule001"
+
+func TestDeps(t *testing.T) {
+	pkgPath := func(name string) string {
+		return testModulePath + "/" + name
+	}
+
+	testCases := []struct {
+		name             string
+		opts             Options
+		expectedPackages []Package
+	}{
+		{
+			name: "packages in search dir",
+			opts: Options{},
+			expectedPackages: []Package{
+				{ImportPath: pkgPath("api"), Name: "api", Imports: []string{pkgPath("store")}},
+				{ImportPath: pkgPath("app"), Name: "main", Imports: []string{pkgPath("api")}},
+				{ImportPath: pkgPath("cyc/x"), Name: "x", Imports: []string{pkgPath("cyc/y")}},
+				{ImportPath: pkgPath("cyc/y"), Name: "y", Imports: []string{pkgPath("cyc/x")}},
+				{ImportPath: pkgPath("store"), Name: "store", Imports: []string{pkgPath("util")}},
+				{ImportPath: pkgPath("util"), Name: "util", Imports: []string{}},
+			},
+		},
+		{
+			name: "include external",
+			opts: Options{IncludeExternal: true},
+			expectedPackages: []Package{
+				{ImportPath: pkgPath("api"), Name: "api", Imports: []string{pkgPath("store")}},
+				{ImportPath: pkgPath("app"), Name: "main", Imports: []string{"fmt", pkgPath("api")}},
+				{ImportPath: pkgPath("cyc/x"), Name: "x", Imports: []string{pkgPath("cyc/y")}},
+				{ImportPath: pkgPath("cyc/y"), Name: "y", Imports: []string{pkgPath("cyc/x")}},
+				{ImportPath: pkgPath("store"), Name: "store", Imports: []string{pkgPath("util"), "strings"}},
+				{ImportPath: pkgPath("util"), Name: "util", Imports: []string{}},
+			},
+		},
+		{
+			name: "importers of",
+			opts: Options{ImportersOf: "store"},
+			expectedPackages: []Package{
+				{ImportPath: pkgPath("api"), Name: "api", Imports: []string{pkgPath("store")}},
+				{ImportPath: pkgPath("app"), Name: "main", Imports: []string{pkgPath("api")}},
+				{ImportPath: pkgPath("store"), Name: "store", Imports: []string{}},
+			},
+		},
+		{
+			name: "importers of with wildcard",
+			opts: Options{ImportersOf: "cyc/..."},
+			expectedPackages: []Package{
+				{ImportPath: pkgPath("cyc/x"), Name: "x", Imports: []string{pkgPath("cyc/y")}},
+				{ImportPath: pkgPath("cyc/y"), Name: "y", Imports: []string{pkgPath("cyc/x")}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Deps("testdata/testmodule001", tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPackages, result.Packages)
+			assert.Equal(t, [][]string{{pkgPath("cyc/x"), pkgPath("cyc/y"), pkgPath("cyc/x")}}, result.Cycles)
+			assert.Empty(t, result.Violations)
+		})
+	}
+}
+
+func TestDepsRules(t *testing.T) {
+	rules, err := ReadRules("testdata/testmodule001/rules.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{From: "store", To: "api"},
+		{From: "api", To: "util"},
+		{From: "app/...", To: "cyc/..."},
+	}, rules)
+
+	result, err := Deps("testdata/testmodule001", Options{Rules: rules})
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{
+			Rule: Rule{From: "api", To: "util"},
+			Path: []string{testModulePath + "/api", testModulePath + "/store", testModulePath + "/util"},
+		},
+	}, result.Violations)
+}
+
+func TestReadRulesInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\npkg/list imports pkg/inspect\n"), 0644))
+
+	_, err := ReadRules(path)
+	assert.EqualError(t, err, `Invalid rule "pkg/list imports pkg/inspect" at `+path+`:2, expected the form "<from> must not import <to>"`)
+}
+
+func TestMatchPkgPattern(t *testing.T) {
+	testCases := []struct {
+		pattern    string
+		importPath string
+		expected   bool
+	}{
+		{pattern: "pkg/list", importPath: "github.com/wedaly/gospelunk/pkg/list", expected: true},
+		{pattern: "list", importPath: "github.com/wedaly/gospelunk/pkg/list", expected: true},
+		{pattern: "ist", importPath: "github.com/wedaly/gospelunk/pkg/list", expected: false},
+		{pattern: "pkg/list", importPath: "github.com/wedaly/gospelunk/pkg/list/sub", expected: false},
+		{pattern: "pkg/list/...", importPath: "github.com/wedaly/gospelunk/pkg/list/sub", expected: true},
+		{pattern: "pkg/list/...", importPath: "github.com/wedaly/gospelunk/pkg/list", expected: true},
+		{pattern: "pkg/list/...", importPath: "github.com/wedaly/gospelunk/pkg/listing", expected: false},
+		{pattern: "fmt", importPath: "fmt", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.importPath, func(t *testing.T) {
+			assert.Equal(t, tc.expected, matchPkgPattern(tc.pattern, tc.importPath))
+		})
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	result := Result{
+		Packages: []Package{
+			{ImportPath: "example.com/a", Name: "a", Imports: []string{"example.com/b", "fmt"}},
+			{ImportPath: "example.com/b", Name: "b", Imports: []string{}},
+		},
+	}
+
+	var dot strings.Builder
+	require.NoError(t, result.WriteDOT(&dot))
+	assert.Equal(t, `digraph deps {
+	"example.com/a";
+	"example.com/b";
+	"example.com/a" -> "example.com/b";
+	"example.com/a" -> "fmt";
+}
+`, dot.String())
+
+	var mermaid strings.Builder
+	require.NoError(t, result.WriteMermaid(&mermaid))
+	assert.Equal(t, `flowchart LR
+	n0["example.com/a"]
+	n1["example.com/b"]
+	n0 --> n1
+	n2["fmt"]
+	n0 --> n2
+`, mermaid.String())
+}
@@ -0,0 +1,84 @@
+package deps
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatDOT     = Format("dot")
+	FormatMermaid = Format("mermaid")
+	FormatJSON    = Format("json")
+)
+
+var AllFormatStrings = []string{
+	string(FormatDOT),
+	string(FormatMermaid),
+	string(FormatJSON),
+}
+
+func FormatFromString(s string) (Format, error) {
+	for _, f := range AllFormatStrings {
+		if s == f {
+			return Format(s), nil
+		}
+	}
+	return Format(""), fmt.Errorf("Invalid format %q, allowed values are [%s]", s, strings.Join(AllFormatStrings, ", "))
+}
+
+// WriteDOT writes the import graph in the Graphviz DOT language.
+func (r Result) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph deps {\n")
+	for _, pkg := range r.Packages {
+		fmt.Fprintf(&sb, "\t%q;\n", pkg.ImportPath)
+	}
+	for _, pkg := range r.Packages {
+		for _, importPath := range pkg.Imports {
+			fmt.Fprintf(&sb, "\t%q -> %q;\n", pkg.ImportPath, importPath)
+		}
+	}
+	sb.WriteString("}\n")
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("io.WriteString: %w", err)
+	}
+	return nil
+}
+
+// WriteMermaid writes the import graph as a Mermaid flowchart.
+// Import paths aren't valid Mermaid node IDs, so nodes are numbered and labeled with the import path.
+func (r Result) WriteMermaid(w io.Writer) error {
+	nodeIDs := make(map[string]string)
+	nodeID := func(importPath string) string {
+		id, ok := nodeIDs[importPath]
+		if !ok {
+			id = fmt.Sprintf("n%d", len(nodeIDs))
+			nodeIDs[importPath] = id
+		}
+		return id
+	}
+
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	for _, pkg := range r.Packages {
+		fmt.Fprintf(&sb, "\t%s[\"%s\"]\n", nodeID(pkg.ImportPath), pkg.ImportPath)
+	}
+	for _, pkg := range r.Packages {
+		for _, importPath := range pkg.Imports {
+			if _, ok := nodeIDs[importPath]; !ok {
+				// External packages aren't in r.Packages, so declare them before the first edge.
+				fmt.Fprintf(&sb, "\t%s[\"%s\"]\n", nodeID(importPath), importPath)
+			}
+			fmt.Fprintf(&sb, "\t%s --> %s\n", nodeID(pkg.ImportPath), nodeID(importPath))
+		}
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("io.WriteString: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package deps
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rule forbids packages matching From from importing packages matching To, directly or transitively.
+// Patterns match import paths that end with the pattern (starting at a path element),
+// and a pattern ending in "/..." also matches packages in subdirectories.
+type Rule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s must not import %s", r.From, r.To)
+}
+
+// Violation is a chain of imports that breaks a rule.
+type Violation struct {
+	Rule Rule `json:"rule"`
+
+	// Path starts with the importing package and ends with the forbidden package.
+	Path []string `json:"path"`
+}
+
+// ruleSeparator separates the patterns in a line of a rules file.
+const ruleSeparator = " must not import "
+
+// ReadRules reads layering rules from a file, one per line, like "pkg/list must not import pkg/inspect".
+// Blank lines and lines starting with "#" are ignored.
+func ReadRules(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		from, to, ok := strings.Cut(line, ruleSeparator)
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" || strings.ContainsAny(from+to, " \t") {
+			return nil, fmt.Errorf("Invalid rule %q at %s:%d, expected the form \"<from> must not import <to>\"", line, path, lineNum)
+		}
+
+		rules = append(rules, Rule{From: from, To: to})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err: %w", err)
+	}
+
+	return rules, nil
+}
+
+// checkRules returns the violations of each rule, with the shortest import chain for each importing package.
+func (g *graph) checkRules(rules []Rule) []Violation {
+	var result []Violation
+	for _, rule := range rules {
+		for _, pkgPath := range g.pkgPaths {
+			if !matchPkgPattern(rule.From, pkgPath) {
+				continue
+			}
+
+			if path := g.shortestImportPath(pkgPath, rule.To); path != nil {
+				result = append(result, Violation{Rule: rule, Path: path})
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,7 @@
+package api
+
+import "github.com/wedaly/gospelunk/pkg/deps/testdata/testmodule001/store"
+
+func Get(key string) string {
+	return store.Load(key)
+}
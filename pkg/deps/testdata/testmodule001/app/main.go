@@ -0,0 +1,11 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wedaly/gospelunk/pkg/deps/testdata/testmodule001/api"
+)
+
+func main() {
+	fmt.Println(api.Get("key"))
+}
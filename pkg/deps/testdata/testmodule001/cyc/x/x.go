@@ -0,0 +1,5 @@
+package x
+
+import "github.com/wedaly/gospelunk/pkg/deps/testdata/testmodule001/cyc/y"
+
+var X = y.Y
@@ -0,0 +1,5 @@
+package y
+
+import "github.com/wedaly/gospelunk/pkg/deps/testdata/testmodule001/cyc/x"
+
+var Y = x.X
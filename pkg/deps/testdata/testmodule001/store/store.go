@@ -0,0 +1,11 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/wedaly/gospelunk/pkg/deps/testdata/testmodule001/util"
+)
+
+func Load(key string) string {
+	return util.Normalize(strings.ToLower(key))
+}
@@ -0,0 +1,5 @@
+package util
+
+func Normalize(s string) string {
+	return s
+}
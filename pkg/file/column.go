@@ -0,0 +1,161 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ColumnUnit is how columns are counted within a line.
+type ColumnUnit string
+
+const (
+	ColumnUnitBytes = ColumnUnit("bytes")
+	ColumnUnitUTF16 = ColumnUnit("utf16")
+	ColumnUnitRunes = ColumnUnit("runes")
+)
+
+var AllColumnUnitStrings = []string{
+	string(ColumnUnitBytes),
+	string(ColumnUnitUTF16),
+	string(ColumnUnitRunes),
+}
+
+func ColumnUnitFromString(s string) (ColumnUnit, error) {
+	for _, u := range AllColumnUnitStrings {
+		if s == u {
+			return ColumnUnit(s), nil
+		}
+	}
+	return ColumnUnit(""), fmt.Errorf("Invalid column unit %q, allowed values are [%s]", s, strings.Join(AllColumnUnitStrings, ", "))
+}
+
+// ColumnConverter converts the columns of locations between bytes and another unit.
+// Offsets are always measured in bytes, so they aren't converted.
+// Each file is read at most once, from the overlay if it has the file.
+type ColumnConverter struct {
+	unit    ColumnUnit
+	overlay Overlay
+	lines   map[string][]string
+}
+
+func NewColumnConverter(unit ColumnUnit, overlay Overlay) *ColumnConverter {
+	return &ColumnConverter{unit: unit, overlay: overlay, lines: make(map[string][]string)}
+}
+
+// FromBytes converts the start and end columns of a location from bytes to the converter's unit.
+func (c *ColumnConverter) FromBytes(loc Loc) (Loc, error) {
+	if c.unit == ColumnUnitBytes || loc.Path == "" {
+		return loc, nil
+	}
+
+	column, err := c.convert(loc.Path, loc.Line, loc.Column, c.unitOffsetForByteOffset)
+	if err != nil {
+		return loc, err
+	}
+
+	if loc.End != (Pos{}) {
+		loc.End.Column, err = c.convert(loc.Path, loc.End.Line, loc.End.Column, c.unitOffsetForByteOffset)
+		if err != nil {
+			return loc, err
+		}
+	}
+
+	loc.Column = column
+	return loc, nil
+}
+
+// ToBytes converts the start and end columns of a location from the converter's unit to bytes.
+func (c *ColumnConverter) ToBytes(loc Loc) (Loc, error) {
+	if c.unit == ColumnUnitBytes || loc.Path == "" {
+		return loc, nil
+	}
+
+	column, err := c.convert(loc.Path, loc.Line, loc.Column, c.byteOffsetForUnitOffset)
+	if err != nil {
+		return loc, err
+	}
+
+	if loc.End != (Pos{}) {
+		loc.End.Column, err = c.convert(loc.Path, loc.End.Line, loc.End.Column, c.byteOffsetForUnitOffset)
+		if err != nil {
+			return loc, err
+		}
+	}
+
+	loc.Column = column
+	return loc, nil
+}
+
+// convert applies f to the zero-indexed offset of a one-indexed column.
+func (c *ColumnConverter) convert(path string, lineNum int, column int, f func(string, int) int) (int, error) {
+	line, err := c.line(path, lineNum)
+	if err != nil {
+		return 0, err
+	}
+	return f(line, column-1) + 1, nil
+}
+
+func (c *ColumnConverter) line(path string, lineNum int) (string, error) {
+	lines, ok := c.lines[path]
+	if !ok {
+		data, err := c.overlay.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		lines = strings.Split(string(data), "\n")
+		c.lines[path] = lines
+	}
+
+	if lineNum < 1 || lineNum > len(lines) {
+		return "", fmt.Errorf("Line %d out of range for %q", lineNum, path)
+	}
+
+	return strings.TrimSuffix(lines[lineNum-1], "\r"), nil
+}
+
+func (c *ColumnConverter) unitOffsetForByteOffset(line string, byteOffset int) int {
+	if c.unit == ColumnUnitRunes {
+		return utf8.RuneCountInString(line[:min(max(byteOffset, 0), len(line))])
+	}
+	return UTF16OffsetForByteOffset(line, byteOffset)
+}
+
+func (c *ColumnConverter) byteOffsetForUnitOffset(line string, unitOffset int) int {
+	if c.unit == ColumnUnitRunes {
+		n := 0
+		for i := range line {
+			if n >= unitOffset {
+				return i
+			}
+			n++
+		}
+		return len(line)
+	}
+	return ByteOffsetForUTF16Offset(line, unitOffset)
+}
+
+// ByteOffsetForUTF16Offset converts an offset in UTF-16 code units within a line to a byte offset.
+func ByteOffsetForUTF16Offset(line string, utf16Offset int) int {
+	n := 0
+	for i, r := range line {
+		if n >= utf16Offset {
+			return i
+		}
+		n += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// UTF16OffsetForByteOffset converts a byte offset within a line to an offset in UTF-16 code units.
+func UTF16OffsetForByteOffset(line string, byteOffset int) int {
+	n := 0
+	for i, r := range line {
+		if i >= byteOffset {
+			break
+		}
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,68 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUTF16Conversion(t *testing.T) {
+	line := `s := "héllo 🌍" + x`
+	xByteOffset := len(`s := "héllo 🌍" + `)
+	xUTF16Offset := len([]rune(`s := "héllo `)) + 2 + len(`" + `)
+
+	assert.Equal(t, xUTF16Offset, UTF16OffsetForByteOffset(line, xByteOffset))
+	assert.Equal(t, xByteOffset, ByteOffsetForUTF16Offset(line, xUTF16Offset))
+}
+
+func TestColumnConverter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nvar s = \"héllo 🌍\" + xyz\n"), 0644))
+
+	// The identifier xyz starts after "var s = \"héllo 🌍\" + ".
+	byteLoc := Loc{Path: path, Line: 3, Column: 25, End: Pos{Line: 3, Column: 28}}
+
+	testCases := []struct {
+		unit     ColumnUnit
+		expected Loc
+	}{
+		{
+			unit:     ColumnUnitBytes,
+			expected: byteLoc,
+		},
+		{
+			unit:     ColumnUnitUTF16,
+			expected: Loc{Path: path, Line: 3, Column: 22, End: Pos{Line: 3, Column: 25}},
+		},
+		{
+			unit:     ColumnUnitRunes,
+			expected: Loc{Path: path, Line: 3, Column: 21, End: Pos{Line: 3, Column: 24}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.unit), func(t *testing.T) {
+			c := NewColumnConverter(tc.unit, nil)
+
+			converted, err := c.FromBytes(byteLoc)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, converted)
+
+			roundTrip, err := c.ToBytes(converted)
+			require.NoError(t, err)
+			assert.Equal(t, byteLoc, roundTrip)
+		})
+	}
+}
+
+func TestColumnUnitFromString(t *testing.T) {
+	unit, err := ColumnUnitFromString("utf16")
+	require.NoError(t, err)
+	assert.Equal(t, ColumnUnitUTF16, unit)
+
+	_, err = ColumnUnitFromString("utf8")
+	assert.EqualError(t, err, `Invalid column unit "utf8", allowed values are [bytes, utf16, runes]`)
+}
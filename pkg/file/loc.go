@@ -1,14 +1,80 @@
 package file
 
-import "fmt"
+import (
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+)
 
 // Loc specifies a location in a file.
+// Line and column are one-indexed, and columns are measured in bytes.
 type Loc struct {
-	Path   string
-	Line   int
-	Column int
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+
+	// Offset is the zero-indexed byte offset of the start of the location, or zero if unknown.
+	Offset int `json:"offset,omitzero"`
+
+	// End is the position just after the text at the location (for example, the end of an identifier),
+	// or the zero Pos if the location is a single point.
+	End Pos `json:"end,omitzero"`
+}
+
+// Pos is a position in the same file as a Loc.
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset,omitzero"`
+}
+
+// LocForPositions returns the location from start to end, which must be in the same file.
+func LocForPositions(start token.Position, end token.Position) Loc {
+	return Loc{
+		Path:   start.Filename,
+		Line:   start.Line,
+		Column: start.Column,
+		Offset: start.Offset,
+		End: Pos{
+			Line:   end.Line,
+			Column: end.Column,
+			Offset: end.Offset,
+		},
+	}
+}
+
+// Start returns the start of the location, without the end position or offset.
+// Use this to compare locations that may have been computed differently.
+func (loc Loc) Start() Loc {
+	return Loc{Path: loc.Path, Line: loc.Line, Column: loc.Column}
 }
 
 func (loc Loc) String() string {
 	return fmt.Sprintf("%s:%d:%d", loc.Path, loc.Line, loc.Column)
 }
+
+// ParseLoc parses a location in the form "path:line:column", the same form returned by Loc.String.
+func ParseLoc(s string) (Loc, error) {
+	// Split from the end, since the path may contain colons.
+	parts := strings.Split(s, ":")
+	if len(parts) < 3 {
+		return Loc{}, fmt.Errorf("Invalid location %q, expected the form path:line:column", s)
+	}
+
+	line, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil {
+		return Loc{}, fmt.Errorf("Invalid line number in location %q", s)
+	}
+
+	column, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return Loc{}, fmt.Errorf("Invalid column number in location %q", s)
+	}
+
+	return Loc{
+		Path:   strings.Join(parts[:len(parts)-2], ":"),
+		Line:   line,
+		Column: column,
+	}, nil
+}
@@ -0,0 +1,114 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Overlay maps absolute file paths to contents that replace the files on disk, such as unsaved editor buffers.
+// It can be used directly as packages.Config.Overlay.
+type Overlay map[string][]byte
+
+// ReadFile returns the overlay contents for a path if there are any, or otherwise the contents of the file on disk.
+func (o Overlay) ReadFile(path string) ([]byte, error) {
+	if len(o) > 0 {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Abs: %w", err)
+		}
+
+		if data, ok := o[absPath]; ok {
+			return data, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	return data, nil
+}
+
+// ReadOverlayJSON reads an overlay in the JSON format used by "go build -overlay",
+// which maps each replaced file to the path of a file with its new contents:
+//
+//	{"Replace": {"foo.go": "/tmp/foo.go"}}
+//
+// Relative paths are resolved relative to the current working directory.
+func ReadOverlayJSON(path string) (Overlay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var overlayJSON struct {
+		Replace map[string]string
+	}
+	if err := json.Unmarshal(data, &overlayJSON); err != nil {
+		return nil, fmt.Errorf("Invalid overlay file %q: %w", path, err)
+	}
+
+	overlay := make(Overlay, len(overlayJSON.Replace))
+	for replacedPath, contentPath := range overlayJSON.Replace {
+		if contentPath == "" {
+			return nil, fmt.Errorf("Invalid overlay file %q: deleting %q isn't supported", path, replacedPath)
+		}
+
+		absPath, err := filepath.Abs(replacedPath)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Abs: %w", err)
+		}
+
+		contents, err := os.ReadFile(contentPath)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		overlay[absPath] = contents
+	}
+
+	return overlay, nil
+}
+
+// ReadOverlayArchive reads an overlay from an archive of file contents, the format editors send to guru and gopls with "-modified".
+// Each file in the archive is its path on one line, its size in bytes on the next line, and then its contents.
+func ReadOverlayArchive(r io.Reader) (Overlay, error) {
+	overlay := make(Overlay)
+	br := bufio.NewReader(r)
+	for {
+		path, err := br.ReadString('\n')
+		if err == io.EOF && path == "" {
+			return overlay, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("Invalid overlay archive: could not read file name: %w", err)
+		}
+		path = strings.TrimSuffix(path, "\n")
+
+		sizeLine, err := br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("Invalid overlay archive: could not read size of %q: %w", path, err)
+		}
+
+		size, err := strconv.Atoi(strings.TrimSuffix(sizeLine, "\n"))
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("Invalid overlay archive: invalid size %q for %q", strings.TrimSpace(sizeLine), path)
+		}
+
+		contents := make([]byte, size)
+		if _, err := io.ReadFull(br, contents); err != nil {
+			return nil, fmt.Errorf("Invalid overlay archive: could not read contents of %q: %w", path, err)
+		}
+
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Abs: %w", err)
+		}
+		overlay[absPath] = contents
+	}
+}
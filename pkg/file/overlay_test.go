@@ -0,0 +1,129 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOverlayJSON(t *testing.T) {
+	dir := t.TempDir()
+	contentPath := filepath.Join(dir, "main.go.tmp")
+	require.NoError(t, os.WriteFile(contentPath, []byte("package main\n"), 0644))
+
+	overlayPath := filepath.Join(dir, "overlay.json")
+	overlayJSON := `{"Replace": {"main.go": "` + contentPath + `"}}`
+	require.NoError(t, os.WriteFile(overlayPath, []byte(overlayJSON), 0644))
+
+	overlay, err := ReadOverlayJSON(overlayPath)
+	require.NoError(t, err)
+
+	absPath, err := filepath.Abs("main.go")
+	require.NoError(t, err)
+	assert.Equal(t, Overlay{absPath: []byte("package main\n")}, overlay)
+}
+
+func TestReadOverlayJSONInvalid(t *testing.T) {
+	testCases := []struct {
+		name        string
+		overlayJSON string
+		expectedErr string
+	}{
+		{
+			name:        "invalid json",
+			overlayJSON: `{"Replace": `,
+			expectedErr: "Invalid overlay file",
+		},
+		{
+			name:        "delete file",
+			overlayJSON: `{"Replace": {"main.go": ""}}`,
+			expectedErr: `deleting "main.go" isn't supported`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			overlayPath := filepath.Join(t.TempDir(), "overlay.json")
+			require.NoError(t, os.WriteFile(overlayPath, []byte(tc.overlayJSON), 0644))
+
+			_, err := ReadOverlayJSON(overlayPath)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErr)
+		})
+	}
+}
+
+func TestReadOverlayArchive(t *testing.T) {
+	testCases := []struct {
+		name        string
+		archive     string
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			name:     "empty",
+			archive:  "",
+			expected: map[string]string{},
+		},
+		{
+			name:    "multiple files",
+			archive: "a.go\n13\npackage main\nb.go\n0\n",
+			expected: map[string]string{
+				"a.go": "package main\n",
+				"b.go": "",
+			},
+		},
+		{
+			name:     "contents without trailing newline",
+			archive:  "a.go\n12\npackage main",
+			expected: map[string]string{"a.go": "package main"},
+		},
+		{
+			name:        "invalid size",
+			archive:     "a.go\nabc\npackage main",
+			expectedErr: `Invalid overlay archive: invalid size "abc" for "a.go"`,
+		},
+		{
+			name:        "truncated contents",
+			archive:     "a.go\n100\npackage main",
+			expectedErr: `Invalid overlay archive: could not read contents of "a.go"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			overlay, err := ReadOverlayArchive(strings.NewReader(tc.archive))
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			expected := make(Overlay, len(tc.expected))
+			for path, contents := range tc.expected {
+				absPath, err := filepath.Abs(path)
+				require.NoError(t, err)
+				expected[absPath] = []byte(contents)
+			}
+			assert.Equal(t, expected, overlay)
+		})
+	}
+}
+
+func TestOverlayReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("on disk"), 0644))
+
+	data, err := Overlay(nil).ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "on disk", string(data))
+
+	data, err = Overlay{path: []byte("in overlay")}.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "in overlay", string(data))
+}
@@ -0,0 +1,196 @@
+package impact
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+// lineRange is an inclusive range of line numbers in a file.
+type lineRange struct {
+	Start int
+	End   int
+}
+
+func (r lineRange) contains(line int) bool {
+	return line >= r.Start && line <= r.End
+}
+
+// gitChangedLines returns the changed lines of every Go file in the diff for a revision range,
+// keyed by absolute path. Line numbers refer to the new version of each file.
+func gitChangedLines(dir string, revRange string) (map[string][]lineRange, error) {
+	rootDir, err := gitRootDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := runGit(dir, "diff", "--no-color", "--no-ext-diff", "--unified=0", "--src-prefix=a/", "--dst-prefix=b/", revRange, "--", "*.go")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseUnifiedDiff(strings.NewReader(diff), rootDir)
+}
+
+// gitRootDir returns the top-level directory of the git repository containing dir.
+func gitRootDir(dir string) (string, error) {
+	rootDir, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(rootDir), nil
+}
+
+// gitEndRev returns the revision at the end of a revision range passed to `git diff`,
+// or an empty string if the diff compares with the working tree (for example, a single revision).
+func gitEndRev(dir string, revRange string) (string, error) {
+	out, err := runGit(dir, "rev-parse", revRange)
+	if err != nil {
+		return "", err
+	}
+
+	// Ranges like A..B, A...B, and A^! have an excluded (negative) revision, and the first included revision is the end.
+	var endRev string
+	var hasExcluded bool
+	for _, rev := range strings.Fields(out) {
+		if strings.HasPrefix(rev, "^") {
+			hasExcluded = true
+		} else if endRev == "" {
+			endRev = rev
+		}
+	}
+
+	if !hasExcluded {
+		return "", nil
+	}
+	return endRev, nil
+}
+
+// gitFileExistsAtRev checks whether a file in the repository at rootDir exists at a revision.
+func gitFileExistsAtRev(rootDir string, rev string, path string) bool {
+	_, err := runGit(rootDir, "cat-file", "-e", gitRevPath(rootDir, rev, path))
+	return err == nil
+}
+
+// gitOverlay returns the contents of files at a revision, for files whose contents differ from the working tree
+// (including files that were deleted from the working tree).
+func gitOverlay(rootDir string, rev string, paths []string) (file.Overlay, error) {
+	overlay := make(file.Overlay)
+	for _, path := range paths {
+		data, err := runGit(rootDir, "show", gitRevPath(rootDir, rev, path))
+		if err != nil {
+			return nil, err
+		}
+
+		if diskData, err := os.ReadFile(path); err == nil && bytes.Equal(diskData, []byte(data)) {
+			continue
+		}
+		overlay[path] = []byte(data)
+	}
+	return overlay, nil
+}
+
+// gitRevPath returns the "rev:path" form of a file at a revision, with the path relative to the repository root.
+func gitRevPath(rootDir string, rev string, path string) string {
+	relPath, err := filepath.Rel(rootDir, path)
+	if err != nil {
+		relPath = path
+	}
+	return fmt.Sprintf("%s:%s", rev, filepath.ToSlash(relPath))
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderrBuf.String()))
+	}
+
+	return stdoutBuf.String(), nil
+}
+
+// parseUnifiedDiff parses the output of `git diff --unified=0` into the changed lines of each file.
+// Deleted files are omitted, since they have no lines in the new version.
+// A hunk that only deletes lines is recorded as a change to the line before the deletion.
+func parseUnifiedDiff(r io.Reader, rootDir string) (map[string][]lineRange, error) {
+	result := make(map[string][]lineRange)
+
+	var path string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if strings.HasPrefix(name, `"`) {
+				// Git quotes paths with unusual characters.
+				unquoted, err := strconv.Unquote(name)
+				if err != nil {
+					return nil, fmt.Errorf("strconv.Unquote: %w", err)
+				}
+				name = unquoted
+			}
+
+			if name == "/dev/null" {
+				path = ""
+			} else {
+				path = filepath.Join(rootDir, filepath.FromSlash(strings.TrimPrefix(name, "b/")))
+			}
+
+		case strings.HasPrefix(line, "@@ ") && path != "":
+			start, count, err := parseHunkNewRange(line)
+			if err != nil {
+				return nil, err
+			}
+
+			if count == 0 {
+				result[path] = append(result[path], lineRange{Start: max(start, 1), End: max(start, 1)})
+			} else {
+				result[path] = append(result[path], lineRange{Start: start, End: start + count - 1})
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Scan: %w", err)
+	}
+
+	return result, nil
+}
+
+// parseHunkNewRange parses the start line and line count for the new version of a file
+// from a hunk header like "@@ -10,2 +12,3 @@".
+func parseHunkNewRange(header string) (int, int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("Invalid hunk header %q", header)
+	}
+
+	startStr, countStr, hasCount := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid hunk header %q", header)
+	}
+
+	count := 1
+	if hasCount {
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid hunk header %q", header)
+		}
+	}
+
+	return start, count, nil
+}
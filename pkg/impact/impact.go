@@ -0,0 +1,459 @@
+package impact
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/cache"
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+	"github.com/wedaly/gospelunk/pkg/list"
+)
+
+type Options struct {
+	// Build is the build configuration used to load packages.
+	Build buildcfg.Config
+
+	// Cache reuses loaded packages across the reference searches for each changed symbol.
+	// If nil, a new cache is used for each call to Impact.
+	Cache *cache.Cache `json:"-"`
+
+	// Index stores package metadata and identifier uses on disk.
+	// If nil, every search loads packages from scratch.
+	Index *index.Index `json:"-"`
+}
+
+type Result struct {
+	// Symbols are the exported definitions whose declarations changed, with their references.
+	Symbols []Symbol `json:"symbols"`
+
+	// Packages are the import paths of packages that changed or reference a changed symbol.
+	Packages []string `json:"packages"`
+
+	// Files are the paths of files that changed or reference a changed symbol.
+	Files []string `json:"files"`
+
+	// Tests are the test functions that changed or reference a changed symbol.
+	Tests []TestFunc `json:"tests"`
+}
+
+// Symbol is an exported definition whose declaration changed.
+type Symbol struct {
+	list.Definition
+	References []inspect.Relation `json:"references"`
+}
+
+// TestFunc is a test, benchmark, fuzz test, or example function.
+type TestFunc struct {
+	file.Loc
+	Pkg  string `json:"pkg"`
+	Name string `json:"name"`
+}
+
+// Impact finds the exported definitions changed by a git revision range (any arguments accepted by `git diff`)
+// and the packages, files, and tests that reference them in searchDir.
+// If the range ends at a revision, changed files are read at that revision, so line numbers in the diff match.
+// References are found in the working tree.
+func Impact(revRange string, searchDir string, opts Options) (Result, error) {
+	var result Result
+
+	if opts.Cache == nil {
+		opts.Cache = cache.New()
+	}
+
+	changedLines, err := gitChangedLines(searchDir, revRange)
+	if err != nil {
+		return result, err
+	}
+
+	endRev, err := gitEndRev(searchDir, revRange)
+	if err != nil {
+		return result, err
+	}
+
+	rootDir, err := gitRootDir(searchDir)
+	if err != nil {
+		return result, err
+	}
+
+	paths := make([]string, 0, len(changedLines))
+	for path := range changedLines {
+		if endRev != "" {
+			// Files are read at the end of the range, even if they were deleted from the working tree since then.
+			if !gitFileExistsAtRev(rootDir, endRev, path) {
+				continue
+			}
+		} else if _, err := os.Stat(path); os.IsNotExist(err) {
+			// The file was deleted or renamed in the working tree.
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var overlay file.Overlay
+	if endRev != "" {
+		overlay, err = gitOverlay(rootDir, endRev, paths)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	a := &analyzer{
+		searchDir: searchDir,
+		opts:      opts,
+		files:     make(map[string]struct{}),
+		tests:     make(map[TestFunc]struct{}),
+		astFiles:  make(map[string]*ast.File),
+		fset:      token.NewFileSet(),
+		overlay:   overlay,
+	}
+
+	for _, path := range paths {
+		if err := a.analyzeFile(path, changedLines[path]); err != nil {
+			return result, err
+		}
+	}
+
+	return a.result()
+}
+
+type analyzer struct {
+	searchDir string
+	opts      Options
+	symbols   []Symbol
+	files     map[string]struct{}
+	tests     map[TestFunc]struct{}
+	astFiles  map[string]*ast.File
+	fset      *token.FileSet
+
+	// overlay contains the changed files at the end of the revision range, if they differ from the working tree.
+	overlay file.Overlay
+}
+
+// analyzeFile finds the definitions in a file that contain changed lines, then searches for references to them.
+func (a *analyzer) analyzeFile(path string, changed []lineRange) error {
+	astFile, err := a.parseFile(path)
+	if err != nil {
+		return err
+	}
+
+	listResult, err := list.List([]string{fmt.Sprintf("file=%s", path)}, list.Options{
+		IncludeStructFields:     true,
+		IncludeInterfaceMethods: true,
+		IncludePrivate:          true,
+		IncludeTests:            isGoTestFile(path),
+		Build:                   a.opts.Build,
+		Cache:                   a.opts.Cache,
+		Index:                   a.opts.Index,
+		Overlay:                 a.overlay,
+	})
+	if err != nil {
+		return err
+	}
+
+	decls := declsInFile(a.fset, astFile)
+
+	type changedDef struct {
+		def  list.Definition
+		decl decl
+	}
+	var candidates []changedDef
+	for _, def := range listResult.Defs {
+		if def.Path != path {
+			continue
+		}
+
+		d, ok := decls[declKey{Line: def.Line, Column: def.Column, Name: shortName(def.Name)}]
+		if ok {
+			candidates = append(candidates, changedDef{def: def, decl: d})
+		}
+	}
+
+	// Attribute each changed line to the innermost definition containing it,
+	// so a change to a field isn't reported as a change to the struct type.
+	changedDefs := make(map[int]struct{})
+	for _, r := range changed {
+		for line := r.Start; line <= r.End; line++ {
+			innermost := -1
+			for i, c := range candidates {
+				if !c.decl.lines.contains(line) {
+					continue
+				}
+				if innermost < 0 || c.decl.lines.End-c.decl.lines.Start < candidates[innermost].decl.lines.End-candidates[innermost].decl.lines.Start {
+					innermost = i
+				}
+			}
+
+			if innermost >= 0 {
+				changedDefs[innermost] = struct{}{}
+			}
+		}
+	}
+
+	a.files[path] = struct{}{}
+
+	for i, c := range candidates {
+		if _, ok := changedDefs[i]; !ok {
+			continue
+		}
+
+		identPosition := a.fset.Position(c.decl.ident.Pos())
+		identLoc := file.Loc{Path: path, Line: identPosition.Line, Column: identPosition.Column}
+
+		if isGoTestFile(path) && c.def.Kind == list.DefKindFunc && isTestFuncName(c.def.Name) {
+			// Test functions are exported, but they aren't part of the API, so nothing references them.
+			if err := a.addTest(path, c.def.Name, identLoc); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !c.def.Exported {
+			continue
+		}
+
+		inspectResult, err := inspect.Inspect(identLoc, a.searchDir, []inspect.RelationKind{inspect.RelationKindRef}, inspect.Options{
+			IncludeTests: true,
+			Build:        a.opts.Build,
+			Cache:        a.opts.Cache,
+			Index:        a.opts.Index,
+			Overlay:      a.overlay,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range inspectResult.Relations {
+			a.files[ref.Path] = struct{}{}
+			if err := a.addEnclosingTest(ref.Loc); err != nil {
+				return err
+			}
+		}
+
+		a.symbols = append(a.symbols, Symbol{Definition: c.def, References: inspectResult.Relations})
+	}
+
+	return nil
+}
+
+// addEnclosingTest records the test function containing a location in a test file, if any.
+func (a *analyzer) addEnclosingTest(loc file.Loc) error {
+	if !isGoTestFile(loc.Path) {
+		return nil
+	}
+
+	astFile, err := a.parseFile(loc.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range astFile.Decls {
+		funcDecl, ok := d.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil || !isTestFuncName(funcDecl.Name.Name) {
+			continue
+		}
+
+		start, end := a.fset.Position(funcDecl.Pos()), a.fset.Position(funcDecl.End())
+		if loc.Line >= start.Line && loc.Line <= end.Line {
+			namePosition := a.fset.Position(funcDecl.Name.Pos())
+			return a.addTest(loc.Path, funcDecl.Name.Name, file.Loc{Path: loc.Path, Line: namePosition.Line, Column: namePosition.Column})
+		}
+	}
+
+	return nil
+}
+
+func (a *analyzer) addTest(path string, name string, loc file.Loc) error {
+	pkgPath, err := a.pkgPathForFile(path)
+	if err != nil {
+		return err
+	}
+
+	a.tests[TestFunc{Loc: loc, Pkg: pkgPath, Name: name}] = struct{}{}
+	return nil
+}
+
+// pkgPathForFile returns the import path of the package containing a Go file.
+// For files in an external test package (like "foo_test"), this is the import path of the package under test,
+// since that's the package passed to `go test`.
+func (a *analyzer) pkgPathForFile(path string) (string, error) {
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles,
+		Dir:     filepath.Dir(path),
+		Tests:   true,
+		Env:     a.opts.Build.Env(),
+		Overlay: a.overlay,
+	}
+
+	pkgs, err := a.opts.Cache.Load(cfg, ".")
+	if err != nil {
+		return "", fmt.Errorf("packages.Load: %w", err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, goFilePath := range pkg.GoFiles {
+			if goFilePath == path {
+				return strings.TrimSuffix(pkg.PkgPath, "_test"), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find Go package for path %q", path)
+}
+
+func (a *analyzer) parseFile(path string) (*ast.File, error) {
+	if astFile, ok := a.astFiles[path]; ok {
+		return astFile, nil
+	}
+
+	src, err := a.overlay.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	astFile, err := parser.ParseFile(a.fset, path, src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parser.ParseFile: %w", err)
+	}
+
+	a.astFiles[path] = astFile
+	return astFile, nil
+}
+
+func (a *analyzer) result() (Result, error) {
+	result := Result{Symbols: a.symbols}
+
+	pkgSet := make(map[string]struct{})
+	for path := range a.files {
+		result.Files = append(result.Files, path)
+
+		pkgPath, err := a.pkgPathForFile(path)
+		if err != nil {
+			return result, err
+		}
+		pkgSet[pkgPath] = struct{}{}
+	}
+	sort.Strings(result.Files)
+
+	for pkgPath := range pkgSet {
+		result.Packages = append(result.Packages, pkgPath)
+	}
+	sort.Strings(result.Packages)
+
+	for test := range a.tests {
+		result.Tests = append(result.Tests, test)
+	}
+	sort.Slice(result.Tests, func(i, j int) bool {
+		if result.Tests[i].Pkg != result.Tests[j].Pkg {
+			return result.Tests[i].Pkg < result.Tests[j].Pkg
+		}
+		return result.Tests[i].Name < result.Tests[j].Name
+	})
+
+	return result, nil
+}
+
+// declKey identifies a definition by the position reported by list.List and its unqualified name.
+type declKey struct {
+	Line   int
+	Column int
+	Name   string
+}
+
+// decl is the declaration of a definition in a source file.
+type decl struct {
+	ident *ast.Ident
+	lines lineRange
+}
+
+// declsInFile finds the declarations that list.List reports as definitions:
+// top-level funcs, methods, types, vars, and consts, along with struct fields and interface methods.
+func declsInFile(fset *token.FileSet, astFile *ast.File) map[declKey]decl {
+	decls := make(map[declKey]decl)
+	add := func(keyPos token.Pos, ident *ast.Ident, node ast.Node) {
+		keyPosition := fset.Position(keyPos)
+		decls[declKey{Line: keyPosition.Line, Column: keyPosition.Column, Name: ident.Name}] = decl{
+			ident: ident,
+			lines: lineRange{Start: fset.Position(node.Pos()).Line, End: fset.Position(node.End()).Line},
+		}
+	}
+
+	addFields := func(fields *ast.FieldList) {
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				add(field.Pos(), name, field)
+			}
+		}
+	}
+
+	for _, d := range astFile.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			add(d.Pos(), d.Name, d)
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						add(spec.Pos(), name, spec)
+					}
+				case *ast.TypeSpec:
+					add(spec.Pos(), spec.Name, spec)
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						addFields(t.Fields)
+					case *ast.InterfaceType:
+						addFields(t.Methods)
+					}
+				}
+			}
+		}
+	}
+
+	return decls
+}
+
+// shortName removes the type name from a method or field name like "T.Method".
+func shortName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func isGoTestFile(path string) bool {
+	return strings.HasSuffix(filepath.Base(path), "_test.go")
+}
+
+// isTestFuncName checks whether a function name is recognized by `go test`,
+// like TestFoo, BenchmarkFoo, FuzzFoo, or ExampleFoo (but not Testfoo).
+func isTestFuncName(name string) bool {
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+		suffix, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+
+		if suffix == "" {
+			return true
+		}
+
+		r, _ := utf8.DecodeRuneInString(suffix)
+		return !unicode.IsLower(r)
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package impact
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+func TestImpact(t *testing.T) {
+	dir := gitRepoFromTestdata(t, "testdata/testmodule001")
+
+	// Change an exported method, an unexported function, and a test function.
+	replaceInFile(t, filepath.Join(dir, "shapes/shapes.go"), "return s.Side * s.Side", "return s.Side * s.Side * 1")
+	replaceInFile(t, filepath.Join(dir, "shapes/shapes.go"), "return s.label", "return \"side \" + s.label")
+	replaceInFile(t, filepath.Join(dir, "shapes/shapes_test.go"), "!= 8 {\n\t\tt.Fail()", "!= 8 {\n\t\tt.Error(\"wrong perimeter\")")
+	runGitInTest(t, dir, "commit", "-q", "-a", "-m", "change")
+
+	result, err := Impact("HEAD~1..HEAD", dir, Options{})
+	require.NoError(t, err)
+
+	require.Len(t, result.Symbols, 1)
+	assert.Equal(t, "Square.Area", result.Symbols[0].Name)
+
+	var refLocs []file.Loc
+	for _, ref := range result.Symbols[0].References {
+		refLocs = append(refLocs, ref.Loc.Start())
+	}
+	assert.Equal(t, []file.Loc{
+		{Path: filepath.Join(dir, "report/report.go"), Line: 10, Column: 46},
+		{Path: filepath.Join(dir, "shapes/shapes_test.go"), Line: 6, Column: 18},
+	}, refLocs)
+
+	assert.Equal(t, []string{
+		"github.com/wedaly/gospelunk/pkg/impact/testdata/testmodule001/report",
+		"github.com/wedaly/gospelunk/pkg/impact/testdata/testmodule001/shapes",
+	}, result.Packages)
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "report/report.go"),
+		filepath.Join(dir, "shapes/shapes.go"),
+		filepath.Join(dir, "shapes/shapes_test.go"),
+	}, result.Files)
+
+	assert.Equal(t, []TestFunc{
+		{
+			Loc:  file.Loc{Path: filepath.Join(dir, "shapes/shapes_test.go"), Line: 5, Column: 6},
+			Pkg:  "github.com/wedaly/gospelunk/pkg/impact/testdata/testmodule001/shapes",
+			Name: "TestArea",
+		},
+		{
+			Loc:  file.Loc{Path: filepath.Join(dir, "shapes/shapes_test.go"), Line: 11, Column: 6},
+			Pkg:  "github.com/wedaly/gospelunk/pkg/impact/testdata/testmodule001/shapes",
+			Name: "TestPerimeter",
+		},
+	}, result.Tests)
+}
+
+func TestImpactRangeEndingBeforeWorkingTree(t *testing.T) {
+	dir := gitRepoFromTestdata(t, "testdata/testmodule001")
+
+	replaceInFile(t, filepath.Join(dir, "shapes/shapes.go"), "return s.Side * s.Side", "return s.Side * s.Side * 1")
+	runGitInTest(t, dir, "commit", "-q", "-a", "-m", "change")
+
+	// Move every definition after NewSquare down in the working tree, so the changed line in the diff is in NewSquare.
+	replaceInFile(t, filepath.Join(dir, "shapes/shapes.go"), "func NewSquare", "// NewSquare constructs a square.\n//\n// The label is empty.\n//\nfunc NewSquare")
+
+	for _, revRange := range []string{"HEAD~1..HEAD", "HEAD~1...HEAD", "HEAD^!"} {
+		t.Run(revRange, func(t *testing.T) {
+			result, err := Impact(revRange, dir, Options{})
+			require.NoError(t, err)
+
+			require.Len(t, result.Symbols, 1)
+			assert.Equal(t, "Square.Area", result.Symbols[0].Name)
+			assert.Equal(t, 12, result.Symbols[0].Line)
+			assert.Len(t, result.Symbols[0].References, 2)
+		})
+	}
+}
+
+func TestImpactFileDeletedAfterRange(t *testing.T) {
+	dir := gitRepoFromTestdata(t, "testdata/testmodule001")
+
+	replaceInFile(t, filepath.Join(dir, "shapes/shapes.go"), "return s.Side * s.Side", "return s.Side * s.Side * 1")
+	runGitInTest(t, dir, "commit", "-q", "-a", "-m", "change")
+	require.NoError(t, os.Remove(filepath.Join(dir, "shapes/shapes.go")))
+
+	result, err := Impact("HEAD~1..HEAD", dir, Options{})
+	require.NoError(t, err)
+
+	require.Len(t, result.Symbols, 1)
+	assert.Equal(t, "Square.Area", result.Symbols[0].Name)
+	assert.Equal(t, filepath.Join(dir, "shapes/shapes.go"), result.Symbols[0].Path)
+	assert.Len(t, result.Symbols[0].References, 2)
+	assert.Contains(t, result.Files, filepath.Join(dir, "shapes/shapes.go"))
+}
+
+func TestImpactNoChanges(t *testing.T) {
+	dir := gitRepoFromTestdata(t, "testdata/testmodule001")
+
+	result, err := Impact("HEAD", dir, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, Result{}, result)
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := `diff --git a/foo.go b/foo.go
+index 1111111..2222222 100644
+--- a/foo.go
++++ b/foo.go
+@@ -3 +3 @@ package foo
+-var x = 1
++var x = 2
+@@ -10,2 +10,0 @@ func f() {
+-	a()
+-	b()
+@@ -20,0 +19,3 @@ func g() {
++	c()
++	d()
++	e()
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package foo
+diff --git "a/sp ace.go" "b/sp ace.go"
+--- "a/sp ace.go"
++++ "b/sp ace.go"
+@@ -1 +1 @@
+-package foo
++package bar
+`
+	result, err := parseUnifiedDiff(strings.NewReader(diff), "/repo")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]lineRange{
+		"/repo/foo.go": {
+			{Start: 3, End: 3},
+			{Start: 10, End: 10},
+			{Start: 19, End: 21},
+		},
+		"/repo/sp ace.go": {
+			{Start: 1, End: 1},
+		},
+	}, result)
+}
+
+// gitRepoFromTestdata copies a testdata module to a temporary git repository with a single commit.
+func gitRepoFromTestdata(t *testing.T, path string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.CopyFS(dir, os.DirFS(path)))
+
+	runGitInTest(t, dir, "init", "-q")
+	runGitInTest(t, dir, "add", ".")
+	runGitInTest(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func runGitInTest(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func replaceInFile(t *testing.T, path string, old string, new string) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), old)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), old, new, 1)), 0644))
+}
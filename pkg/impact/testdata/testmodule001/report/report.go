@@ -0,0 +1,11 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/wedaly/gospelunk/pkg/impact/testdata/testmodule001/shapes"
+)
+
+func Describe(s shapes.Square) string {
+	return fmt.Sprintf("square with area %f", s.Area())
+}
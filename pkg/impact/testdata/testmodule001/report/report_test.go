@@ -0,0 +1,14 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/wedaly/gospelunk/pkg/impact/testdata/testmodule001/report"
+	"github.com/wedaly/gospelunk/pkg/impact/testdata/testmodule001/shapes"
+)
+
+func TestDescribe(t *testing.T) {
+	if report.Describe(shapes.NewSquare(1)) == "" {
+		t.Fail()
+	}
+}
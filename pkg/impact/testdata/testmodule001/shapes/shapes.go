@@ -0,0 +1,22 @@
+package shapes
+
+type Square struct {
+	Side  float64
+	label string
+}
+
+func NewSquare(side float64) Square {
+	return Square{Side: side}
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func (s Square) Perimeter() float64 {
+	return 4 * s.Side
+}
+
+func describeSide(s Square) string {
+	return s.label
+}
@@ -0,0 +1,15 @@
+package shapes
+
+import "testing"
+
+func TestArea(t *testing.T) {
+	if NewSquare(2).Area() != 4 {
+		t.Fail()
+	}
+}
+
+func TestPerimeter(t *testing.T) {
+	if NewSquare(2).Perimeter() != 8 {
+		t.Fail()
+	}
+}
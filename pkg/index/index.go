@@ -0,0 +1,315 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Index stores records on disk so they can be reused across processes.
+// Records are keyed by hashes of file contents, so a record is never stale:
+// when a file changes, its key changes and the old record is ignored.
+// A nil *Index is valid and never stores anything.
+type Index struct {
+	dir string
+
+	mu         sync.Mutex
+	fileHashes map[string]fileHash
+}
+
+// fileHash memoizes the content hash of a file, so unchanged files aren't read again.
+type fileHash struct {
+	ModTime time.Time
+	Size    int64
+	Hash    string
+}
+
+// DefaultDir returns the directory used when the user doesn't specify one.
+func DefaultDir() (string, error) {
+	cacheDir := os.Getenv("XDG_CACHE_HOME")
+	if cacheDir == "" {
+		var err error
+		cacheDir, err = os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("os.UserCacheDir: %w", err)
+		}
+	}
+	return filepath.Join(cacheDir, "gospelunk"), nil
+}
+
+// Open returns an index that stores records in dir, creating the directory if necessary.
+func Open(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	return &Index{dir: dir, fileHashes: make(map[string]fileHash)}, nil
+}
+
+// Get loads the record for a key into v. It returns false if there is no such record.
+// Records that can't be decoded (for example, if a previous write was interrupted) are treated as missing.
+func (idx *Index) Get(namespace string, key string, v any) (bool, error) {
+	if idx == nil {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(idx.recordPath(namespace, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Put stores the record for a key, replacing any existing record.
+func (idx *Index) Put(namespace string, key string, v any) error {
+	if idx == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	path := idx.recordPath(namespace, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	// Write to a temporary file, then rename, so concurrent readers never see a partial record.
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("os.CreateTemp: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("tmpFile.Write: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("tmpFile.Close: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("os.Rename: %w", err)
+	}
+
+	return nil
+}
+
+func (idx *Index) recordPath(namespace string, key string) string {
+	return filepath.Join(idx.dir, namespace, key[:2], key+".json")
+}
+
+// HashFile returns a hash of a file's contents.
+// Missing files have an empty hash, so creating the file later changes the hash.
+func (idx *Index) HashFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("os.Stat: %w", err)
+	}
+
+	idx.mu.Lock()
+	fh, ok := idx.fileHashes[path]
+	idx.mu.Unlock()
+
+	if ok && fh.ModTime.Equal(info.ModTime()) && fh.Size == info.Size() {
+		return fh.Hash, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("io.Copy: %w", err)
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	idx.mu.Lock()
+	idx.fileHashes[path] = fileHash{ModTime: info.ModTime(), Size: info.Size(), Hash: hash}
+	idx.mu.Unlock()
+
+	return hash, nil
+}
+
+// PackageKeys returns a key for each package in the graph, indexed by package ID.
+// The key covers the contents of the package's files and (recursively) the keys of its imports,
+// since a change to a dependency can change the type information of the packages that import it.
+// The packages must be loaded with NeedFiles, NeedImports, and NeedDeps.
+func (idx *Index) PackageKeys(pkgs []*packages.Package, salt string) (map[string]string, error) {
+	keys := make(map[string]string)
+	var visitErr error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if visitErr != nil {
+			return
+		}
+
+		parts := []string{salt, pkg.ID}
+		for _, path := range pkg.GoFiles {
+			hash, err := idx.HashFile(path)
+			if err != nil {
+				visitErr = err
+				return
+			}
+			parts = append(parts, path, hash)
+		}
+
+		importPaths := make([]string, 0, len(pkg.Imports))
+		for importPath := range pkg.Imports {
+			importPaths = append(importPaths, importPath)
+		}
+		sort.Strings(importPaths)
+
+		for _, importPath := range importPaths {
+			// Visit calls this func for imports before the packages that import them.
+			parts = append(parts, importPath, keys[pkg.Imports[importPath].ID])
+		}
+
+		keys[pkg.ID] = HashStrings(parts...)
+	})
+
+	if visitErr != nil {
+		return nil, visitErr
+	}
+
+	return keys, nil
+}
+
+// DirKey returns a key covering every Go file that `go list ./...` would find in dir,
+// along with the module files that control how those packages are resolved.
+func (idx *Index) DirKey(dir string, salt string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("filepath.Abs: %w", err)
+	}
+
+	parts := []string{salt, absDir}
+
+	for _, path := range moduleFilesForDir(absDir) {
+		hash, err := idx.HashFile(path)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, path, hash)
+	}
+
+	err = filepath.WalkDir(absDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path == absDir {
+				return nil
+			}
+
+			// Skip the same directories as the go command: nested modules, testdata,
+			// vendor, and directories beginning with "." or "_".
+			name := d.Name()
+			if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		hash, err := idx.HashFile(path)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, path, hash)
+		return nil
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("filepath.WalkDir: %w", err)
+	}
+
+	return HashStrings(parts...), nil
+}
+
+// moduleFilesForDir returns the paths of module files that affect how packages in dir are resolved.
+// Files that don't exist are included, so creating them later changes the key.
+func moduleFilesForDir(dir string) []string {
+	for {
+		goModPath := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(goModPath); err == nil {
+			return []string{goModPath, filepath.Join(dir, "go.sum"), filepath.Join(dir, "go.work")}
+		}
+
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return nil
+		}
+		dir = parentDir
+	}
+}
+
+// PatternForPkg returns the pattern that loads a package by import path.
+// Test variants (like "foo [foo.test]", "foo_test [foo.test]", or "foo.test") can't be loaded by
+// their own path, so this returns the path of the package under test. Load them with Tests set.
+func PatternForPkg(pkg *packages.Package) string {
+	if _, variant, ok := strings.Cut(pkg.ID, " ["); ok {
+		return strings.TrimSuffix(strings.TrimSuffix(variant, "]"), ".test")
+	}
+	return strings.TrimSuffix(pkg.PkgPath, ".test")
+}
+
+// HashStrings returns a hash of a sequence of strings.
+func HashStrings(parts ...string) string {
+	h := sha256.New()
+	for _, s := range parts {
+		// Include the length so different sequences can't produce the same input to the hash.
+		fmt.Fprintf(h, "%d:%s\n", len(s), s)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// EnvSalt returns a string covering environment variables that change how the go command loads packages.
+// Include it in keys so records loaded for one platform aren't reused for another.
+func EnvSalt(env []string) string {
+	vars := []string{"GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED", "GOEXPERIMENT", "GOWORK"}
+	parts := make([]string, 0, len(vars))
+	for _, v := range vars {
+		value := os.Getenv(v)
+		for _, kv := range env {
+			if k, val, ok := strings.Cut(kv, "="); ok && k == v {
+				value = val
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", v, value))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,140 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestGetAndPut(t *testing.T) {
+	idx, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	type record struct {
+		Names []string
+	}
+
+	key := HashStrings("test")
+
+	var missing record
+	ok, err := idx.Get("test", key, &missing)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, idx.Put("test", key, record{Names: []string{"foo", "bar"}}))
+
+	var found record
+	ok, err = idx.Get("test", key, &found)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, record{Names: []string{"foo", "bar"}}, found)
+
+	// Records are separated by namespace.
+	ok, err = idx.Get("other", key, &found)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNilIndex(t *testing.T) {
+	var idx *Index
+	require.NoError(t, idx.Put("test", HashStrings("test"), "value"))
+
+	var value string
+	ok, err := idx.Get("test", HashStrings("test"), &value)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDirKeyChangesWhenGoFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/testmodule\n\ngo 1.19\n")
+	goFilePath := filepath.Join(dir, "main.go")
+	writeFile(t, goFilePath, "package testmodule\n")
+
+	idx, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	firstKey, err := idx.DirKey(dir, "")
+	require.NoError(t, err)
+
+	// Files the go command ignores don't change the key.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "testdata"), 0755))
+	writeFile(t, filepath.Join(dir, "testdata", "ignored.go"), "package ignored\n")
+	writeFile(t, filepath.Join(dir, "README.md"), "readme\n")
+
+	secondKey, err := idx.DirKey(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, firstKey, secondKey)
+
+	writeFile(t, goFilePath, "package testmodule\n\nimport \"fmt\"\n")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(goFilePath, future, future))
+
+	thirdKey, err := idx.DirKey(dir, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, firstKey, thirdKey)
+}
+
+func TestPackageKeysChangeWhenDependencyChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/testmodule\n\ngo 1.19\n")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nimport \"example.com/testmodule/dep\"\n\nvar x = dep.Value\n")
+	depPath := filepath.Join(dir, "dep", "dep.go")
+	require.NoError(t, os.Mkdir(filepath.Dir(depPath), 0755))
+	writeFile(t, depPath, "package dep\n\nconst Value = 1\n")
+
+	idx, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	loadKeys := func() map[string]string {
+		pkgs, err := packages.Load(&packages.Config{
+			Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps,
+			Dir:  dir,
+		}, "./...")
+		require.NoError(t, err)
+
+		keys, err := idx.PackageKeys(pkgs, "")
+		require.NoError(t, err)
+		return keys
+	}
+
+	firstKeys := loadKeys()
+	require.Contains(t, firstKeys, "example.com/testmodule")
+	require.Contains(t, firstKeys, "example.com/testmodule/dep")
+
+	writeFile(t, depPath, "package dep\n\nconst Value = \"one\"\n")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(depPath, future, future))
+
+	secondKeys := loadKeys()
+	assert.NotEqual(t, firstKeys["example.com/testmodule/dep"], secondKeys["example.com/testmodule/dep"])
+	assert.NotEqual(t, firstKeys["example.com/testmodule"], secondKeys["example.com/testmodule"])
+}
+
+func TestPatternForPkg(t *testing.T) {
+	testCases := []struct {
+		pkg      *packages.Package
+		expected string
+	}{
+		{pkg: &packages.Package{ID: "example.com/foo", PkgPath: "example.com/foo"}, expected: "example.com/foo"},
+		{pkg: &packages.Package{ID: "example.com/foo [example.com/foo.test]", PkgPath: "example.com/foo"}, expected: "example.com/foo"},
+		{pkg: &packages.Package{ID: "example.com/foo_test [example.com/foo.test]", PkgPath: "example.com/foo_test"}, expected: "example.com/foo"},
+		{pkg: &packages.Package{ID: "example.com/foo.test", PkgPath: "example.com/foo.test"}, expected: "example.com/foo"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pkg.ID, func(t *testing.T) {
+			assert.Equal(t, tc.expected, PatternForPkg(tc.pkg))
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	err := os.WriteFile(path, []byte(content), 0644)
+	require.NoError(t, err)
+}
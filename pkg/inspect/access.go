@@ -0,0 +1,130 @@
+package inspect
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// refAccessForIdent classifies how an identifier uses the object it refers to.
+func refAccessForIdent(pkg *packages.Package, ident *ast.Ident, obj types.Object) RefAccess {
+	switch obj.(type) {
+	case *types.PkgName:
+		return RefAccessImport
+	case *types.TypeName:
+		return RefAccessType
+	}
+
+	root, err := astFileForPath(pkg, pkg.Fset.Position(ident.Pos()).Filename)
+	if err != nil {
+		return RefAccessRead
+	}
+
+	path, _ := astutil.PathEnclosingInterval(root, ident.Pos(), ident.End())
+	if len(path) < 2 {
+		return RefAccessRead
+	}
+
+	if kv, ok := path[1].(*ast.KeyValueExpr); ok && kv.Key == ident && len(path) > 2 {
+		if _, ok := path[2].(*ast.CompositeLit); ok {
+			if v, ok := obj.(*types.Var); ok && v.IsField() {
+				return RefAccessFieldKey
+			}
+		}
+	}
+
+	// Find the outermost expression that denotes the same object as the identifier,
+	// so that x.f and (x.f) are classified like f.
+	var expr ast.Expr = ident
+	i := 1
+	for ; i < len(path); i++ {
+		if sel, ok := path[i].(*ast.SelectorExpr); ok && sel.Sel == expr {
+			expr = sel
+		} else if paren, ok := path[i].(*ast.ParenExpr); ok && paren.X == expr {
+			expr = paren
+		} else {
+			break
+		}
+	}
+
+	if i == len(path) {
+		return RefAccessRead
+	}
+
+	switch parent := path[i].(type) {
+	case *ast.CallExpr:
+		if parent.Fun == expr {
+			return RefAccessCall
+		}
+	case *ast.UnaryExpr:
+		if parent.Op == token.AND && parent.X == expr {
+			return RefAccessAddress
+		}
+	}
+
+	if isAssignedThrough(path[i:], expr) {
+		return RefAccessWrite
+	}
+
+	return RefAccessRead
+}
+
+// isAssignedThrough checks whether an expression is assigned, either directly or through an index,
+// a field selection, or a pointer indirection. For example, x is written in x[i] = v, x.f.g = v, and *x = v.
+// The path starts at the parent of the expression.
+func isAssignedThrough(path []ast.Node, expr ast.Expr) bool {
+	for _, node := range path {
+		switch node := node.(type) {
+		case *ast.SelectorExpr:
+			if node.X == expr {
+				expr = node
+				continue
+			}
+		case *ast.IndexExpr:
+			if node.X == expr {
+				expr = node
+				continue
+			}
+		case *ast.StarExpr:
+			if node.X == expr {
+				expr = node
+				continue
+			}
+		case *ast.ParenExpr:
+			if node.X == expr {
+				expr = node
+				continue
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				if lhs == expr {
+					return true
+				}
+			}
+		case *ast.IncDecStmt:
+			return node.X == expr
+		case *ast.RangeStmt:
+			return node.Tok == token.ASSIGN && (node.Key == expr || node.Value == expr)
+		}
+		return false
+	}
+	return false
+}
+
+// includeRefAccess checks whether a reference with an access should be included, given the accesses to filter for.
+// If accesses is empty, every reference is included.
+func includeRefAccess(access RefAccess, accesses []RefAccess) bool {
+	if len(accesses) == 0 {
+		return true
+	}
+
+	for _, a := range accesses {
+		if a == access {
+			return true
+		}
+	}
+	return false
+}
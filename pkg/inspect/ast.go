@@ -67,7 +67,7 @@ func astFileForPath(pkg *packages.Package, path string) (*ast.File, error) {
 	return nil, fmt.Errorf("Could not find ast.File for %q", targetPath)
 }
 
-// selectivelyParseFileFunc removes function bodies that do not contain the target line.
+// selectivelyParseFileFunc removes the bodies of functions that do not contain the target line.
 func selectivelyParseFileFunc(targetFilename string, targetLine int) func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
 	return func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
 		isTargetFile := filename == targetFilename
@@ -76,8 +76,9 @@ func selectivelyParseFileFunc(targetFilename string, targetLine int) func(fset *
 			return nil, err
 		}
 
-		// Delete function bodies that don't contain the target line.
+		// Delete function bodies of functions that don't contain the target line.
 		// This reduces the amount of code we need to typecheck later.
+		// The target line may be in the signature, which can span several lines before the body.
 		ast.Inspect(astFile, func(node ast.Node) bool {
 			if node == nil {
 				return false
@@ -88,7 +89,7 @@ func selectivelyParseFileFunc(targetFilename string, targetLine int) func(fset *
 				return true
 			}
 
-			start := fset.Position(funcDecl.Body.Lbrace)
+			start := fset.Position(funcDecl.Pos())
 			end := fset.Position(funcDecl.Body.Rbrace)
 			if !isTargetFile || targetLine < start.Line || targetLine > end.Line {
 				funcDecl.Body = nil
@@ -0,0 +1,89 @@
+package inspect
+
+import (
+	"path/filepath"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/cache"
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+// BatchResult is the result of inspecting one location in a batch.
+// If inspecting the location failed, Error describes the failure and Result is nil.
+type BatchResult struct {
+	Loc    file.Loc `json:"loc"`
+	Result *Result  `json:"result,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// InspectBatch inspects many locations, returning one result for each location in the same order.
+// Locations are grouped by package so each package is loaded only once,
+// and packages in searchDir are loaded once for each kind of relation, then reused for every location.
+// A failure for one location doesn't stop the others.
+func InspectBatch(locs []file.Loc, searchDir string, includeRelKinds []RelationKind, opts Options) []BatchResult {
+	if opts.Cache == nil {
+		opts.Cache = cache.New()
+	}
+	opts.loadAllSearchPkgs = true
+
+	// Group locations by the directory of the package, whether it includes tests,
+	// and the build configuration, in the order each group first appears.
+	type pkgGroup struct {
+		dir          string
+		includeTests bool
+		build        string
+	}
+	var groups []pkgGroup
+	locIndicesByGroup := make(map[pkgGroup][]int)
+	buildByGroup := make(map[pkgGroup]buildcfg.Config)
+	results := make([]BatchResult, len(locs))
+	for i, loc := range locs {
+		results[i].Loc = loc
+
+		absPath, err := filepath.Abs(loc.Path)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		build, err := buildcfg.ConfigForFile(loc.Path, opts.Build, opts.Overlay)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		group := pkgGroup{dir: filepath.Dir(absPath), includeTests: isGoTestFile(loc.Path), build: build.String()}
+		if _, ok := locIndicesByGroup[group]; !ok {
+			groups = append(groups, group)
+			buildByGroup[group] = build
+		}
+		locIndicesByGroup[group] = append(locIndicesByGroup[group], i)
+	}
+
+	for _, group := range groups {
+		opts := opts
+		opts.Build = buildByGroup[group]
+		pkgs, err := loadGoPackagesInDir(group.dir, group.includeTests, nil, opts)
+		for _, i := range locIndicesByGroup[group] {
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+
+			pkg, err := goPackageForPath(pkgs, locs[i].Path)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+
+			result, err := inspectPkg(pkg, locs[i], searchDir, includeRelKinds, opts)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Result = result
+		}
+	}
+
+	return results
+}
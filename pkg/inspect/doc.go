@@ -0,0 +1,149 @@
+package inspect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+func enrichResultDoc(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
+	if err != nil {
+		return err
+	}
+
+	obj, err := typeObjUseOrDefForAstIdent(ident, pkg)
+	if err != nil || obj.Pkg() == nil || !obj.Pos().IsValid() {
+		// Builtins and universe objects don't have a declaration.
+		return nil
+	}
+
+	// The package may have been loaded without comments or function bodies,
+	// so parse the file containing the declaration again.
+	position := pkg.Fset.Position(obj.Pos())
+	src, err := opts.Overlay.ReadFile(position.Filename)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, position.Filename, src, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("parser.ParseFile: %w", err)
+	}
+
+	pos := fset.File(astFile.Pos()).Pos(position.Offset)
+	path, _ := astutil.PathEnclosingInterval(astFile, pos, pos)
+	result.Doc, result.Decl = docAndDeclForPath(fset, src, path)
+
+	declPkgPath := obj.Pkg().Path()
+	if pkgNameObj, ok := obj.(*types.PkgName); ok {
+		// For an imported package name, show the doc for the imported package.
+		declPkgPath = pkgNameObj.Imported().Path()
+	}
+
+	pkgDoc, err := pkgDocForPkgPath(pkg, declPkgPath, opts.Overlay)
+	if err != nil {
+		return err
+	}
+	result.PkgDoc = new(doc.Package).Synopsis(pkgDoc)
+
+	return nil
+}
+
+// docAndDeclForPath finds the innermost declaration in an AST path (ordered from innermost to outermost node),
+// and returns its doc comment and source code.
+func docAndDeclForPath(fset *token.FileSet, src []byte, path []ast.Node) (string, string) {
+	snippet := func(start, end token.Pos) string {
+		return string(src[fset.Position(start).Offset:fset.Position(end).Offset])
+	}
+
+	for i, node := range path {
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			return node.Doc.Text(), snippet(node.Pos(), node.Type.End())
+
+		case *ast.Field:
+			// Struct fields, interface methods, and function params or results.
+			docGroup := node.Doc
+			if docGroup == nil {
+				docGroup = node.Comment
+			}
+			return docGroup.Text(), snippet(node.Pos(), node.End())
+
+		case *ast.AssignStmt:
+			// Variables declared with ":=".
+			return "", snippet(node.Pos(), node.End())
+
+		case *ast.ValueSpec, *ast.TypeSpec, *ast.ImportSpec:
+			if i+1 >= len(path) {
+				return "", ""
+			}
+
+			genDecl, ok := path[i+1].(*ast.GenDecl)
+			if !ok {
+				return "", ""
+			}
+
+			var docGroup *ast.CommentGroup
+			switch spec := node.(type) {
+			case *ast.ValueSpec:
+				docGroup = spec.Doc
+			case *ast.TypeSpec:
+				docGroup = spec.Doc
+			case *ast.ImportSpec:
+				docGroup = spec.Doc
+			}
+
+			// Like godoc, use the doc comment on the declaration if it has only one spec.
+			if docGroup == nil && len(genDecl.Specs) == 1 {
+				docGroup = genDecl.Doc
+			}
+
+			return docGroup.Text(), fmt.Sprintf("%s %s", genDecl.Tok, snippet(node.Pos(), node.End()))
+		}
+	}
+
+	return "", ""
+}
+
+// pkgDocForPkgPath returns the package doc comment for a package in the import graph of pkg.
+func pkgDocForPkgPath(pkg *packages.Package, pkgPath string, overlay file.Overlay) (string, error) {
+	var declPkg *packages.Package
+	packages.Visit([]*packages.Package{pkg}, func(p *packages.Package) bool {
+		if p.PkgPath == pkgPath {
+			declPkg = p
+		}
+		return declPkg == nil
+	}, nil)
+
+	if declPkg == nil {
+		return "", nil
+	}
+
+	for _, path := range declPkg.GoFiles {
+		src, err := overlay.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		astFile, err := parser.ParseFile(token.NewFileSet(), path, src, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			return "", fmt.Errorf("parser.ParseFile: %w", err)
+		}
+
+		if astFile.Doc != nil {
+			return astFile.Doc.Text(), nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,373 @@
+package inspect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+func enrichResultEmbedsRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	typeObj := typeNameDefinedAtLoc(pkg, loc)
+	if typeObj == nil {
+		return nil
+	}
+
+	relationSet := make(map[Relation]struct{})
+
+	// Walk the embedding chain breadth-first, so each embedded type is reported with the shortest path.
+	type embedding struct {
+		named *types.Named
+		via   []string
+	}
+	seen := map[*types.TypeName]struct{}{typeObj: {}}
+	queue := []embedding{{named: namedTypeForObj(typeObj)}}
+	for i := 0; i < len(queue); i++ {
+		cur := queue[i]
+		if cur.named == nil {
+			continue
+		}
+
+		if len(cur.via) > 0 {
+			relationSet[Relation{
+				Kind: RelationKindEmbeds,
+				Pkg:  pkgNameForTypeObj(cur.named.Obj()),
+				Name: cur.named.Obj().Name(),
+				Loc:  fileLocForTypeObj(pkg, cur.named.Obj()),
+				Via:  strings.Join(cur.via[:len(cur.via)-1], "."),
+			}] = struct{}{}
+
+			// An interface gets the methods of every interface it embeds.
+			if types.IsInterface(typeObj.Type()) {
+				for _, method := range declaredMembers(cur.named) {
+					relationSet[Relation{
+						Kind: RelationKindEmbeds,
+						Pkg:  pkgNameForTypeObj(method),
+						Name: memberName(cur.named, method),
+						Loc:  fileLocForTypeObj(pkg, method),
+						Via:  strings.Join(cur.via, "."),
+					}] = struct{}{}
+				}
+			}
+		}
+
+		for _, embeddedType := range embeddedNamedTypes(cur.named) {
+			if _, ok := seen[embeddedType.Obj()]; ok {
+				continue
+			}
+			seen[embeddedType.Obj()] = struct{}{}
+
+			via := append(append([]string(nil), cur.via...), embeddedType.Obj().Name())
+			queue = append(queue, embedding{named: embeddedType, via: via})
+		}
+	}
+
+	// Report fields and methods promoted from the embedded types of a struct.
+	for _, member := range promotedStructMembers(typeObj.Type()) {
+		relationSet[Relation{
+			Kind: RelationKindEmbeds,
+			Pkg:  pkgNameForTypeObj(member.obj),
+			Name: member.name,
+			Loc:  fileLocForTypeObj(pkg, member.obj),
+			Via:  strings.Join(member.via, "."),
+		}] = struct{}{}
+	}
+
+	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
+	return nil
+}
+
+func enrichResultEmbeddedByRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	typeObj := typeNameDefinedAtLoc(pkg, loc)
+	if typeObj == nil {
+		return nil
+	}
+
+	loadMode := (packages.NeedName |
+		packages.NeedDeps |
+		packages.NeedTypes |
+		packages.NeedTypesInfo |
+		packages.NeedImports)
+
+	includeTests := includeTestsForLoc(loc, opts)
+
+	// A type that embeds the target may itself be embedded by a type in a package that doesn't import the target's package,
+	// so keep searching the importers of each package with an embedding type until there are no new packages.
+	targetPkgPaths := map[string]struct{}{pkg.PkgPath: {}}
+	for {
+		numTargetPkgPaths := len(targetPkgPaths)
+		searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, opts, loadMode, includeTests, func(candidate skeletonPkg) bool {
+			for pkgPath := range targetPkgPaths {
+				if candidate.ImportPath == pkgPath || candidate.ImportsPkg(pkgPath) {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return err
+		}
+
+		relationSet := make(map[Relation]struct{})
+		forEachTypeEmbeddingType(pkg.Fset.Position(typeObj.Pos()), searchPkgs, func(searchPkg *packages.Package, embeddingObj *types.TypeName, via string) {
+			relationSet[Relation{
+				Kind: RelationKindEmbeddedBy,
+				Pkg:  pkgNameForTypeObj(embeddingObj),
+				Name: embeddingObj.Name(),
+				Loc:  fileLocForTypeObj(searchPkg, embeddingObj),
+				Via:  via,
+			}] = struct{}{}
+			targetPkgPaths[embeddingObj.Pkg().Path()] = struct{}{}
+		})
+
+		if len(targetPkgPaths) == numTargetPkgPaths {
+			result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
+			return nil
+		}
+	}
+}
+
+// forEachTypeEmbeddingType calls f for every type in searchPkgs that embeds the type declared at targetPosition, directly or transitively.
+// Types are visited breadth-first, so via is the shortest path of embedded types between the embedding type and the target.
+func forEachTypeEmbeddingType(targetPosition token.Position, searchPkgs []*packages.Package, f func(*packages.Package, *types.TypeName, string)) {
+	// Map from the position of each type found so far to the path from that type to the target (including the type itself).
+	prefixes := map[token.Position]string{targetPosition: ""}
+	level := map[token.Position]struct{}{targetPosition: {}}
+
+	for len(level) > 0 {
+		type found struct {
+			pkg *packages.Package
+			obj *types.TypeName
+			via string
+		}
+		nextLevel := make(map[token.Position]found)
+
+		for _, searchPkg := range searchPkgs {
+			for _, obj := range searchPkg.TypesInfo.Defs {
+				typeObj, ok := obj.(*types.TypeName)
+				if !ok || typeObj.IsAlias() {
+					continue
+				}
+
+				position := searchPkg.Fset.Position(typeObj.Pos())
+				if _, ok := prefixes[position]; ok {
+					continue
+				}
+
+				named := namedTypeForObj(typeObj)
+				if named == nil {
+					continue
+				}
+
+				for _, embeddedType := range embeddedNamedTypes(named) {
+					embeddedPosition := searchPkg.Fset.Position(embeddedType.Obj().Pos())
+					if _, ok := level[embeddedPosition]; !ok {
+						continue
+					}
+
+					// If a type embeds several types at this level, choose the first path in lexical order so the result is deterministic.
+					via := prefixes[embeddedPosition]
+					if prev, ok := nextLevel[position]; !ok || via < prev.via {
+						nextLevel[position] = found{pkg: searchPkg, obj: typeObj, via: via}
+					}
+				}
+			}
+		}
+
+		level = make(map[token.Position]struct{}, len(nextLevel))
+		for position, fd := range nextLevel {
+			f(fd.pkg, fd.obj, fd.via)
+			level[position] = struct{}{}
+			prefixes[position] = joinVia(fd.obj.Name(), fd.via)
+		}
+	}
+}
+
+// promotedMember is a field or method promoted from an embedded type.
+type promotedMember struct {
+	obj  types.Object
+	name string
+	via  []string
+}
+
+// promotedStructMembers returns the fields and methods of a struct type that are promoted from embedded types.
+func promotedStructMembers(t types.Type) []promotedMember {
+	if _, ok := t.Underlying().(*types.Struct); !ok {
+		return nil
+	}
+
+	// Collect the fields and methods of every embedded type,
+	// then check which ones are selectable from t (a member may be shadowed or ambiguous).
+	var candidates []types.Object
+	seen := make(map[*types.TypeName]struct{})
+	queue := []types.Type{t}
+	for i := 0; i < len(queue); i++ {
+		if named := namedTypeOrPointerElem(queue[i]); named != nil {
+			if _, ok := seen[named.Obj()]; ok {
+				continue
+			}
+			seen[named.Obj()] = struct{}{}
+		}
+
+		if i > 0 {
+			candidates = append(candidates, declaredMembers(queue[i])...)
+		}
+
+		switch u := derefType(queue[i]).Underlying().(type) {
+		case *types.Struct:
+			for j := 0; j < u.NumFields(); j++ {
+				if field := u.Field(j); field.Embedded() {
+					queue = append(queue, field.Type())
+				}
+			}
+		case *types.Interface:
+			// Include methods the interface gets from interfaces it embeds.
+			for j := 0; j < u.NumMethods(); j++ {
+				candidates = append(candidates, u.Method(j))
+			}
+		}
+	}
+
+	var result []promotedMember
+	seenMembers := make(map[types.Object]struct{})
+	for _, candidate := range candidates {
+		obj, index, _ := types.LookupFieldOrMethod(t, true, candidate.Pkg(), candidate.Name())
+		if obj == nil || obj.Pos() != candidate.Pos() || len(index) < 2 {
+			continue
+		}
+
+		if _, ok := seenMembers[obj]; ok {
+			continue
+		}
+		seenMembers[obj] = struct{}{}
+
+		// Every index except the last selects an embedded field.
+		var via []string
+		cur := t
+		for _, fieldIndex := range index[:len(index)-1] {
+			s, ok := derefType(cur).Underlying().(*types.Struct)
+			if !ok {
+				break
+			}
+			field := s.Field(fieldIndex)
+			via = append(via, field.Name())
+			cur = field.Type()
+		}
+
+		result = append(result, promotedMember{
+			obj:  obj,
+			name: memberName(cur, obj),
+			via:  via,
+		})
+	}
+
+	return result
+}
+
+// declaredMembers returns the fields and methods declared directly by a type (not promoted from embedded types).
+func declaredMembers(t types.Type) []types.Object {
+	var members []types.Object
+
+	named := namedTypeOrPointerElem(t)
+	if named != nil {
+		for i := 0; i < named.NumMethods(); i++ {
+			members = append(members, named.Method(i))
+		}
+	}
+
+	switch u := derefType(t).Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			members = append(members, u.Field(i))
+		}
+	case *types.Interface:
+		for i := 0; i < u.NumExplicitMethods(); i++ {
+			members = append(members, u.ExplicitMethod(i))
+		}
+	}
+
+	return members
+}
+
+// memberName returns the name of a field or method qualified by the type that declares it, like "T.Field" or "T.Method()".
+func memberName(declaringType types.Type, obj types.Object) string {
+	typeName := obj.Name()
+	if named := namedTypeOrPointerElem(declaringType); named != nil {
+		typeName = fmt.Sprintf("%s.%s", named.Obj().Name(), obj.Name())
+	}
+
+	if _, ok := obj.(*types.Func); ok {
+		return fmt.Sprintf("%s()", typeName)
+	}
+	return typeName
+}
+
+// embeddedNamedTypes returns the named types embedded directly in a struct or interface type.
+// Instantiated generic types are replaced by their generic declaration.
+func embeddedNamedTypes(named *types.Named) []*types.Named {
+	var result []*types.Named
+	switch u := named.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if field := u.Field(i); field.Embedded() {
+				if embeddedType := namedTypeOrPointerElem(field.Type()); embeddedType != nil {
+					result = append(result, embeddedType.Origin())
+				}
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < u.NumEmbeddeds(); i++ {
+			if embeddedType, ok := types.Unalias(u.EmbeddedType(i)).(*types.Named); ok {
+				result = append(result, embeddedType.Origin())
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Obj().Name() < result[j].Obj().Name()
+	})
+	return result
+}
+
+// typeNameDefinedAtLoc returns the type declared by the identifier at loc,
+// or nil if the identifier isn't the name in a type declaration.
+func typeNameDefinedAtLoc(pkg *packages.Package, loc file.Loc) *types.TypeName {
+	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
+	if err != nil {
+		return nil
+	}
+
+	typeObj, _ := pkg.TypesInfo.Defs[ident].(*types.TypeName)
+	return typeObj
+}
+
+func namedTypeForObj(typeObj *types.TypeName) *types.Named {
+	named, _ := types.Unalias(typeObj.Type()).(*types.Named)
+	return named
+}
+
+func namedTypeOrPointerElem(t types.Type) *types.Named {
+	named, _ := types.Unalias(derefType(t)).(*types.Named)
+	return named
+}
+
+func derefType(t types.Type) types.Type {
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+	return t
+}
+
+// joinVia prepends a type name to a path of embedded types.
+func joinVia(name string, via string) string {
+	if via == "" {
+		return name
+	}
+	return fmt.Sprintf("%s.%s", name, via)
+}
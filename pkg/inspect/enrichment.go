@@ -6,6 +6,7 @@ import (
 	"go/token"
 	"go/types"
 	"sort"
+	"strconv"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
@@ -13,17 +14,30 @@ import (
 	"github.com/wedaly/gospelunk/pkg/file"
 )
 
-type enrichResultFunc func(result *Result, pkg *packages.Package, loc file.Loc, searchDir string) error
+// NoIdentifierError is returned by Inspect when there isn't an identifier with a type object at the location.
+type NoIdentifierError struct {
+	Err error
+}
+
+func (e *NoIdentifierError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NoIdentifierError) Unwrap() error {
+	return e.Err
+}
+
+type enrichResultFunc func(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error
 
-func enrichResultNameAndType(result *Result, pkg *packages.Package, loc file.Loc, searchDir string) error {
+func enrichResultNameAndType(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
 	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
 	if err != nil {
-		return err
+		return &NoIdentifierError{Err: err}
 	}
 
 	obj, err := typeObjUseOrDefForAstIdent(ident, pkg)
 	if err != nil {
-		return err
+		return &NoIdentifierError{Err: err}
 	}
 
 	var typeName string
@@ -33,10 +47,12 @@ func enrichResultNameAndType(result *Result, pkg *packages.Package, loc file.Loc
 
 	result.Name = ident.Name
 	result.Type = typeName
+	result.TypeParams = typeParamsForObj(obj)
+	result.Instance = instanceForIdent(pkg, ident)
 	return nil
 }
 
-func enrichResultDefRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string) error {
+func enrichResultDefRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
 	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
 	if err != nil {
 		return err
@@ -63,7 +79,78 @@ func enrichResultDefRelation(result *Result, pkg *packages.Package, loc file.Loc
 	return nil
 }
 
-func enrichResultRefRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string) error {
+func enrichResultTypeDefRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
+	if err != nil {
+		return err
+	}
+
+	obj, err := typeObjUseOrDefForAstIdent(ident, pkg)
+	if err != nil {
+		return nil
+	}
+
+	// Only values have a type declared somewhere else.
+	switch obj.(type) {
+	case *types.Var, *types.Const:
+	default:
+		return nil
+	}
+
+	relationSet := make(map[Relation]struct{})
+	for _, typeObj := range typeNamesInType(obj.Type()) {
+		if !typeObj.Pos().IsValid() {
+			// Builtin types like int and error don't have a declaration.
+			continue
+		}
+
+		relationSet[Relation{
+			Kind: RelationKindTypeDef,
+			Pkg:  pkgNameForTypeObj(typeObj),
+			Name: typeObj.Name(),
+			Loc:  fileLocForTypeObj(pkg, typeObj),
+		}] = struct{}{}
+	}
+
+	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
+	return nil
+}
+
+// typeNamesInType returns the declared types that make up a type,
+// unwrapping pointers, slices, arrays, maps, channels, and the type arguments of generic instantiations.
+// For example, map[string]*List[Config] returns List and Config.
+func typeNamesInType(t types.Type) []*types.TypeName {
+	var result []*types.TypeName
+	var visit func(types.Type)
+	visit = func(t types.Type) {
+		switch t := t.(type) {
+		case *types.Alias:
+			result = append(result, t.Obj())
+		case *types.Named:
+			result = append(result, t.Origin().Obj())
+			for i := 0; i < t.TypeArgs().Len(); i++ {
+				visit(t.TypeArgs().At(i))
+			}
+		case *types.TypeParam:
+			result = append(result, t.Obj())
+		case *types.Pointer:
+			visit(t.Elem())
+		case *types.Slice:
+			visit(t.Elem())
+		case *types.Array:
+			visit(t.Elem())
+		case *types.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case *types.Chan:
+			visit(t.Elem())
+		}
+	}
+	visit(t)
+	return result
+}
+
+func enrichResultRefRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
 	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
 	if err != nil {
 		return err
@@ -81,15 +168,39 @@ func enrichResultRefRelation(result *Result, pkg *packages.Package, loc file.Loc
 		packages.NeedTypes |
 		packages.NeedTypesInfo)
 
-	includeTests := isGoTestFile(loc.Path)
-	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, loadMode, includeTests, func(candidate skeletonPkg) bool {
+	includeTests := includeTestsForLoc(loc, opts)
+	predicate := func(candidate skeletonPkg) bool {
 		return candidate.ImportPath == pkg.PkgPath || (ident.IsExported() && candidate.ImportsPkg(pkg.PkgPath))
-	})
+	}
+
+	relationSet := make(map[Relation]struct{})
+
+	if opts.Index != nil && len(opts.Overlay) == 0 {
+		allRefs, err := loadIndexedRefsMatchingPredicate(searchDir, opts, includeTests, predicate)
+		if err != nil {
+			return err
+		}
+
+		// Match on the start of the definition, like the search below, since the
+		// indexed range of a definition can differ from the range of its identifier.
+		targetLoc := fileLocForIdent(pkg, ident).Start()
+		for _, refs := range allRefs {
+			for _, use := range refs.Uses {
+				if use.Def.Start() == targetLoc && includeRefAccess(use.Ref.Access, opts.RefAccesses) {
+					relationSet[use.Ref] = struct{}{}
+				}
+			}
+		}
+
+		result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
+		return nil
+	}
+
+	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, opts, loadMode, includeTests, predicate)
 	if err != nil {
 		return err
 	}
 
-	relationSet := make(map[Relation]struct{})
 	for _, searchPkg := range searchPkgs {
 		for refIdent, refObj := range searchPkg.TypesInfo.Uses {
 			refPosition := searchPkg.Fset.Position(refObj.Pos())
@@ -97,11 +208,17 @@ func enrichResultRefRelation(result *Result, pkg *packages.Package, loc file.Loc
 				continue
 			}
 
+			access := refAccessForIdent(searchPkg, refIdent, refObj)
+			if !includeRefAccess(access, opts.RefAccesses) {
+				continue
+			}
+
 			r := Relation{
-				Kind: RelationKindRef,
-				Pkg:  searchPkg.Name,
-				Name: nameForRefRelation(searchPkg, refIdent.Pos(), refIdent.Name),
-				Loc:  fileLocForIdent(searchPkg, refIdent),
+				Kind:   RelationKindRef,
+				Pkg:    searchPkg.Name,
+				Name:   nameForRefRelation(searchPkg, refIdent.Pos(), refIdent.Name),
+				Loc:    fileLocForIdent(searchPkg, refIdent),
+				Access: access,
 			}
 			relationSet[r] = struct{}{}
 		}
@@ -134,21 +251,7 @@ func nameForRefRelation(pkg *packages.Package, pos token.Pos, identName string)
 			return false
 
 		case *ast.FuncDecl:
-			funcName := node.Name.Name
-			if node.Recv != nil && len(node.Recv.List) > 0 {
-				for _, field := range node.Recv.List {
-					if recvTypeIdent, ok := field.Type.(*ast.Ident); ok {
-						funcName = fmt.Sprintf("%s.%s", recvTypeIdent.Name, funcName)
-						break
-					} else if recvTypeStar, ok := field.Type.(*ast.StarExpr); ok {
-						if recvTypeIdent, ok := recvTypeStar.X.(*ast.Ident); ok {
-							funcName = fmt.Sprintf("%s.%s", recvTypeIdent.Name, funcName)
-							break
-						}
-					}
-				}
-			}
-
+			funcName := funcNameForFuncDecl(node)
 			if node.Body != nil && !(pos < node.Body.Lbrace || pos > node.Body.Rbrace) {
 				refName = fmt.Sprintf("%s in %s() body", identName, funcName)
 			} else if !(pos < node.Type.Params.Opening || pos > node.Type.Params.Closing) {
@@ -198,7 +301,23 @@ func nameForRefRelation(pkg *packages.Package, pos token.Pos, identName string)
 	return refName
 }
 
-func enrichResultImplRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string) error {
+func funcNameForFuncDecl(funcDecl *ast.FuncDecl) string {
+	funcName := funcDecl.Name.Name
+	if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+		for _, field := range funcDecl.Recv.List {
+			if recvTypeIdent, ok := field.Type.(*ast.Ident); ok {
+				return fmt.Sprintf("%s.%s", recvTypeIdent.Name, funcName)
+			} else if recvTypeStar, ok := field.Type.(*ast.StarExpr); ok {
+				if recvTypeIdent, ok := recvTypeStar.X.(*ast.Ident); ok {
+					return fmt.Sprintf("%s.%s", recvTypeIdent.Name, funcName)
+				}
+			}
+		}
+	}
+	return funcName
+}
+
+func enrichResultImplRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
 	ifaceName, ifaceType := interfaceNameAndTypeAtFileLoc(pkg, loc)
 	if ifaceType == nil || ifaceType.Empty() {
 		return nil
@@ -206,29 +325,53 @@ func enrichResultImplRelation(result *Result, pkg *packages.Package, loc file.Lo
 
 	methodName := methodNameForTypeAtLoc(pkg, loc, ifaceType) // Empty string if not on method identifier.
 
+	relationSet := make(map[Relation]struct{})
+	err := forEachTypeImplementingIface(pkg.PkgPath, ifaceName, searchDir, opts, includeTestsForLoc(loc, opts), func(searchPkg *packages.Package, obj types.Object) {
+		if methodName == "" {
+			// If we're not looking for a specific method, the relation points to the implementation of the interface type.
+			r := Relation{
+				Kind: RelationKindImpl,
+				Pkg:  pkgNameForTypeObj(obj),
+				Name: obj.Name(),
+				Loc:  fileLocForTypeObj(searchPkg, obj),
+			}
+			relationSet[r] = struct{}{}
+		} else if r, ok := methodImplRelation(RelationKindImpl, searchPkg, obj, methodName); ok {
+			// If we're looking for a specific method, the relation points to the implementation of the method.
+			relationSet[r] = struct{}{}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
+	return nil
+}
+
+// forEachTypeImplementingIface calls f for every type in searchDir that implements an interface.
+func forEachTypeImplementingIface(ifacePkgPath string, ifaceName string, searchDir string, opts Options, includeTests bool, f func(*packages.Package, types.Object)) error {
 	loadMode := (packages.NeedName |
 		packages.NeedDeps |
 		packages.NeedTypes |
 		packages.NeedTypesInfo |
 		packages.NeedImports)
 
-	includeTests := isGoTestFile(loc.Path)
-	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, loadMode, includeTests, func(candidate skeletonPkg) bool {
-		return candidate.ImportPath == pkg.PkgPath || candidate.ImportsPkg(pkg.PkgPath)
+	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, opts, loadMode, includeTests, func(candidate skeletonPkg) bool {
+		return candidate.ImportPath == ifacePkgPath || candidate.ImportsPkg(ifacePkgPath)
 	})
 	if err != nil {
 		return err
 	}
 
-	relationSet := make(map[Relation]struct{})
 	for _, searchPkg := range searchPkgs {
 		// Lookup the interface type either in the package or its imports.
 		// We need this to check if other types in the package implement the interface.
-		// (We can't use ifaceType directly because it comes from a different package, so it isn't comparable to types in this pkg.)
+		// (We can't use the original interface type directly because it comes from a different package, so it isn't comparable to types in this pkg.)
 		var pkgIfaceType *types.Interface
-		if searchPkg.PkgPath == pkg.PkgPath {
+		if searchPkg.PkgPath == ifacePkgPath {
 			pkgIfaceType = interfaceTypeInPkgScopeWithName(searchPkg, ifaceName)
-		} else if importedPkg, ok := searchPkg.Imports[pkg.PkgPath]; ok {
+		} else if importedPkg, ok := searchPkg.Imports[ifacePkgPath]; ok {
 			pkgIfaceType = interfaceTypeInPkgScopeWithName(importedPkg, ifaceName)
 		}
 
@@ -261,47 +404,40 @@ func enrichResultImplRelation(result *Result, pkg *packages.Package, loc file.Lo
 
 			// Check if this type OR a pointer to this type implements the interface.
 			if types.Implements(obj.Type(), pkgIfaceType) || types.Implements(types.NewPointer(obj.Type()), pkgIfaceType) {
-				if methodName == "" {
-					// If we're not looking for a specific method, the relation points to the implementation of the interface type.
-					r := Relation{
-						Kind: RelationKindImpl,
-						Pkg:  pkgNameForTypeObj(obj),
-						Name: obj.Name(),
-						Loc:  fileLocForTypeObj(searchPkg, obj),
-					}
-					relationSet[r] = struct{}{}
-				} else {
-					// If we're looking for a specific method, the relation points to the implementation of the method.
-					methodObj, _, _ := types.LookupFieldOrMethod(obj.Type(), true, searchPkg.Types, methodName)
-					if methodObj != nil {
-						r := Relation{
-							Kind: RelationKindImpl,
-							Pkg:  pkgNameForTypeObj(methodObj),
-							Name: fmt.Sprintf("%s.%s()", obj.Name(), methodObj.Name()),
-							Loc:  fileLocForTypeObj(searchPkg, methodObj),
-						}
-						relationSet[r] = struct{}{}
-					}
-				}
+				f(searchPkg, obj)
 			}
 		}
 	}
 
-	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
 	return nil
 }
 
-func enrichResultIfaceRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string) error {
+// methodImplRelation constructs a relation to the method with a given name on an implementation type.
+func methodImplRelation(kind RelationKind, pkg *packages.Package, implObj types.Object, methodName string) (Relation, bool) {
+	methodObj, _, _ := types.LookupFieldOrMethod(implObj.Type(), true, pkg.Types, methodName)
+	if methodObj == nil {
+		return Relation{}, false
+	}
+
+	return Relation{
+		Kind: kind,
+		Pkg:  pkgNameForTypeObj(methodObj),
+		Name: fmt.Sprintf("%s.%s()", implObj.Name(), methodObj.Name()),
+		Loc:  fileLocForTypeObj(pkg, methodObj),
+	}, true
+}
+
+func enrichResultIfaceRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
 	if typeSpec, err := astNodeAtLoc[*ast.TypeSpec](pkg, loc); err == nil {
-		return enrichResultIfaceRelationFromTypeSpec(result, pkg, loc, searchDir, typeSpec)
+		return enrichResultIfaceRelationFromTypeSpec(result, pkg, loc, searchDir, opts, typeSpec)
 	} else if funcDecl, err := astNodeAtLoc[*ast.FuncDecl](pkg, loc); err == nil {
-		return enrichResultIfaceRelationFromFuncDecl(result, pkg, loc, searchDir, funcDecl)
+		return enrichResultIfaceRelationFromFuncDecl(result, pkg, loc, searchDir, opts, funcDecl)
 	}
 
 	return nil
 }
 
-func enrichResultIfaceRelationFromTypeSpec(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, typeSpec *ast.TypeSpec) error {
+func enrichResultIfaceRelationFromTypeSpec(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options, typeSpec *ast.TypeSpec) error {
 	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
 	if err != nil || ident != typeSpec.Name {
 		// Not on the name of the typespec, so skip it.
@@ -322,7 +458,7 @@ func enrichResultIfaceRelationFromTypeSpec(result *Result, pkg *packages.Package
 	}
 
 	relationSet := make(map[Relation]struct{})
-	err = forEachIfaceImplementingType(implObj, pkg, loc, searchDir, func(pkg *packages.Package, ifaceName string, ifaceType *types.Interface, implObj types.Object) {
+	err = forEachIfaceImplementingType(implObj, pkg, loc, searchDir, opts, func(pkg *packages.Package, ifaceName string, ifaceType *types.Interface, implObj types.Object) {
 		r := Relation{
 			Kind: RelationKindIface,
 			Pkg:  pkgNameForTypeObj(implObj),
@@ -335,11 +471,34 @@ func enrichResultIfaceRelationFromTypeSpec(result *Result, pkg *packages.Package
 		return err
 	}
 
+	universeIfaces, err := universeIfacesForType(implObj, pkg, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, ui := range universeIfaces {
+		r := universeIfaceRelation(ui, ui.obj.Name(), ui.obj)
+		if len(ui.missing) > 0 {
+			result.NearMisses = append(result.NearMisses, NearMiss{Relation: r, Missing: ui.missing})
+		} else {
+			addLabeledRelation(relationSet, r)
+		}
+	}
+	sortNearMisses(result.NearMisses)
+
 	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
 	return nil
 }
 
-func enrichResultIfaceRelationFromFuncDecl(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, funcDecl *ast.FuncDecl) error {
+// addLabeledRelation adds a relation labeled with its module, replacing the same relation without the label.
+func addLabeledRelation(relationSet map[Relation]struct{}, r Relation) {
+	unlabeled := r
+	unlabeled.Module, unlabeled.Version = "", ""
+	delete(relationSet, unlabeled)
+	relationSet[r] = struct{}{}
+}
+
+func enrichResultIfaceRelationFromFuncDecl(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options, funcDecl *ast.FuncDecl) error {
 	methodName := funcDecl.Name.Name
 
 	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
@@ -369,7 +528,7 @@ func enrichResultIfaceRelationFromFuncDecl(result *Result, pkg *packages.Package
 	}
 
 	relationSet := make(map[Relation]struct{})
-	err := forEachIfaceImplementingType(implObj, pkg, loc, searchDir, func(pkg *packages.Package, ifaceName string, ifaceType *types.Interface, implObj types.Object) {
+	err := forEachIfaceImplementingType(implObj, pkg, loc, searchDir, opts, func(pkg *packages.Package, ifaceName string, ifaceType *types.Interface, implObj types.Object) {
 		methodObj, _, _ := types.LookupFieldOrMethod(ifaceType, true, pkg.Types, methodName)
 		if methodObj != nil {
 			r := Relation{
@@ -385,25 +544,42 @@ func enrichResultIfaceRelationFromFuncDecl(result *Result, pkg *packages.Package
 		return err
 	}
 
+	universeIfaces, err := universeIfacesForType(implObj, pkg, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, ui := range universeIfaces {
+		if len(ui.missing) > 0 {
+			continue
+		}
+
+		methodObj, _, _ := types.LookupFieldOrMethod(ui.iface, true, ui.pkg.Types, methodName)
+		if methodObj != nil {
+			addLabeledRelation(relationSet, universeIfaceRelation(ui, fmt.Sprintf("%s.%s()", ui.obj.Name(), methodObj.Name()), methodObj))
+		}
+	}
+
 	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
 	return nil
 }
 
-func forEachIfaceImplementingType(implObj types.Object, pkg *packages.Package, loc file.Loc, searchDir string, f func(*packages.Package, string, *types.Interface, types.Object)) error {
+func forEachIfaceImplementingType(implObj types.Object, pkg *packages.Package, loc file.Loc, searchDir string, opts Options, f func(*packages.Package, string, *types.Interface, types.Object)) error {
 	loadMode := (packages.NeedName |
 		packages.NeedDeps |
 		packages.NeedTypes |
 		packages.NeedTypesInfo |
 		packages.NeedImports)
 
-	includeTests := isGoTestFile(loc.Path)
-	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, loadMode, includeTests, func(candidate skeletonPkg) bool {
+	includeTests := includeTestsForLoc(loc, opts)
+	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, opts, loadMode, includeTests, func(candidate skeletonPkg) bool {
 		return candidate.ImportPath == pkg.PkgPath || candidate.ImportsPkg(pkg.PkgPath)
 	})
 	if err != nil {
 		return err
 	}
 
+	visitedDepPkgs := make(map[*packages.Package]struct{})
 	for _, searchPkg := range searchPkgs {
 		// Lookup the impl type either in the package or its imports.
 		// We need this to find interfaces in this package that implement the target implementation.
@@ -447,11 +623,266 @@ func forEachIfaceImplementingType(implObj types.Object, pkg *packages.Package, l
 				f(searchPkg, obj.Name(), ifaceType, obj)
 			}
 		}
+
+		if opts.SearchDeps {
+			// Dependencies can't import the impl type, so search the interfaces they declare instead of the ones they use.
+			// These are comparable to the impl type because they're loaded along with the search package.
+			forEachDepPkg([]*packages.Package{searchPkg}, visitedDepPkgs, func(depPkg *packages.Package) {
+				scope := depPkg.Types.Scope()
+				for _, name := range scope.Names() {
+					obj, ok := scope.Lookup(name).(*types.TypeName)
+					if !ok || !obj.Exported() {
+						continue
+					}
+
+					// Skip empty interfaces, which every type implements.
+					ifaceType, ok := obj.Type().Underlying().(*types.Interface)
+					if !ok || ifaceType.Empty() {
+						continue
+					}
+
+					if _, ok := seen[obj]; ok {
+						continue
+					}
+					seen[obj] = struct{}{}
+
+					if types.Implements(pkgImplType, ifaceType) || types.Implements(types.NewPointer(pkgImplType), ifaceType) {
+						f(depPkg, obj.Name(), ifaceType, obj)
+					}
+				}
+			})
+		}
+	}
+
+	return nil
+}
+
+func enrichResultCallerRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	funcObj := funcObjDefinedAtLoc(pkg, loc)
+	if funcObj == nil {
+		return nil
+	}
+
+	// A method may be called through an interface it implements,
+	// so look for calls to the interface method as well as the method itself.
+	targetPositions := map[token.Position]struct{}{pkg.Fset.Position(funcObj.Pos()): {}}
+	targetPkgPaths := map[string]struct{}{pkg.PkgPath: {}}
+	if recvObj := recvTypeObjForFunc(funcObj); recvObj != nil && !types.IsInterface(recvObj.Type()) {
+		err := forEachIfaceImplementingType(recvObj, pkg, loc, searchDir, opts, func(ifacePkg *packages.Package, ifaceName string, ifaceType *types.Interface, ifaceObj types.Object) {
+			methodObj, _, _ := types.LookupFieldOrMethod(ifaceType, true, ifacePkg.Types, funcObj.Name())
+			if methodObj != nil && methodObj.Pkg() != nil {
+				targetPositions[ifacePkg.Fset.Position(methodObj.Pos())] = struct{}{}
+				targetPkgPaths[methodObj.Pkg().Path()] = struct{}{}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	loadMode := (packages.NeedName |
+		packages.NeedSyntax |
+		packages.NeedDeps |
+		packages.NeedTypes |
+		packages.NeedTypesInfo)
+
+	includeTests := includeTestsForLoc(loc, opts)
+	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, opts, loadMode, includeTests, func(candidate skeletonPkg) bool {
+		for pkgPath := range targetPkgPaths {
+			if candidate.ImportPath == pkgPath || (funcObj.Exported() && candidate.ImportsPkg(pkgPath)) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	relationSet := make(map[Relation]struct{})
+	for _, searchPkg := range searchPkgs {
+		for _, astFile := range searchPkg.Syntax {
+			ast.Inspect(astFile, func(node ast.Node) bool {
+				callExpr, ok := node.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				calleeIdent := calleeIdentForCallExpr(callExpr)
+				if calleeIdent == nil {
+					return true
+				}
+
+				calleeObj, ok := searchPkg.TypesInfo.Uses[calleeIdent]
+				if !ok {
+					return true
+				}
+
+				if _, ok := targetPositions[searchPkg.Fset.Position(calleeObj.Pos())]; !ok {
+					return true
+				}
+
+				r := Relation{
+					Kind: RelationKindCaller,
+					Pkg:  searchPkg.Name,
+					Name: nameForCallerRelation(searchPkg, astFile, calleeIdent),
+					Loc:  fileLocForIdent(searchPkg, calleeIdent),
+				}
+				relationSet[r] = struct{}{}
+				return true
+			})
+		}
+	}
+
+	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
+	return nil
+}
+
+// nameForCallerRelation names the function enclosing a call site.
+// If the call isn't in a function (for example, in a package-level variable declaration),
+// it falls back to the same name as a reference relation.
+func nameForCallerRelation(pkg *packages.Package, astFile *ast.File, calleeIdent *ast.Ident) string {
+	pos := calleeIdent.Pos()
+	for _, decl := range astFile.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if ok && funcDecl.Body != nil && pos >= funcDecl.Body.Lbrace && pos <= funcDecl.Body.Rbrace {
+			return fmt.Sprintf("%s()", funcNameForFuncDecl(funcDecl))
+		}
+	}
+	return nameForRefRelation(pkg, pos, calleeIdent.Name)
+}
+
+func enrichResultCalleeRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	funcDecl, err := astNodeAtLoc[*ast.FuncDecl](pkg, loc)
+	if err != nil || funcDecl.Body == nil {
+		return nil
+	}
+
+	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
+	if err != nil || ident != funcDecl.Name {
+		// Not on the name of the function, so skip it.
+		return nil
+	}
+
+	relationSet := make(map[Relation]struct{})
+	var ifaceMethodObjs []*types.Func
+	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		callExpr, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		calleeIdent := calleeIdentForCallExpr(callExpr)
+		if calleeIdent == nil {
+			return true
+		}
+
+		// Builtins, type conversions, and calls to function values don't resolve to a *types.Func.
+		calleeObj, ok := pkg.TypesInfo.Uses[calleeIdent].(*types.Func)
+		if !ok {
+			return true
+		}
+
+		r := Relation{
+			Kind: RelationKindCallee,
+			Pkg:  pkgNameForTypeObj(calleeObj),
+			Name: nameForFuncObj(calleeObj),
+			Loc:  fileLocForTypeObj(pkg, calleeObj),
+		}
+		relationSet[r] = struct{}{}
+
+		if recvObj := recvTypeObjForFunc(calleeObj); recvObj != nil && types.IsInterface(recvObj.Type()) {
+			ifaceMethodObjs = append(ifaceMethodObjs, calleeObj)
+		}
+
+		return true
+	})
+
+	// Calls to interface methods may dispatch to any implementation of the interface.
+	includeTests := includeTestsForLoc(loc, opts)
+	for _, methodObj := range ifaceMethodObjs {
+		recvObj := recvTypeObjForFunc(methodObj)
+		if recvObj.Pkg() == nil {
+			// Interfaces in the universe scope (like "error") can't be imported, so skip them.
+			continue
+		}
+
+		err := forEachTypeImplementingIface(recvObj.Pkg().Path(), recvObj.Name(), searchDir, opts, includeTests, func(searchPkg *packages.Package, implObj types.Object) {
+			if r, ok := methodImplRelation(RelationKindCallee, searchPkg, implObj, methodObj.Name()); ok {
+				relationSet[r] = struct{}{}
+			}
+		})
+		if err != nil {
+			return err
+		}
 	}
 
+	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
 	return nil
 }
 
+// funcObjDefinedAtLoc returns the function or method defined by the identifier at loc,
+// or nil if the identifier isn't the name in a function or method declaration.
+func funcObjDefinedAtLoc(pkg *packages.Package, loc file.Loc) *types.Func {
+	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
+	if err != nil {
+		return nil
+	}
+
+	funcObj, _ := pkg.TypesInfo.Defs[ident].(*types.Func)
+	return funcObj
+}
+
+// recvTypeObjForFunc returns the named receiver type of a method, or nil if the function isn't a method.
+func recvTypeObjForFunc(funcObj *types.Func) *types.TypeName {
+	sig, ok := funcObj.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return nil
+	}
+
+	recvType := sig.Recv().Type()
+	if ptrType, ok := recvType.(*types.Pointer); ok {
+		recvType = ptrType.Elem()
+	}
+
+	namedType, ok := recvType.(*types.Named)
+	if !ok {
+		return nil
+	}
+
+	return namedType.Obj()
+}
+
+func nameForFuncObj(funcObj *types.Func) string {
+	if recvObj := recvTypeObjForFunc(funcObj); recvObj != nil {
+		return fmt.Sprintf("%s.%s()", recvObj.Name(), funcObj.Name())
+	}
+	return fmt.Sprintf("%s()", funcObj.Name())
+}
+
+// calleeIdentForCallExpr returns the identifier for the function called by a call expression,
+// or nil if the function isn't named (for example, a call to a function literal).
+func calleeIdentForCallExpr(callExpr *ast.CallExpr) *ast.Ident {
+	fun := callExpr.Fun
+	for {
+		switch x := fun.(type) {
+		case *ast.ParenExpr:
+			fun = x.X
+		case *ast.IndexExpr:
+			// Explicit instantiation of a generic function, like f[int]().
+			fun = x.X
+		case *ast.IndexListExpr:
+			fun = x.X
+		case *ast.SelectorExpr:
+			return x.Sel
+		case *ast.Ident:
+			return x
+		default:
+			return nil
+		}
+	}
+}
+
 func typeObjUseOrDefForAstIdent(ident *ast.Ident, pkg *packages.Package) (types.Object, error) {
 	obj, ok := pkg.TypesInfo.Uses[ident]
 	if !ok {
@@ -472,25 +903,36 @@ func pkgNameForTypeObj(obj types.Object) string {
 	return obj.Pkg().Name()
 }
 
+// fileLocForTypeObj returns the location of the name in an object's declaration.
 func fileLocForTypeObj(pkg *packages.Package, obj types.Object) file.Loc {
 	if !obj.Pos().IsValid() {
 		return file.Loc{}
 	}
-	position := pkg.Fset.Position(obj.Pos())
-	return file.Loc{
-		Path:   position.Filename,
-		Line:   position.Line,
-		Column: position.Column,
+
+	nameLen := len(obj.Name())
+	if pkgName, ok := obj.(*types.PkgName); ok && isImportWithoutName(pkg, pkgName) {
+		// An import without a name is located at the import path.
+		nameLen = len(strconv.Quote(pkgName.Imported().Path()))
 	}
+
+	return file.LocForPositions(pkg.Fset.Position(obj.Pos()), pkg.Fset.Position(obj.Pos()+token.Pos(nameLen)))
 }
 
-func fileLocForIdent(pkg *packages.Package, ident *ast.Ident) file.Loc {
-	position := pkg.Fset.Position(ident.Pos())
-	return file.Loc{
-		Path:   position.Filename,
-		Line:   position.Line,
-		Column: position.Column,
+// isImportWithoutName checks whether a package name was declared implicitly by an import without a name.
+func isImportWithoutName(pkg *packages.Package, pkgName *types.PkgName) bool {
+	if pkg.TypesInfo == nil {
+		return false
+	}
+	for _, implicitObj := range pkg.TypesInfo.Implicits {
+		if implicitObj == pkgName {
+			return true
+		}
 	}
+	return false
+}
+
+func fileLocForIdent(pkg *packages.Package, ident *ast.Ident) file.Loc {
+	return file.LocForPositions(pkg.Fset.Position(ident.Pos()), pkg.Fset.Position(ident.End()))
 }
 
 func interfaceNameAndTypeAtFileLoc(pkg *packages.Package, loc file.Loc) (string, *types.Interface) {
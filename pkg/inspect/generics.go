@@ -0,0 +1,159 @@
+package inspect
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+// TypeParam is a type parameter of a generic function or type.
+type TypeParam struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+}
+
+// Instance is an instantiation of a generic function or type.
+type Instance struct {
+	TypeArgs []string `json:"typeArgs"`
+	Type     string   `json:"type"`
+}
+
+// typeParamsForObj returns the type parameters of a generic function or type,
+// or the type parameters of the receiver type for a method of a generic type.
+func typeParamsForObj(obj types.Object) []TypeParam {
+	var tparams *types.TypeParamList
+	switch obj := obj.(type) {
+	case *types.Func:
+		sig := obj.Type().(*types.Signature)
+		tparams = sig.TypeParams()
+		if tparams.Len() == 0 {
+			tparams = sig.RecvTypeParams()
+		}
+	case *types.TypeName:
+		if named, ok := obj.Type().(*types.Named); ok && !obj.IsAlias() {
+			tparams = named.TypeParams()
+		}
+	}
+
+	var result []TypeParam
+	for i := 0; i < tparams.Len(); i++ {
+		result = append(result, TypeParam{
+			Name:       tparams.At(i).Obj().Name(),
+			Constraint: tparams.At(i).Constraint().String(),
+		})
+	}
+	return result
+}
+
+// instanceForIdent returns the instantiation of a generic function or type at an identifier,
+// or nil if the identifier doesn't instantiate anything.
+func instanceForIdent(pkg *packages.Package, ident *ast.Ident) *Instance {
+	inst, ok := pkg.TypesInfo.Instances[ident]
+	if !ok {
+		return nil
+	}
+
+	typeArgs := make([]string, 0, inst.TypeArgs.Len())
+	for i := 0; i < inst.TypeArgs.Len(); i++ {
+		typeArgs = append(typeArgs, inst.TypeArgs.At(i).String())
+	}
+
+	return &Instance{
+		TypeArgs: typeArgs,
+		Type:     inst.Type.String(),
+	}
+}
+
+func enrichResultInstantiationRelation(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	ident, err := astNodeAtLoc[*ast.Ident](pkg, loc)
+	if err != nil {
+		return err
+	}
+
+	obj, err := typeObjUseOrDefForAstIdent(ident, pkg)
+	if err != nil {
+		return nil
+	}
+
+	genericObj := genericOriginForObj(obj)
+	if genericObj == nil || genericObj.Pkg() == nil {
+		return nil
+	}
+
+	targetPosition := pkg.Fset.Position(genericObj.Pos())
+	targetPkgPath := genericObj.Pkg().Path()
+
+	loadMode := (packages.NeedName |
+		packages.NeedSyntax |
+		packages.NeedDeps |
+		packages.NeedTypes |
+		packages.NeedTypesInfo)
+
+	includeTests := includeTestsForLoc(loc, opts)
+	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, opts, loadMode, includeTests, func(candidate skeletonPkg) bool {
+		return candidate.ImportPath == targetPkgPath || (genericObj.Exported() && candidate.ImportsPkg(targetPkgPath))
+	})
+	if err != nil {
+		return err
+	}
+
+	relationSet := make(map[Relation]struct{})
+	for _, searchPkg := range searchPkgs {
+		for instIdent, inst := range searchPkg.TypesInfo.Instances {
+			instObj := genericOriginForObj(searchPkg.TypesInfo.Uses[instIdent])
+			if instObj == nil || searchPkg.Fset.Position(instObj.Pos()) != targetPosition {
+				continue
+			}
+
+			qualifier := qualifierForPkg(searchPkg.Types)
+			typeArgs := make([]string, 0, inst.TypeArgs.Len())
+			for i := 0; i < inst.TypeArgs.Len(); i++ {
+				typeArgs = append(typeArgs, types.TypeString(inst.TypeArgs.At(i), qualifier))
+			}
+			instName := fmt.Sprintf("%s[%s]", instIdent.Name, strings.Join(typeArgs, ", "))
+
+			relationSet[Relation{
+				Kind: RelationKindInstantiation,
+				Pkg:  searchPkg.Name,
+				Name: nameForRefRelation(searchPkg, instIdent.Pos(), instName),
+				Loc:  fileLocForIdent(searchPkg, instIdent),
+			}] = struct{}{}
+		}
+	}
+
+	result.Relations = append(result.Relations, relationSetToSortedSlice(relationSet)...)
+	return nil
+}
+
+// genericOriginForObj returns the generic declaration of a function or type,
+// or nil if the object isn't a generic function or type.
+func genericOriginForObj(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Func:
+		origin := obj.Origin()
+		if origin.Type().(*types.Signature).TypeParams().Len() > 0 {
+			return origin
+		}
+	case *types.TypeName:
+		if named, ok := obj.Type().(*types.Named); ok && !obj.IsAlias() && named.TypeParams().Len() > 0 {
+			return named.Origin().Obj()
+		}
+	}
+	return nil
+}
+
+// qualifierForPkg omits the package for types in the current package,
+// and uses the package name (rather than the full import path) for everything else.
+func qualifierForPkg(pkg *types.Package) types.Qualifier {
+	return func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	}
+}
@@ -0,0 +1,168 @@
+package inspect
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
+)
+
+// refIndexNamespace identifies identifier use records in the on-disk index.
+// Increment the version whenever the record format changes.
+const refIndexNamespace = "refs-v4"
+
+// indexedRefs contains every identifier use in a package, along with the definition it refers to.
+type indexedRefs struct {
+	Uses []indexedUse
+}
+
+type indexedUse struct {
+	Def file.Loc
+	Ref Relation
+}
+
+// loadIndexedRefsMatchingPredicate loads identifier uses for packages matching a predicate,
+// typechecking only the packages that aren't indexed yet.
+func loadIndexedRefsMatchingPredicate(searchDir string, opts Options, includeTests bool, f func(skeletonPkg) bool) ([]indexedRefs, error) {
+	env := opts.Build.Env()
+	roots, err := findLoadRootsInSearchDir(searchDir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []indexedRefs
+	for _, root := range roots {
+		candidatePkgs, err := goListSkeletonPkgs(root, env, opts.SearchDeps, opts.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		var pkgPaths, depPkgPaths []string
+		for _, pkg := range candidatePkgs {
+			if f(pkg) {
+				pkgPaths, depPkgPaths = appendSkeletonPkgPath(pkgPaths, depPkgPaths, pkg)
+			}
+		}
+
+		if len(pkgPaths) > 0 {
+			refs, err := loadIndexedRefsForPkgPaths(root.Dir, opts, includeTests, pkgPaths)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, refs...)
+		}
+
+		if len(depPkgPaths) > 0 {
+			// Like loadGoPackagesMatchingPredicate, dependencies are loaded without their tests.
+			refs, err := loadIndexedRefsForPkgPaths(root.Dir, opts, false, depPkgPaths)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, refs...)
+		}
+	}
+
+	return result, nil
+}
+
+func loadIndexedRefsForPkgPaths(dir string, opts Options, includeTests bool, pkgPaths []string) ([]indexedRefs, error) {
+	// Loading metadata is much faster than parsing and typechecking,
+	// and it's enough to compute the key for each package.
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps,
+		Dir:   dir,
+		Tests: includeTests,
+		Env:   opts.Build.Env(),
+	}
+
+	pkgs, err := opts.Cache.Load(cfg, pkgPaths...)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	if includeTests {
+		pkgs = deduplicateTestPkgs(pkgs)
+	}
+
+	pkgKeys, err := opts.Index.PackageKeys(pkgs, index.EnvSalt(cfg.Env))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]indexedRefs, 0, len(pkgs))
+	missingPkgIDs := make(map[string]struct{})
+	var missingPatterns []string
+	for _, pkg := range pkgs {
+		var refs indexedRefs
+		ok, err := opts.Index.Get(refIndexNamespace, pkgKeys[pkg.ID], &refs)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			result = append(result, refs)
+		} else {
+			missingPkgIDs[pkg.ID] = struct{}{}
+			missingPatterns = append(missingPatterns, index.PatternForPkg(pkg))
+		}
+	}
+
+	if len(missingPkgIDs) == 0 {
+		return result, nil
+	}
+
+	// Typecheck only the packages that are missing from the index.
+	cfg.Mode = (packages.NeedName |
+		packages.NeedFiles |
+		packages.NeedSyntax |
+		packages.NeedDeps |
+		packages.NeedTypes |
+		packages.NeedTypesInfo)
+
+	loadedPkgs, err := opts.Cache.Load(cfg, missingPatterns...)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	if includeTests {
+		loadedPkgs = deduplicateTestPkgs(loadedPkgs)
+	}
+
+	for _, pkg := range loadedPkgs {
+		if _, ok := missingPkgIDs[pkg.ID]; !ok {
+			continue
+		}
+
+		refs := indexedRefsForPkg(pkg)
+		if err := opts.Index.Put(refIndexNamespace, pkgKeys[pkg.ID], refs); err != nil {
+			return nil, err
+		}
+		result = append(result, refs)
+	}
+
+	return result, nil
+}
+
+func indexedRefsForPkg(pkg *packages.Package) indexedRefs {
+	var refs indexedRefs
+	for refIdent, refObj := range pkg.TypesInfo.Uses {
+		if refObj.Pkg() == nil || !refObj.Pos().IsValid() {
+			// Builtins and universe objects don't have a definition to search for.
+			continue
+		}
+
+		refs.Uses = append(refs.Uses, indexedUse{
+			Def: fileLocForTypeObj(pkg, refObj),
+			Ref: Relation{
+				Kind:   RelationKindRef,
+				Pkg:    pkg.Name,
+				Name:   nameForRefRelation(pkg, refIdent.Pos(), refIdent.Name),
+				Loc:    fileLocForIdent(pkg, refIdent),
+				Access: refAccessForIdent(pkg, refIdent, refObj),
+			},
+		})
+	}
+	return refs
+}
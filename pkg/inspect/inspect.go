@@ -1,31 +1,115 @@
 package inspect
 
 import (
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/cache"
 	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
 )
 
+type Options struct {
+	// IncludeDoc populates the documentation fields of the result.
+	IncludeDoc bool
+
+	// RefAccesses restricts reference relations to references with one of these accesses.
+	// If empty, every reference is included.
+	RefAccesses []RefAccess
+
+	// IncludeTests searches test packages for relations, even if the identifier isn't in a test file.
+	IncludeTests bool
+
+	// Universe are package patterns, like "std" or "github.com/pkg/errors", whose exported interfaces are checked
+	// for interface relations, in addition to the interfaces used in the search directory.
+	// Interfaces that a type almost implements are reported in the result's NearMisses.
+	Universe []string
+
+	// SearchDeps also searches the dependencies of the modules in the search directory (vendored or in the module cache),
+	// except for the standard library. Relations in dependencies are labeled with their module path and version.
+	SearchDeps bool
+
+	// Build is the build configuration used to load packages.
+	// If it excludes the file being inspected, Inspect chooses a configuration that includes it.
+	Build buildcfg.Config
+
+	// Overlay replaces the contents of files on disk, such as files with unsaved changes in an editor.
+	// The on-disk index isn't used when there is an overlay, since it's keyed by the contents of files on disk.
+	Overlay file.Overlay
+
+	// Cache reuses loaded packages across calls to Inspect.
+	// If nil, every call loads packages from scratch.
+	Cache *cache.Cache `json:"-"`
+
+	// Index stores package metadata and identifier uses on disk,
+	// so searches don't need to typecheck unchanged packages again.
+	// If nil, every search loads packages from scratch.
+	Index *index.Index `json:"-"`
+
+	// loadAllSearchPkgs loads every package in a search directory when searching for relations,
+	// so a batch can reuse the same cached packages for every location.
+	loadAllSearchPkgs bool
+}
+
 type Result struct {
-	Name      string
-	Type      string
-	Relations []Relation
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Relations []Relation `json:"relations"`
+
+	// NearMisses are interfaces in the universe that the type almost implements, with the methods it's missing.
+	// They're only found for interface relations of a type when Options.Universe is set.
+	NearMisses []NearMiss `json:"nearMisses,omitempty"`
+
+	// TypeParams are the type parameters of a generic function or type,
+	// or of the receiver type for a method of a generic type.
+	TypeParams []TypeParam `json:"typeParams,omitempty"`
+
+	// Instance is the instantiation of a generic function or type at the identifier, if any.
+	Instance *Instance `json:"instance,omitempty"`
+
+	// Doc is the doc comment on the declaration of the identifier.
+	Doc string `json:"doc,omitempty"`
+
+	// Decl is the source code of the declaration of the identifier, excluding function bodies.
+	Decl string `json:"decl,omitempty"`
+
+	// PkgDoc is the synopsis of the doc comment for the package that declares the identifier.
+	PkgDoc string `json:"pkgDoc,omitempty"`
 }
 
-func Inspect(loc file.Loc, searchDir string, includeRelKinds []RelationKind) (*Result, error) {
-	pkg, err := loadGoPackageForFileLoc(loc)
+func Inspect(loc file.Loc, searchDir string, includeRelKinds []RelationKind, opts Options) (*Result, error) {
+	build, err := buildcfg.ConfigForFile(loc.Path, opts.Build, opts.Overlay)
 	if err != nil {
 		return nil, err
 	}
+	opts.Build = build
 
+	pkg, err := loadGoPackageForFileLoc(loc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return inspectPkg(pkg, loc, searchDir, includeRelKinds, opts)
+}
+
+func inspectPkg(pkg *packages.Package, loc file.Loc, searchDir string, includeRelKinds []RelationKind, opts Options) (*Result, error) {
 	enrichments := []enrichResultFunc{enrichResultNameAndType}
+	if opts.IncludeDoc {
+		enrichments = append(enrichments, enrichResultDoc)
+	}
 	for _, relKind := range includeRelKinds {
 		if e := enrichmentForRelKind(relKind); e != nil {
 			enrichments = append(enrichments, e)
 		}
 	}
+	if opts.SearchDeps {
+		// Must run last, after every relation has been added.
+		enrichments = append(enrichments, enrichResultRelationModules)
+	}
 
 	var result Result
 	for _, enrichFunc := range enrichments {
-		if err := enrichFunc(&result, pkg, loc, searchDir); err != nil {
+		if err := enrichFunc(&result, pkg, loc, searchDir, opts); err != nil {
 			return nil, err
 		}
 	}
@@ -37,12 +121,24 @@ func enrichmentForRelKind(relKind RelationKind) enrichResultFunc {
 	switch relKind {
 	case RelationKindDef:
 		return enrichResultDefRelation
+	case RelationKindTypeDef:
+		return enrichResultTypeDefRelation
 	case RelationKindRef:
 		return enrichResultRefRelation
 	case RelationKindImpl:
 		return enrichResultImplRelation
 	case RelationKindIface:
 		return enrichResultIfaceRelation
+	case RelationKindCaller:
+		return enrichResultCallerRelation
+	case RelationKindCallee:
+		return enrichResultCalleeRelation
+	case RelationKindEmbeds:
+		return enrichResultEmbedsRelation
+	case RelationKindEmbeddedBy:
+		return enrichResultEmbeddedByRelation
+	case RelationKindInstantiation:
+		return enrichResultInstantiationRelation
 	default:
 		return nil
 	}
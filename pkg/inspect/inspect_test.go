@@ -7,7 +7,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
 	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
 )
 
 func TestInspectLocalVariableDefinedInSameFunction(t *testing.T) {
@@ -15,7 +17,7 @@ func TestInspectLocalVariableDefinedInSameFunction(t *testing.T) {
 		Path:   "testdata/testmodule001/localvar.go",
 		Line:   7,
 		Column: 32,
-	}, "testdata/testmodule001", AllRelationKinds)
+	}, "testdata/testmodule001", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -30,6 +32,8 @@ func TestInspectLocalVariableDefinedInSameFunction(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule001/localvar.go"),
 					Line:   6,
 					Column: 2,
+					Offset: 43,
+					End:    file.Pos{Line: 6, Column: 10, Offset: 51},
 				},
 			},
 		},
@@ -42,7 +46,7 @@ func TestInspectStructTypeLiteral(t *testing.T) {
 		Path:   "testdata/testmodule002/struct.go",
 		Line:   11,
 		Column: 7,
-	}, "testdata/testmodule002", AllRelationKinds)
+	}, "testdata/testmodule002", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -57,6 +61,8 @@ func TestInspectStructTypeLiteral(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule002/struct.go"),
 					Line:   5,
 					Column: 6,
+					Offset: 33,
+					End:    file.Pos{Line: 5, Column: 16, Offset: 43},
 				},
 			},
 		},
@@ -69,7 +75,7 @@ func TestInspectStructLiteralField(t *testing.T) {
 		Path:   "testdata/testmodule002/struct.go",
 		Line:   12,
 		Column: 3,
-	}, "testdata/testmodule002", AllRelationKinds)
+	}, "testdata/testmodule002", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -84,6 +90,8 @@ func TestInspectStructLiteralField(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule002/struct.go"),
 					Line:   6,
 					Column: 2,
+					Offset: 54,
+					End:    file.Pos{Line: 6, Column: 13, Offset: 65},
 				},
 			},
 		},
@@ -96,7 +104,7 @@ func TestInspectStructSelectionField(t *testing.T) {
 		Path:   "testdata/testmodule002/struct.go",
 		Line:   16,
 		Column: 23,
-	}, "testdata/testmodule002", AllRelationKinds)
+	}, "testdata/testmodule002", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -111,6 +119,8 @@ func TestInspectStructSelectionField(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule002/struct.go"),
 					Line:   7,
 					Column: 2,
+					Offset: 74,
+					End:    file.Pos{Line: 7, Column: 10, Offset: 82},
 				},
 			},
 		},
@@ -123,7 +133,7 @@ func TestInspectStructMethodCall(t *testing.T) {
 		Path:   "testdata/testmodule004/methods.go",
 		Line:   28,
 		Column: 23,
-	}, "testdata/testmodule004", AllRelationKinds)
+	}, "testdata/testmodule004", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -138,6 +148,8 @@ func TestInspectStructMethodCall(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule004/methods.go"),
 					Line:   12,
 					Column: 22,
+					Offset: 142,
+					End:    file.Pos{Line: 12, Column: 28, Offset: 148},
 				},
 			},
 		},
@@ -150,7 +162,7 @@ func TestInspectStructMethodCallOnReturnedValue(t *testing.T) {
 		Path:   "testdata/testmodule004/methods.go",
 		Line:   29,
 		Column: 32,
-	}, "testdata/testmodule004", AllRelationKinds)
+	}, "testdata/testmodule004", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -165,6 +177,8 @@ func TestInspectStructMethodCallOnReturnedValue(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule004/methods.go"),
 					Line:   22,
 					Column: 26,
+					Offset: 312,
+					End:    file.Pos{Line: 22, Column: 32, Offset: 318},
 				},
 			},
 		},
@@ -177,7 +191,7 @@ func TestInspectInterfaceMethodCall(t *testing.T) {
 		Path:   "testdata/testmodule004/methods.go",
 		Line:   33,
 		Column: 27,
-	}, "testdata/testmodule004", AllRelationKinds)
+	}, "testdata/testmodule004", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -192,6 +206,8 @@ func TestInspectInterfaceMethodCall(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule004/methods.go"),
 					Line:   7,
 					Column: 2,
+					Offset: 74,
+					End:    file.Pos{Line: 7, Column: 10, Offset: 82},
 				},
 			},
 		},
@@ -204,7 +220,7 @@ func TestInspectInterfaceEmbeddedMethodCall(t *testing.T) {
 		Path:   "testdata/testmodule004/methods.go",
 		Line:   34,
 		Column: 27,
-	}, "testdata/testmodule004", AllRelationKinds)
+	}, "testdata/testmodule004", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.Equal(t, "String", result.Name)
@@ -219,7 +235,7 @@ func TestInspectFuncCallSameFile(t *testing.T) {
 		Path:   "testdata/testmodule003/func.go",
 		Line:   14,
 		Column: 9,
-	}, "testdata/testmodule004", AllRelationKinds)
+	}, "testdata/testmodule004", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -234,6 +250,8 @@ func TestInspectFuncCallSameFile(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule003/func.go"),
 					Line:   9,
 					Column: 6,
+					Offset: 121,
+					End:    file.Pos{Line: 9, Column: 20, Offset: 135},
 				},
 			},
 		},
@@ -246,7 +264,7 @@ func TestInspectFuncCallOtherFile(t *testing.T) {
 		Path:   "testdata/testmodule003/func.go",
 		Line:   15,
 		Column: 9,
-	}, "testdata/testmodule003", AllRelationKinds)
+	}, "testdata/testmodule003", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -261,6 +279,8 @@ func TestInspectFuncCallOtherFile(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule003/other.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 21, Offset: 43},
 				},
 			},
 		},
@@ -273,7 +293,7 @@ func TestInspectFuncCallOtherPkg(t *testing.T) {
 		Path:   "testdata/testmodule003/func.go",
 		Line:   16,
 		Column: 14,
-	}, "testdata/testmodule003", AllRelationKinds)
+	}, "testdata/testmodule003", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -288,6 +308,8 @@ func TestInspectFuncCallOtherPkg(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule003/subpkg/subpkgfunc.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 21,
+					End:    file.Pos{Line: 3, Column: 20, Offset: 35},
 				},
 			},
 		},
@@ -300,7 +322,7 @@ func TestInspectFuncCallStdlib(t *testing.T) {
 		Path:   "testdata/testmodule003/func.go",
 		Line:   17,
 		Column: 6,
-	}, "testdata/testmodule003", AllRelationKinds)
+	}, "testdata/testmodule003", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.Equal(t, "Printf", result.Name)
@@ -317,7 +339,7 @@ func TestInspectPkgNameInSelection(t *testing.T) {
 		Path:   "testdata/testmodule003/func.go",
 		Line:   17,
 		Column: 2,
-	}, "testdata/testmodule003", AllRelationKinds)
+	}, "testdata/testmodule003", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -332,6 +354,8 @@ func TestInspectPkgNameInSelection(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule003/func.go"),
 					Line:   4,
 					Column: 2,
+					Offset: 33,
+					End:    file.Pos{Line: 4, Column: 7, Offset: 38},
 				},
 			},
 		},
@@ -344,7 +368,7 @@ func TestInspectFileWithComments(t *testing.T) {
 		Path:   "testdata/testmodule005/comments.go",
 		Line:   12,
 		Column: 6,
-	}, "testdata/testmodule005", AllRelationKinds)
+	}, "testdata/testmodule005", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.Equal(t, "Println", result.Name)
@@ -356,12 +380,80 @@ func TestInspectFileWithComments(t *testing.T) {
 	assert.Contains(t, result.Relations[0].Path, "src/fmt")
 }
 
+func TestInspectDoc(t *testing.T) {
+	testCases := []struct {
+		name           string
+		loc            file.Loc
+		expectedName   string
+		expectedDoc    string
+		expectedDecl   string
+		expectedPkgDoc string
+	}{
+		{
+			name:           "const use",
+			loc:            file.Loc{Path: "testdata/testmodule005/greeting.go", Line: 12, Column: 35},
+			expectedName:   "Greeting",
+			expectedDoc:    "Greeting is the message printed by greet.\n",
+			expectedDecl:   `const Greeting = "Hello"`,
+			expectedPkgDoc: "Greeter prints greetings to standard output.",
+		},
+		{
+			name:           "func definition",
+			loc:            file.Loc{Path: "testdata/testmodule005/greeting.go", Line: 11, Column: 6},
+			expectedName:   "greet",
+			expectedDoc:    "greet prints a greeting.\n\nIt returns the number of bytes written.\n",
+			expectedDecl:   "func greet(name string) int",
+			expectedPkgDoc: "Greeter prints greetings to standard output.",
+		},
+		{
+			name:           "param",
+			loc:            file.Loc{Path: "testdata/testmodule005/greeting.go", Line: 12, Column: 45},
+			expectedName:   "name",
+			expectedDecl:   "name string",
+			expectedPkgDoc: "Greeter prints greetings to standard output.",
+		},
+		{
+			name:           "local variable",
+			loc:            file.Loc{Path: "testdata/testmodule005/greeting.go", Line: 13, Column: 9},
+			expectedName:   "n",
+			expectedDecl:   `n, _ := fmt.Printf("%s, %s!\n", Greeting, name)`,
+			expectedPkgDoc: "Greeter prints greetings to standard output.",
+		},
+		{
+			name:           "imported func",
+			loc:            file.Loc{Path: "testdata/testmodule005/greeting.go", Line: 12, Column: 14},
+			expectedName:   "Printf",
+			expectedDoc:    "Printf formats according to a format specifier and writes to standard output.\nIt returns the number of bytes written and any write error encountered.\n",
+			expectedDecl:   "func Printf(format string, a ...any) (n int, err error)",
+			expectedPkgDoc: "Package fmt implements formatted I/O with functions analogous to C's printf and scanf.",
+		},
+		{
+			name:           "imported package name",
+			loc:            file.Loc{Path: "testdata/testmodule005/greeting.go", Line: 12, Column: 10},
+			expectedName:   "fmt",
+			expectedDecl:   `import "fmt"`,
+			expectedPkgDoc: "Package fmt implements formatted I/O with functions analogous to C's printf and scanf.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Inspect(tc.loc, "testdata/testmodule005", nil, Options{IncludeDoc: true})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedName, result.Name)
+			assert.Equal(t, tc.expectedDoc, result.Doc)
+			assert.Equal(t, tc.expectedDecl, result.Decl)
+			assert.Equal(t, tc.expectedPkgDoc, result.PkgDoc)
+		})
+	}
+}
+
 func TestInspectIntegerLiteral(t *testing.T) {
 	result, err := Inspect(file.Loc{
 		Path:   "testdata/testmodule006/const.go",
 		Line:   5,
 		Column: 18,
-	}, "testdata/testmodule006", AllRelationKinds)
+	}, "testdata/testmodule006", AllRelationKinds, Options{})
 	assert.EqualError(t, err, "Could not find AST node of type *ast.Ident at location testdata/testmodule006/const.go:5:18")
 	assert.Nil(t, result)
 }
@@ -371,7 +463,7 @@ func TestInspectIntegerConst(t *testing.T) {
 		Path:   "testdata/testmodule006/const.go",
 		Line:   13,
 		Column: 23,
-	}, "testdata/testmodule006", AllRelationKinds)
+	}, "testdata/testmodule006", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -386,6 +478,8 @@ func TestInspectIntegerConst(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule006/const.go"),
 					Line:   5,
 					Column: 7,
+					Offset: 34,
+					End:    file.Pos{Line: 5, Column: 15, Offset: 42},
 				},
 			},
 		},
@@ -398,7 +492,7 @@ func TestInspectNil(t *testing.T) {
 		Path:   "testdata/testmodule006/const.go",
 		Line:   9,
 		Column: 10,
-	}, "testdata/testmodule006", AllRelationKinds)
+	}, "testdata/testmodule006", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	expected := &Result{
 		Name: "nil",
@@ -412,7 +506,7 @@ func TestInspectEmbeddedStruct(t *testing.T) {
 		Path:   "testdata/testmodule007/struct.go",
 		Line:   6,
 		Column: 10,
-	}, "testdata/testmodule007", AllRelationKinds)
+	}, "testdata/testmodule007", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -427,6 +521,8 @@ func TestInspectEmbeddedStruct(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule007/subpkg/struct.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 21,
+					End:    file.Pos{Line: 3, Column: 20, Offset: 35},
 				},
 			},
 		},
@@ -439,7 +535,7 @@ func TestInspectFileWithCGo(t *testing.T) {
 		Path:   "testdata/testmodule008/cgo.go",
 		Line:   6,
 		Column: 6,
-	}, "testdata/testmodule008", AllRelationKinds)
+	}, "testdata/testmodule008", AllRelationKinds, Options{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	expected := &Result{
@@ -454,6 +550,8 @@ func TestInspectFileWithCGo(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule008/cgo.go"),
 					Line:   6,
 					Column: 6,
+					Offset: 62,
+					End:    file.Pos{Line: 6, Column: 14, Offset: 70},
 				},
 			},
 		},
@@ -466,7 +564,7 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 		Path:   "testdata/testmodule009/iface.go",
 		Line:   3,
 		Column: 7,
-	}, "testdata/testmodule009", AllRelationKinds)
+	}, "testdata/testmodule009", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -481,6 +579,8 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/iface.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 17, Offset: 39},
 				},
 			},
 			{
@@ -491,7 +591,10 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/impl.go"),
 					Line:   23,
 					Column: 7,
+					Offset: 430,
+					End:    file.Pos{Line: 23, Column: 18, Offset: 441},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -501,7 +604,10 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/impl.go"),
 					Line:   24,
 					Column: 7,
+					Offset: 468,
+					End:    file.Pos{Line: 24, Column: 18, Offset: 479},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -511,7 +617,10 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/subpkg/impl.go"),
 					Line:   17,
 					Column: 9,
+					Offset: 313,
+					End:    file.Pos{Line: 17, Column: 20, Offset: 324},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "implementation",
@@ -521,6 +630,8 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/impl.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 21, Offset: 43},
 				},
 			},
 			{
@@ -531,6 +642,8 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/impl.go"),
 					Line:   13,
 					Column: 6,
+					Offset: 217,
+					End:    file.Pos{Line: 13, Column: 28, Offset: 239},
 				},
 			},
 			{
@@ -541,6 +654,8 @@ func TestInspectInterfaceWithImpl(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/subpkg/impl.go"),
 					Line:   7,
 					Column: 6,
+					Offset: 101,
+					End:    file.Pos{Line: 7, Column: 27, Offset: 122},
 				},
 			},
 		},
@@ -553,7 +668,7 @@ func TestInspectImplOfInterface(t *testing.T) {
 		Path:   "testdata/testmodule009/impl.go",
 		Line:   3,
 		Column: 7,
-	}, "testdata/testmodule009", []RelationKind{RelationKindIface})
+	}, "testdata/testmodule009", []RelationKind{RelationKindIface}, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -568,6 +683,8 @@ func TestInspectImplOfInterface(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/iface.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 17, Offset: 39},
 				},
 			},
 		},
@@ -580,7 +697,7 @@ func TestInspectPointerImplOfInterface(t *testing.T) {
 		Path:   "testdata/testmodule009/impl.go",
 		Line:   13,
 		Column: 7,
-	}, "testdata/testmodule009", []RelationKind{RelationKindIface})
+	}, "testdata/testmodule009", []RelationKind{RelationKindIface}, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -595,6 +712,8 @@ func TestInspectPointerImplOfInterface(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/iface.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 17, Offset: 39},
 				},
 			},
 		},
@@ -607,7 +726,7 @@ func TestInspectInterfaceWithImplMethod(t *testing.T) {
 		Path:   "testdata/testmodule009/iface.go",
 		Line:   5,
 		Column: 3,
-	}, "testdata/testmodule009", AllRelationKinds)
+	}, "testdata/testmodule009", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -622,6 +741,8 @@ func TestInspectInterfaceWithImplMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/iface.go"),
 					Line:   5,
 					Column: 2,
+					Offset: 78,
+					End:    file.Pos{Line: 5, Column: 16, Offset: 92},
 				},
 			},
 			{
@@ -632,6 +753,8 @@ func TestInspectInterfaceWithImplMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/impl.go"),
 					Line:   9,
 					Column: 26,
+					Offset: 148,
+					End:    file.Pos{Line: 9, Column: 40, Offset: 162},
 				},
 			},
 			{
@@ -642,6 +765,8 @@ func TestInspectInterfaceWithImplMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/impl.go"),
 					Line:   19,
 					Column: 34,
+					Offset: 360,
+					End:    file.Pos{Line: 19, Column: 48, Offset: 374},
 				},
 			},
 			{
@@ -652,6 +777,8 @@ func TestInspectInterfaceWithImplMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/subpkg/impl.go"),
 					Line:   13,
 					Column: 32,
+					Offset: 239,
+					End:    file.Pos{Line: 13, Column: 46, Offset: 253},
 				},
 			},
 		},
@@ -664,7 +791,7 @@ func TestInspectImplOfInterfaceMethod(t *testing.T) {
 		Path:   "testdata/testmodule009/impl.go",
 		Line:   5,
 		Column: 26,
-	}, "testdata/testmodule009", []RelationKind{RelationKindIface})
+	}, "testdata/testmodule009", []RelationKind{RelationKindIface}, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -679,6 +806,8 @@ func TestInspectImplOfInterfaceMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/iface.go"),
 					Line:   4,
 					Column: 2,
+					Offset: 53,
+					End:    file.Pos{Line: 4, Column: 16, Offset: 67},
 				},
 			},
 		},
@@ -691,7 +820,7 @@ func TestInspectImplOfInterfaceMethodWithPointerReceiver(t *testing.T) {
 		Path:   "testdata/testmodule009/impl.go",
 		Line:   19,
 		Column: 37,
-	}, "testdata/testmodule009", []RelationKind{RelationKindIface})
+	}, "testdata/testmodule009", []RelationKind{RelationKindIface}, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -706,6 +835,8 @@ func TestInspectImplOfInterfaceMethodWithPointerReceiver(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule009/iface.go"),
 					Line:   5,
 					Column: 2,
+					Offset: 78,
+					End:    file.Pos{Line: 5, Column: 16, Offset: 92},
 				},
 			},
 		},
@@ -718,7 +849,7 @@ func TestInspectInterfaceWithImplAndIfaceInDifferentPkgs(t *testing.T) {
 		Path:   "testdata/testmodule010/subpkgWithIface/iface.go",
 		Line:   5,
 		Column: 7,
-	}, "testdata/testmodule010", AllRelationKinds)
+	}, "testdata/testmodule010", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -733,6 +864,8 @@ func TestInspectInterfaceWithImplAndIfaceInDifferentPkgs(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule010/subpkgWithIface/iface.go"),
 					Line:   5,
 					Column: 6,
+					Offset: 44,
+					End:    file.Pos{Line: 5, Column: 17, Offset: 55},
 				},
 			},
 			{
@@ -743,7 +876,10 @@ func TestInspectInterfaceWithImplAndIfaceInDifferentPkgs(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule010/subpkgWithIface/iface.go"),
 					Line:   9,
 					Column: 14,
+					Offset: 104,
+					End:    file.Pos{Line: 9, Column: 25, Offset: 115},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "implementation",
@@ -753,6 +889,8 @@ func TestInspectInterfaceWithImplAndIfaceInDifferentPkgs(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule010/subpkgWithImpl/impl.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 29,
+					End:    file.Pos{Line: 3, Column: 21, Offset: 44},
 				},
 			},
 		},
@@ -765,7 +903,7 @@ func TestInspectStructWithReference(t *testing.T) {
 		Path:   "testdata/testmodule011/subpkg/def.go",
 		Line:   3,
 		Column: 7,
-	}, "testdata/testmodule011", AllRelationKinds)
+	}, "testdata/testmodule011", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -780,6 +918,8 @@ func TestInspectStructWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 21,
+					End:    file.Pos{Line: 3, Column: 14, Offset: 29},
 				},
 			},
 			{
@@ -790,7 +930,10 @@ func TestInspectStructWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/main.go"),
 					Line:   8,
 					Column: 14,
+					Offset: 126,
+					End:    file.Pos{Line: 8, Column: 22, Offset: 134},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -800,7 +943,10 @@ func TestInspectStructWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   15,
 					Column: 9,
+					Offset: 154,
+					End:    file.Pos{Line: 15, Column: 17, Offset: 162},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -810,7 +956,10 @@ func TestInspectStructWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
 					Line:   7,
 					Column: 22,
+					Offset: 56,
+					End:    file.Pos{Line: 7, Column: 30, Offset: 64},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "interface",
@@ -820,6 +969,8 @@ func TestInspectStructWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   11,
 					Column: 6,
+					Offset: 100,
+					End:    file.Pos{Line: 11, Column: 17, Offset: 111},
 				},
 			},
 		},
@@ -832,7 +983,7 @@ func TestInspectStructFieldWithReference(t *testing.T) {
 		Path:   "testdata/testmodule011/subpkg/def.go",
 		Line:   4,
 		Column: 2,
-	}, "testdata/testmodule011", AllRelationKinds)
+	}, "testdata/testmodule011", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -847,6 +998,8 @@ func TestInspectStructFieldWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   4,
 					Column: 2,
+					Offset: 40,
+					End:    file.Pos{Line: 4, Column: 5, Offset: 43},
 				},
 			},
 			{
@@ -857,7 +1010,10 @@ func TestInspectStructFieldWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/main.go"),
 					Line:   8,
 					Column: 23,
+					Offset: 135,
+					End:    file.Pos{Line: 8, Column: 26, Offset: 138},
 				},
+				Access: RefAccessFieldKey,
 			},
 		},
 	}
@@ -869,7 +1025,7 @@ func TestInspectStructMethodWithReference(t *testing.T) {
 		Path:   "testdata/testmodule011/subpkg/def.go",
 		Line:   15,
 		Column: 20,
-	}, "testdata/testmodule011", AllRelationKinds)
+	}, "testdata/testmodule011", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -884,6 +1040,8 @@ func TestInspectStructMethodWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   15,
 					Column: 19,
+					Offset: 164,
+					End:    file.Pos{Line: 15, Column: 27, Offset: 172},
 				},
 			},
 			{
@@ -894,7 +1052,10 @@ func TestInspectStructMethodWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
 					Line:   9,
 					Column: 21,
+					Offset: 109,
+					End:    file.Pos{Line: 9, Column: 29, Offset: 117},
 				},
+				Access: RefAccessCall,
 			},
 			{
 				Kind: "interface",
@@ -904,6 +1065,32 @@ func TestInspectStructMethodWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   12,
 					Column: 2,
+					Offset: 125,
+					End:    file.Pos{Line: 12, Column: 10, Offset: 133},
+				},
+			},
+			{
+				Kind: "caller",
+				Pkg:  "subpkg",
+				Name: "PrintMyStruct()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
+					Line:   9,
+					Column: 21,
+					Offset: 109,
+					End:    file.Pos{Line: 9, Column: 29, Offset: 117},
+				},
+			},
+			{
+				Kind: "caller",
+				Pkg:  "subpkg",
+				Name: "PrintMyInterface()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
+					Line:   18,
+					Column: 21,
+					Offset: 242,
+					End:    file.Pos{Line: 18, Column: 29, Offset: 250},
 				},
 			},
 		},
@@ -916,7 +1103,7 @@ func TestInspectFunctionWithReference(t *testing.T) {
 		Path:   "testdata/testmodule011/subpkg/def.go",
 		Line:   7,
 		Column: 7,
-	}, "testdata/testmodule011", AllRelationKinds)
+	}, "testdata/testmodule011", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -931,6 +1118,8 @@ func TestInspectFunctionWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   7,
 					Column: 6,
+					Offset: 59,
+					End:    file.Pos{Line: 7, Column: 12, Offset: 65},
 				},
 			},
 			{
@@ -941,6 +1130,21 @@ func TestInspectFunctionWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
 					Line:   13,
 					Column: 7,
+					Offset: 150,
+					End:    file.Pos{Line: 13, Column: 13, Offset: 156},
+				},
+				Access: RefAccessCall,
+			},
+			{
+				Kind: "caller",
+				Pkg:  "subpkg",
+				Name: "CallMyFunc()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
+					Line:   13,
+					Column: 7,
+					Offset: 150,
+					End:    file.Pos{Line: 13, Column: 13, Offset: 156},
 				},
 			},
 		},
@@ -953,7 +1157,7 @@ func TestInspectIfaceMethodWithReference(t *testing.T) {
 		Path:   "testdata/testmodule011/subpkg/def.go",
 		Line:   12,
 		Column: 3,
-	}, "testdata/testmodule011", AllRelationKinds)
+	}, "testdata/testmodule011", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -968,6 +1172,8 @@ func TestInspectIfaceMethodWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   12,
 					Column: 2,
+					Offset: 125,
+					End:    file.Pos{Line: 12, Column: 10, Offset: 133},
 				},
 			},
 			{
@@ -978,7 +1184,10 @@ func TestInspectIfaceMethodWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
 					Line:   18,
 					Column: 21,
+					Offset: 242,
+					End:    file.Pos{Line: 18, Column: 29, Offset: 250},
 				},
+				Access: RefAccessCall,
 			},
 			{
 				Kind: "implementation",
@@ -988,6 +1197,20 @@ func TestInspectIfaceMethodWithReference(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule011/subpkg/def.go"),
 					Line:   15,
 					Column: 19,
+					Offset: 164,
+					End:    file.Pos{Line: 15, Column: 27, Offset: 172},
+				},
+			},
+			{
+				Kind: "caller",
+				Pkg:  "subpkg",
+				Name: "PrintMyInterface()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule011/subpkg/ref.go"),
+					Line:   18,
+					Column: 21,
+					Offset: 242,
+					End:    file.Pos{Line: 18, Column: 29, Offset: 250},
 				},
 			},
 		},
@@ -1000,7 +1223,7 @@ func TestInspectStructReturnedFromStructMethod(t *testing.T) {
 		Path:   "testdata/testmodule012/main.go",
 		Line:   14,
 		Column: 7,
-	}, "testdata/testmodule012", AllRelationKinds)
+	}, "testdata/testmodule012", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1015,6 +1238,8 @@ func TestInspectStructReturnedFromStructMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule012/main.go"),
 					Line:   14,
 					Column: 6,
+					Offset: 161,
+					End:    file.Pos{Line: 14, Column: 18, Offset: 173},
 				},
 			},
 			{
@@ -1025,7 +1250,10 @@ func TestInspectStructReturnedFromStructMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule012/main.go"),
 					Line:   18,
 					Column: 43,
+					Offset: 238,
+					End:    file.Pos{Line: 18, Column: 55, Offset: 250},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -1035,7 +1263,10 @@ func TestInspectStructReturnedFromStructMethod(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule012/main.go"),
 					Line:   19,
 					Column: 9,
+					Offset: 261,
+					End:    file.Pos{Line: 19, Column: 21, Offset: 273},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
@@ -1047,7 +1278,7 @@ func TestInspectStructFieldFromReturnValue(t *testing.T) {
 		Path:   "testdata/testmodule012/main.go",
 		Line:   15,
 		Column: 2,
-	}, "testdata/testmodule012", AllRelationKinds)
+	}, "testdata/testmodule012", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1062,6 +1293,8 @@ func TestInspectStructFieldFromReturnValue(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule012/main.go"),
 					Line:   15,
 					Column: 2,
+					Offset: 184,
+					End:    file.Pos{Line: 15, Column: 3, Offset: 185},
 				},
 			},
 			{
@@ -1072,7 +1305,10 @@ func TestInspectStructFieldFromReturnValue(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule012/main.go"),
 					Line:   6,
 					Column: 50,
+					Offset: 91,
+					End:    file.Pos{Line: 6, Column: 51, Offset: 92},
 				},
+				Access: RefAccessRead,
 			},
 			{
 				Kind: "reference",
@@ -1082,7 +1318,10 @@ func TestInspectStructFieldFromReturnValue(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule012/main.go"),
 					Line:   19,
 					Column: 22,
+					Offset: 274,
+					End:    file.Pos{Line: 19, Column: 23, Offset: 275},
 				},
+				Access: RefAccessFieldKey,
 			},
 		},
 	}
@@ -1094,7 +1333,7 @@ func TestInspectStructWithVarDeclaration(t *testing.T) {
 		Path:   "testdata/testmodule013/values.go",
 		Line:   3,
 		Column: 7,
-	}, "testdata/testmodule013", AllRelationKinds)
+	}, "testdata/testmodule013", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1109,6 +1348,8 @@ func TestInspectStructWithVarDeclaration(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/values.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 14, Offset: 36},
 				},
 			},
 			{
@@ -1119,7 +1360,10 @@ func TestInspectStructWithVarDeclaration(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/values.go"),
 					Line:   8,
 					Column: 11,
+					Offset: 79,
+					End:    file.Pos{Line: 8, Column: 19, Offset: 87},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
@@ -1131,7 +1375,7 @@ func TestInspectTypeWithVarAndConstDeclaration(t *testing.T) {
 		Path:   "testdata/testmodule013/values.go",
 		Line:   5,
 		Column: 7,
-	}, "testdata/testmodule013", AllRelationKinds)
+	}, "testdata/testmodule013", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1146,6 +1390,8 @@ func TestInspectTypeWithVarAndConstDeclaration(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/values.go"),
 					Line:   5,
 					Column: 6,
+					Offset: 52,
+					End:    file.Pos{Line: 5, Column: 11, Offset: 57},
 				},
 			},
 			{
@@ -1156,7 +1402,10 @@ func TestInspectTypeWithVarAndConstDeclaration(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/values.go"),
 					Line:   9,
 					Column: 11,
+					Offset: 98,
+					End:    file.Pos{Line: 9, Column: 16, Offset: 103},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -1166,7 +1415,10 @@ func TestInspectTypeWithVarAndConstDeclaration(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/values.go"),
 					Line:   12,
 					Column: 9,
+					Offset: 115,
+					End:    file.Pos{Line: 12, Column: 14, Offset: 120},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
@@ -1178,7 +1430,7 @@ func TestInspectTypeWithReferenceInFuncTypeArg(t *testing.T) {
 		Path:   "testdata/testmodule013/func.go",
 		Line:   5,
 		Column: 7,
-	}, "testdata/testmodule013", AllRelationKinds)
+	}, "testdata/testmodule013", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1193,6 +1445,8 @@ func TestInspectTypeWithReferenceInFuncTypeArg(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/func.go"),
 					Line:   5,
 					Column: 6,
+					Offset: 42,
+					End:    file.Pos{Line: 5, Column: 11, Offset: 47},
 				},
 			},
 			{
@@ -1203,7 +1457,10 @@ func TestInspectTypeWithReferenceInFuncTypeArg(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/func.go"),
 					Line:   9,
 					Column: 20,
+					Offset: 94,
+					End:    file.Pos{Line: 9, Column: 25, Offset: 99},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -1213,7 +1470,10 @@ func TestInspectTypeWithReferenceInFuncTypeArg(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/func.go"),
 					Line:   17,
 					Column: 21,
+					Offset: 245,
+					End:    file.Pos{Line: 17, Column: 26, Offset: 250},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
@@ -1225,7 +1485,7 @@ func TestInspectTypeWithReferenceInFuncTypeReturn(t *testing.T) {
 		Path:   "testdata/testmodule013/func.go",
 		Line:   7,
 		Column: 7,
-	}, "testdata/testmodule013", AllRelationKinds)
+	}, "testdata/testmodule013", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1240,6 +1500,8 @@ func TestInspectTypeWithReferenceInFuncTypeReturn(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/func.go"),
 					Line:   7,
 					Column: 6,
+					Offset: 61,
+					End:    file.Pos{Line: 7, Column: 14, Offset: 69},
 				},
 			},
 			{
@@ -1250,7 +1512,10 @@ func TestInspectTypeWithReferenceInFuncTypeReturn(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/func.go"),
 					Line:   9,
 					Column: 27,
+					Offset: 101,
+					End:    file.Pos{Line: 9, Column: 35, Offset: 109},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -1260,7 +1525,10 @@ func TestInspectTypeWithReferenceInFuncTypeReturn(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/func.go"),
 					Line:   17,
 					Column: 28,
+					Offset: 252,
+					End:    file.Pos{Line: 17, Column: 36, Offset: 260},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -1270,7 +1538,10 @@ func TestInspectTypeWithReferenceInFuncTypeReturn(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/func.go"),
 					Line:   18,
 					Column: 11,
+					Offset: 273,
+					End:    file.Pos{Line: 18, Column: 19, Offset: 281},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
@@ -1282,7 +1553,7 @@ func TestInspectTypeWithEmbeddedStructRef(t *testing.T) {
 		Path:   "testdata/testmodule013/embed.go",
 		Line:   3,
 		Column: 7,
-	}, "testdata/testmodule013", AllRelationKinds)
+	}, "testdata/testmodule013", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1297,6 +1568,8 @@ func TestInspectTypeWithEmbeddedStructRef(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/embed.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 22, Offset: 44},
 				},
 			},
 			{
@@ -1307,6 +1580,21 @@ func TestInspectTypeWithEmbeddedStructRef(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/embed.go"),
 					Line:   6,
 					Column: 2,
+					Offset: 85,
+					End:    file.Pos{Line: 6, Column: 18, Offset: 101},
+				},
+				Access: RefAccessType,
+			},
+			{
+				Kind: "embedded-by",
+				Pkg:  "testmodule013",
+				Name: "MyParentStruct",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule013/embed.go"),
+					Line:   5,
+					Column: 6,
+					Offset: 60,
+					End:    file.Pos{Line: 5, Column: 20, Offset: 74},
 				},
 			},
 		},
@@ -1319,7 +1607,7 @@ func TestInspectTypeReturnedByMethodWithPointerReceiver(t *testing.T) {
 		Path:   "testdata/testmodule013/method.go",
 		Line:   3,
 		Column: 7,
-	}, "testdata/testmodule013", AllRelationKinds)
+	}, "testdata/testmodule013", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1334,6 +1622,8 @@ func TestInspectTypeReturnedByMethodWithPointerReceiver(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/method.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 20, Offset: 42},
 				},
 			},
 			{
@@ -1344,7 +1634,10 @@ func TestInspectTypeReturnedByMethodWithPointerReceiver(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/method.go"),
 					Line:   7,
 					Column: 46,
+					Offset: 132,
+					End:    file.Pos{Line: 7, Column: 60, Offset: 146},
 				},
+				Access: RefAccessType,
 			},
 			{
 				Kind: "reference",
@@ -1354,7 +1647,10 @@ func TestInspectTypeReturnedByMethodWithPointerReceiver(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule013/method.go"),
 					Line:   8,
 					Column: 9,
+					Offset: 157,
+					End:    file.Pos{Line: 8, Column: 23, Offset: 171},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
@@ -1366,7 +1662,7 @@ func TestInspectLocalVar(t *testing.T) {
 		Path:   "testdata/testmodule014/localref.go",
 		Line:   6,
 		Column: 2,
-	}, "testdata/testmodule014", AllRelationKinds)
+	}, "testdata/testmodule014", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1381,6 +1677,8 @@ func TestInspectLocalVar(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule014/localref.go"),
 					Line:   6,
 					Column: 2,
+					Offset: 64,
+					End:    file.Pos{Line: 6, Column: 3, Offset: 65},
 				},
 			},
 			{
@@ -1391,7 +1689,10 @@ func TestInspectLocalVar(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule014/localref.go"),
 					Line:   7,
 					Column: 19,
+					Offset: 94,
+					End:    file.Pos{Line: 7, Column: 20, Offset: 95},
 				},
+				Access: RefAccessRead,
 			},
 		},
 	}
@@ -1403,7 +1704,7 @@ func TestInspectPrivateVar(t *testing.T) {
 		Path:   "testdata/testmodule014/privatevar.go",
 		Line:   5,
 		Column: 5,
-	}, "testdata/testmodule014", AllRelationKinds)
+	}, "testdata/testmodule014", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1418,6 +1719,8 @@ func TestInspectPrivateVar(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule014/privatevar.go"),
 					Line:   5,
 					Column: 5,
+					Offset: 41,
+					End:    file.Pos{Line: 5, Column: 15, Offset: 51},
 				},
 			},
 			{
@@ -1428,7 +1731,10 @@ func TestInspectPrivateVar(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule014/privatevar.go"),
 					Line:   8,
 					Column: 19,
+					Offset: 104,
+					End:    file.Pos{Line: 8, Column: 29, Offset: 114},
 				},
+				Access: RefAccessRead,
 			},
 		},
 	}
@@ -1440,7 +1746,7 @@ func TestInspectStructWithReferenceInTest(t *testing.T) {
 		Path:   "testdata/testmodule015/def.go",
 		Line:   3,
 		Column: 6,
-	}, "testdata/testmodule015", AllRelationKinds)
+	}, "testdata/testmodule015", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1455,6 +1761,8 @@ func TestInspectStructWithReferenceInTest(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule015/def.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 14, Offset: 36},
 				},
 			},
 			{
@@ -1465,7 +1773,10 @@ func TestInspectStructWithReferenceInTest(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule015/def.go"),
 					Line:   7,
 					Column: 15,
+					Offset: 79,
+					End:    file.Pos{Line: 7, Column: 23, Offset: 87},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
@@ -1477,7 +1788,7 @@ func TestInspectReferenceToStructInTest(t *testing.T) {
 		Path:   "testdata/testmodule015/def_test.go",
 		Line:   10,
 		Column: 7,
-	}, "testdata/testmodule015", AllRelationKinds)
+	}, "testdata/testmodule015", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1492,6 +1803,8 @@ func TestInspectReferenceToStructInTest(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule015/def.go"),
 					Line:   3,
 					Column: 6,
+					Offset: 28,
+					End:    file.Pos{Line: 3, Column: 14, Offset: 36},
 				},
 			},
 		},
@@ -1504,7 +1817,7 @@ func TestInspectStructDefinedAndReferencedInTest(t *testing.T) {
 		Path:   "testdata/testmodule015/def_test.go",
 		Line:   5,
 		Column: 6,
-	}, "testdata/testmodule015", AllRelationKinds)
+	}, "testdata/testmodule015", AllRelationKinds, Options{})
 
 	require.NoError(t, err)
 	expected := &Result{
@@ -1519,6 +1832,8 @@ func TestInspectStructDefinedAndReferencedInTest(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule015/def_test.go"),
 					Line:   5,
 					Column: 6,
+					Offset: 46,
+					End:    file.Pos{Line: 5, Column: 18, Offset: 58},
 				},
 			},
 			{
@@ -1529,20 +1844,1370 @@ func TestInspectStructDefinedAndReferencedInTest(t *testing.T) {
 					Path:   absPath(t, "testdata/testmodule015/def_test.go"),
 					Line:   7,
 					Column: 13,
+					Offset: 81,
+					End:    file.Pos{Line: 7, Column: 25, Offset: 93},
 				},
+				Access: RefAccessType,
 			},
 		},
 	}
 	assert.Equal(t, expected, result)
 }
 
+func TestInspectFunctionCallees(t *testing.T) {
+	result, err := Inspect(file.Loc{
+		Path:   "testdata/testmodule016/main.go",
+		Line:   9,
+		Column: 7,
+	}, "testdata/testmodule016", []RelationKind{RelationKindCallee}, Options{})
+
+	require.NoError(t, err)
+	expected := &Result{
+		Name: "describe",
+		Type: "func(s github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule016/shapes.Shape) float64",
+		Relations: []Relation{
+			{
+				Kind: "callee",
+				Pkg:  "main",
+				Name: "offset()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule016/main.go"),
+					Line:   13,
+					Column: 6,
+					Offset: 218,
+					End:    file.Pos{Line: 13, Column: 12, Offset: 224},
+				},
+			},
+			{
+				Kind: "callee",
+				Pkg:  "shapes",
+				Name: "Shape.Area()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule016/shapes/shapes.go"),
+					Line:   4,
+					Column: 2,
+					Offset: 40,
+					End:    file.Pos{Line: 4, Column: 6, Offset: 44},
+				},
+			},
+			{
+				Kind: "callee",
+				Pkg:  "shapes",
+				Name: "Square.Area()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule016/shapes/shapes.go"),
+					Line:   15,
+					Column: 18,
+					Offset: 183,
+					End:    file.Pos{Line: 15, Column: 22, Offset: 187},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestInspectFunctionCalleesWithWrappedParams(t *testing.T) {
+	result, err := Inspect(file.Loc{
+		Path:   "testdata/testmodule025/main.go",
+		Line:   9,
+		Column: 6,
+	}, "testdata/testmodule025", []RelationKind{RelationKindCallee}, Options{})
+
+	require.NoError(t, err)
+	expected := &Result{
+		Name: "describe",
+		Type: "func(name string, sides int) string",
+		Relations: []Relation{
+			{
+				Kind: "callee",
+				Pkg:  "main",
+				Name: "repeat()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule025/main.go"),
+					Line:   16,
+					Column: 6,
+					Offset: 169,
+					End:    file.Pos{Line: 16, Column: 12, Offset: 175},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestInspectMethodCallersThroughInterface(t *testing.T) {
+	result, err := Inspect(file.Loc{
+		Path:   "testdata/testmodule016/shapes/shapes.go",
+		Line:   15,
+		Column: 19,
+	}, "testdata/testmodule016", []RelationKind{RelationKindCaller}, Options{})
+
+	require.NoError(t, err)
+	expected := &Result{
+		Name: "Area",
+		Type: "func() float64",
+		Relations: []Relation{
+			{
+				Kind: "caller",
+				Pkg:  "main",
+				Name: "describe()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule016/main.go"),
+					Line:   10,
+					Column: 11,
+					Offset: 192,
+					End:    file.Pos{Line: 10, Column: 15, Offset: 196},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestInspectStructEmbedsTransitively(t *testing.T) {
+	result, err := Inspect(file.Loc{
+		Path:   "testdata/testmodule017/animals.go",
+		Line:   14,
+		Column: 6,
+	}, "testdata/testmodule017", []RelationKind{RelationKindEmbeds}, Options{})
+
+	require.NoError(t, err)
+	expected := &Result{
+		Name: "Dog",
+		Type: "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017.Dog",
+		Relations: []Relation{
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Animal",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/animals.go"),
+					Line:   5,
+					Column: 6,
+					Offset: 97,
+					End:    file.Pos{Line: 5, Column: 12, Offset: 103},
+				},
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Animal.Entity",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/animals.go"),
+					Line:   6,
+					Column: 7,
+					Offset: 119,
+					End:    file.Pos{Line: 6, Column: 13, Offset: 125},
+				},
+				Via: "Animal",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Animal.Legs",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/animals.go"),
+					Line:   7,
+					Column: 2,
+					Offset: 127,
+					End:    file.Pos{Line: 7, Column: 6, Offset: 131},
+				},
+				Via: "Animal",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Animal.Describe()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/animals.go"),
+					Line:   10,
+					Column: 17,
+					Offset: 155,
+					End:    file.Pos{Line: 10, Column: 25, Offset: 163},
+				},
+				Via: "Animal",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "base",
+				Name: "Entity",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/base/base.go"),
+					Line:   3,
+					Column: 6,
+					Offset: 19,
+					End:    file.Pos{Line: 3, Column: 12, Offset: 25},
+				},
+				Via: "Animal",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "base",
+				Name: "Entity.ID",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/base/base.go"),
+					Line:   4,
+					Column: 2,
+					Offset: 36,
+					End:    file.Pos{Line: 4, Column: 4, Offset: 38},
+				},
+				Via: "Animal.Entity",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "base",
+				Name: "Entity.name",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/base/base.go"),
+					Line:   5,
+					Column: 2,
+					Offset: 46,
+					End:    file.Pos{Line: 5, Column: 6, Offset: 50},
+				},
+				Via: "Animal.Entity",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "base",
+				Name: "Entity.Name()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/base/base.go"),
+					Line:   8,
+					Column: 17,
+					Offset: 77,
+					End:    file.Pos{Line: 8, Column: 21, Offset: 81},
+				},
+				Via: "Animal.Entity",
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestInspectInterfaceEmbedsTransitively(t *testing.T) {
+	result, err := Inspect(file.Loc{
+		Path:   "testdata/testmodule017/ifaces.go",
+		Line:   12,
+		Column: 6,
+	}, "testdata/testmodule017", []RelationKind{RelationKindEmbeds}, Options{})
+
+	require.NoError(t, err)
+	expected := &Result{
+		Name: "Pet",
+		Type: "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017.Pet",
+		Relations: []Relation{
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Named",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/ifaces.go"),
+					Line:   3,
+					Column: 6,
+					Offset: 19,
+					End:    file.Pos{Line: 3, Column: 11, Offset: 24},
+				},
+				Via: "Describer",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Named.Name()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/ifaces.go"),
+					Line:   4,
+					Column: 2,
+					Offset: 38,
+					End:    file.Pos{Line: 4, Column: 6, Offset: 42},
+				},
+				Via: "Describer.Named",
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Describer",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/ifaces.go"),
+					Line:   7,
+					Column: 6,
+					Offset: 60,
+					End:    file.Pos{Line: 7, Column: 15, Offset: 69},
+				},
+			},
+			{
+				Kind: "embeds",
+				Pkg:  "main",
+				Name: "Describer.Describe()",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/ifaces.go"),
+					Line:   9,
+					Column: 2,
+					Offset: 90,
+					End:    file.Pos{Line: 9, Column: 10, Offset: 98},
+				},
+				Via: "Describer",
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestInspectStructEmbeddedByInOtherPkg(t *testing.T) {
+	result, err := Inspect(file.Loc{
+		Path:   "testdata/testmodule017/base/base.go",
+		Line:   3,
+		Column: 6,
+	}, "testdata/testmodule017", []RelationKind{RelationKindEmbeddedBy}, Options{})
+
+	require.NoError(t, err)
+	expected := &Result{
+		Name: "Entity",
+		Type: "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017/base.Entity",
+		Relations: []Relation{
+			{
+				Kind: "embedded-by",
+				Pkg:  "main",
+				Name: "Animal",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/animals.go"),
+					Line:   5,
+					Column: 6,
+					Offset: 97,
+					End:    file.Pos{Line: 5, Column: 12, Offset: 103},
+				},
+			},
+			{
+				Kind: "embedded-by",
+				Pkg:  "main",
+				Name: "Dog",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/animals.go"),
+					Line:   14,
+					Column: 6,
+					Offset: 200,
+					End:    file.Pos{Line: 14, Column: 9, Offset: 203},
+				},
+				Via: "Animal",
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestInspectInterfaceEmbeddedBy(t *testing.T) {
+	result, err := Inspect(file.Loc{
+		Path:   "testdata/testmodule017/ifaces.go",
+		Line:   3,
+		Column: 6,
+	}, "testdata/testmodule017", []RelationKind{RelationKindEmbeddedBy}, Options{})
+
+	require.NoError(t, err)
+	expected := &Result{
+		Name: "Named",
+		Type: "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017.Named",
+		Relations: []Relation{
+			{
+				Kind: "embedded-by",
+				Pkg:  "main",
+				Name: "Describer",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/ifaces.go"),
+					Line:   7,
+					Column: 6,
+					Offset: 60,
+					End:    file.Pos{Line: 7, Column: 15, Offset: 69},
+				},
+			},
+			{
+				Kind: "embedded-by",
+				Pkg:  "main",
+				Name: "Pet",
+				Loc: file.Loc{
+					Path:   absPath(t, "testdata/testmodule017/ifaces.go"),
+					Line:   12,
+					Column: 6,
+					Offset: 116,
+					End:    file.Pos{Line: 12, Column: 9, Offset: 119},
+				},
+				Via: "Describer",
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestInspectTypeDefinition(t *testing.T) {
+	configPath := absPath(t, "testdata/testmodule018/config/config.go")
+	configRelation := Relation{
+		Kind: RelationKindTypeDef,
+		Pkg:  "config",
+		Name: "Config",
+		Loc:  file.Loc{Path: configPath, Line: 3, Column: 6, Offset: 21, End: file.Pos{Line: 3, Column: 12, Offset: 27}},
+	}
+
+	testCases := []struct {
+		name              string
+		loc               file.Loc
+		expectedRelations []Relation
+	}{
+		{
+			name:              "pointer to named type",
+			loc:               file.Loc{Path: "testdata/testmodule018/main.go", Line: 13, Column: 10},
+			expectedRelations: []Relation{configRelation},
+		},
+		{
+			name:              "map of slice of pointers",
+			loc:               file.Loc{Path: "testdata/testmodule018/main.go", Line: 7, Column: 5},
+			expectedRelations: []Relation{configRelation},
+		},
+		{
+			name: "channel of generic instantiation",
+			loc:  file.Loc{Path: "testdata/testmodule018/main.go", Line: 11, Column: 2},
+			expectedRelations: []Relation{
+				configRelation,
+				{
+					Kind: RelationKindTypeDef,
+					Pkg:  "config",
+					Name: "List",
+					Loc:  file.Loc{Path: configPath, Line: 9, Column: 6, Offset: 74, End: file.Pos{Line: 9, Column: 10, Offset: 78}},
+				},
+			},
+		},
+		{
+			name: "const",
+			loc:  file.Loc{Path: "testdata/testmodule018/main.go", Line: 13, Column: 36},
+			expectedRelations: []Relation{
+				{
+					Kind: RelationKindTypeDef,
+					Pkg:  "config",
+					Name: "Level",
+					Loc:  file.Loc{Path: configPath, Line: 7, Column: 6, Offset: 58, End: file.Pos{Line: 7, Column: 11, Offset: 63}},
+				},
+			},
+		},
+		{
+			name: "type parameter",
+			loc:  file.Loc{Path: "testdata/testmodule018/main.go", Line: 16, Column: 19},
+			expectedRelations: []Relation{
+				{
+					Kind: RelationKindTypeDef,
+					Pkg:  "main",
+					Name: "T",
+					Loc:  file.Loc{Path: absPath(t, "testdata/testmodule018/main.go"), Line: 16, Column: 12, Offset: 395, End: file.Pos{Line: 16, Column: 13, Offset: 396}},
+				},
+			},
+		},
+		{
+			name:              "builtin type",
+			loc:               file.Loc{Path: "testdata/testmodule018/main.go", Line: 12, Column: 2},
+			expectedRelations: nil,
+		},
+		{
+			name:              "func",
+			loc:               file.Loc{Path: "testdata/testmodule018/main.go", Line: 16, Column: 6},
+			expectedRelations: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Inspect(tc.loc, "testdata/testmodule018", []RelationKind{RelationKindTypeDef}, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRelations, result.Relations)
+		})
+	}
+}
+
+func TestInspectGenericInstantiations(t *testing.T) {
+	mainPath := absPath(t, "testdata/testmodule018/main.go")
+	otherPath := absPath(t, "testdata/testmodule018/other.go")
+
+	testCases := []struct {
+		name           string
+		loc            file.Loc
+		relKinds       []RelationKind
+		expectedResult *Result
+	}{
+		{
+			name:     "generic func",
+			loc:      file.Loc{Path: "testdata/testmodule018/main.go", Line: 16, Column: 6},
+			relKinds: []RelationKind{RelationKindInstantiation},
+			expectedResult: &Result{
+				Name: "first",
+				Type: "func[T any](items []T) T",
+				Relations: []Relation{
+					{
+						Kind: RelationKindInstantiation,
+						Pkg:  "main",
+						Name: "first[config.Level] in main() body",
+						Loc:  file.Loc{Path: mainPath, Line: 13, Column: 50, Offset: 344, End: file.Pos{Line: 13, Column: 55, Offset: 349}},
+					},
+					{
+						Kind: RelationKindInstantiation,
+						Pkg:  "main",
+						Name: "first[string] in declaration of names",
+						Loc:  file.Loc{Path: otherPath, Line: 5, Column: 13, Offset: 106, End: file.Pos{Line: 5, Column: 18, Offset: 111}},
+					},
+				},
+				TypeParams: []TypeParam{{Name: "T", Constraint: "any"}},
+			},
+		},
+		{
+			name:     "generic type in other package",
+			loc:      file.Loc{Path: "testdata/testmodule018/config/config.go", Line: 9, Column: 6},
+			relKinds: []RelationKind{RelationKindInstantiation},
+			expectedResult: &Result{
+				Name: "List",
+				Type: "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule018/config.List[T any]",
+				Relations: []Relation{
+					{
+						Kind: RelationKindInstantiation,
+						Pkg:  "main",
+						Name: "List[*config.Config] in main() body",
+						Loc:  file.Loc{Path: mainPath, Line: 11, Column: 30, Offset: 251, End: file.Pos{Line: 11, Column: 34, Offset: 255}},
+					},
+					{
+						Kind: RelationKindInstantiation,
+						Pkg:  "main",
+						Name: "List[config.Level] in declaration of levels",
+						Loc:  file.Loc{Path: otherPath, Line: 7, Column: 19, Offset: 151, End: file.Pos{Line: 7, Column: 23, Offset: 155}},
+					},
+				},
+				TypeParams: []TypeParam{{Name: "T", Constraint: "any"}},
+			},
+		},
+		{
+			name:     "instance at call",
+			loc:      file.Loc{Path: "testdata/testmodule018/main.go", Line: 13, Column: 50},
+			relKinds: []RelationKind{RelationKindDef},
+			expectedResult: &Result{
+				Name: "first",
+				Type: "func[T any](items []T) T",
+				Relations: []Relation{
+					{
+						Kind: RelationKindDef,
+						Pkg:  "main",
+						Name: "first",
+						Loc:  file.Loc{Path: mainPath, Line: 16, Column: 6, Offset: 389, End: file.Pos{Line: 16, Column: 11, Offset: 394}},
+					},
+				},
+				TypeParams: []TypeParam{{Name: "T", Constraint: "any"}},
+				Instance: &Instance{
+					TypeArgs: []string{"github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule018/config.Level"},
+					Type:     "func(items []github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule018/config.Level) github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule018/config.Level",
+				},
+			},
+		},
+		{
+			name:     "non-generic func",
+			loc:      file.Loc{Path: "testdata/testmodule018/main.go", Line: 9, Column: 6},
+			relKinds: []RelationKind{RelationKindInstantiation},
+			expectedResult: &Result{
+				Name: "main",
+				Type: "func()",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Inspect(tc.loc, "testdata/testmodule018", tc.relKinds, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResult, result)
+		})
+	}
+}
+
+func TestInspectReferenceAccess(t *testing.T) {
+	mainPath := absPath(t, "testdata/testmodule019/main.go")
+	refRelation := func(name string, loc file.Loc, access RefAccess) Relation {
+		return Relation{
+			Kind:   RelationKindRef,
+			Pkg:    "main",
+			Name:   name,
+			Loc:    loc,
+			Access: access,
+		}
+	}
+
+	testCases := []struct {
+		name              string
+		loc               file.Loc
+		refAccesses       []RefAccess
+		expectedRelations []Relation
+	}{
+		{
+			name: "field with all accesses",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 6, Column: 2},
+			expectedRelations: []Relation{
+				refRelation("Total in Counter.Add() body", file.Loc{Path: mainPath, Line: 10, Column: 4, Offset: 98, End: file.Pos{Line: 10, Column: 9, Offset: 103}}, RefAccessWrite),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 14, Column: 15, Offset: 140, End: file.Pos{Line: 14, Column: 20, Offset: 145}}, RefAccessFieldKey),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 15, Column: 4, Offset: 153, End: file.Pos{Line: 15, Column: 9, Offset: 158}}, RefAccessWrite),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 16, Column: 10, Offset: 170, End: file.Pos{Line: 16, Column: 15, Offset: 175}}, RefAccessAddress),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 18, Column: 11, Offset: 197, End: file.Pos{Line: 18, Column: 16, Offset: 202}}, RefAccessWrite),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 20, Column: 16, Offset: 243, End: file.Pos{Line: 20, Column: 21, Offset: 248}}, RefAccessRead),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 20, Column: 26, Offset: 253, End: file.Pos{Line: 20, Column: 31, Offset: 258}}, RefAccessRead),
+			},
+		},
+		{
+			name:        "field writes",
+			loc:         file.Loc{Path: "testdata/testmodule019/main.go", Line: 6, Column: 2},
+			refAccesses: []RefAccess{RefAccessWrite},
+			expectedRelations: []Relation{
+				refRelation("Total in Counter.Add() body", file.Loc{Path: mainPath, Line: 10, Column: 4, Offset: 98, End: file.Pos{Line: 10, Column: 9, Offset: 103}}, RefAccessWrite),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 15, Column: 4, Offset: 153, End: file.Pos{Line: 15, Column: 9, Offset: 158}}, RefAccessWrite),
+				refRelation("Total in main() body", file.Loc{Path: mainPath, Line: 18, Column: 11, Offset: 197, End: file.Pos{Line: 18, Column: 16, Offset: 202}}, RefAccessWrite),
+			},
+		},
+		{
+			name:        "method calls",
+			loc:         file.Loc{Path: "testdata/testmodule019/main.go", Line: 9, Column: 19},
+			refAccesses: []RefAccess{RefAccessCall},
+			expectedRelations: []Relation{
+				refRelation("Add in main() body", file.Loc{Path: mainPath, Line: 17, Column: 4, Offset: 179, End: file.Pos{Line: 17, Column: 7, Offset: 182}}, RefAccessCall),
+			},
+		},
+		{
+			name:        "type references",
+			loc:         file.Loc{Path: "testdata/testmodule019/main.go", Line: 5, Column: 6},
+			refAccesses: []RefAccess{RefAccessType, RefAccessRead},
+			expectedRelations: []Relation{
+				refRelation("receiver in Counter.Add()", file.Loc{Path: mainPath, Line: 9, Column: 10, Offset: 73, End: file.Pos{Line: 9, Column: 17, Offset: 80}}, RefAccessType),
+				refRelation("Counter in main() body", file.Loc{Path: mainPath, Line: 14, Column: 7, Offset: 132, End: file.Pos{Line: 14, Column: 14, Offset: 139}}, RefAccessType),
+			},
+		},
+		{
+			name: "writes through index",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 31, Column: 13},
+			expectedRelations: []Relation{
+				refRelation("shapes in update() body", file.Loc{Path: mainPath, Line: 32, Column: 2, Offset: 415, End: file.Pos{Line: 32, Column: 8, Offset: 421}}, RefAccessWrite),
+				refRelation("shapes in update() body", file.Loc{Path: mainPath, Line: 36, Column: 2, Offset: 475, End: file.Pos{Line: 36, Column: 8, Offset: 481}}, RefAccessWrite),
+			},
+		},
+		{
+			name: "map index increment",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 31, Column: 29},
+			expectedRelations: []Relation{
+				refRelation("counts in update() body", file.Loc{Path: mainPath, Line: 33, Column: 2, Offset: 436, End: file.Pos{Line: 33, Column: 8, Offset: 442}}, RefAccessWrite),
+				refRelation("counts in update() body", file.Loc{Path: mainPath, Line: 36, Column: 24, Offset: 497, End: file.Pos{Line: 36, Column: 30, Offset: 503}}, RefAccessRead),
+			},
+		},
+		{
+			name: "index is read",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 31, Column: 70},
+			expectedRelations: []Relation{
+				refRelation("key in update() body", file.Loc{Path: mainPath, Line: 33, Column: 9, Offset: 443, End: file.Pos{Line: 33, Column: 12, Offset: 446}}, RefAccessRead),
+				refRelation("key in update() body", file.Loc{Path: mainPath, Line: 36, Column: 31, Offset: 504, End: file.Pos{Line: 36, Column: 34, Offset: 507}}, RefAccessRead),
+			},
+		},
+		{
+			name: "writes through field selectors",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 31, Column: 52},
+			expectedRelations: []Relation{
+				refRelation("s in update() body", file.Loc{Path: mainPath, Line: 34, Column: 2, Offset: 451, End: file.Pos{Line: 34, Column: 3, Offset: 452}}, RefAccessWrite),
+				refRelation("s in update() body", file.Loc{Path: mainPath, Line: 37, Column: 14, Offset: 522, End: file.Pos{Line: 37, Column: 15, Offset: 523}}, RefAccessRead),
+			},
+		},
+		{
+			name: "selected fields are written",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 28, Column: 2},
+			expectedRelations: []Relation{
+				refRelation("Origin in update() body", file.Loc{Path: mainPath, Line: 34, Column: 4, Offset: 453, End: file.Pos{Line: 34, Column: 10, Offset: 459}}, RefAccessWrite),
+				refRelation("Origin in update() body", file.Loc{Path: mainPath, Line: 36, Column: 12, Offset: 485, End: file.Pos{Line: 36, Column: 18, Offset: 491}}, RefAccessWrite),
+				refRelation("Origin in update() body", file.Loc{Path: mainPath, Line: 37, Column: 16, Offset: 524, End: file.Pos{Line: 37, Column: 22, Offset: 530}}, RefAccessRead),
+			},
+		},
+		{
+			name: "last selected field is written",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 24, Column: 2},
+			expectedRelations: []Relation{
+				refRelation("X in update() body", file.Loc{Path: mainPath, Line: 34, Column: 11, Offset: 460, End: file.Pos{Line: 34, Column: 12, Offset: 461}}, RefAccessWrite),
+				refRelation("X in update() body", file.Loc{Path: mainPath, Line: 36, Column: 19, Offset: 492, End: file.Pos{Line: 36, Column: 20, Offset: 493}}, RefAccessWrite),
+				refRelation("X in update() body", file.Loc{Path: mainPath, Line: 37, Column: 23, Offset: 531, End: file.Pos{Line: 37, Column: 24, Offset: 532}}, RefAccessRead),
+			},
+		},
+		{
+			name: "writes through pointer",
+			loc:  file.Loc{Path: "testdata/testmodule019/main.go", Line: 31, Column: 62},
+			expectedRelations: []Relation{
+				refRelation("p in update() body", file.Loc{Path: mainPath, Line: 35, Column: 3, Offset: 468, End: file.Pos{Line: 35, Column: 4, Offset: 469}}, RefAccessWrite),
+				refRelation("p in update() body", file.Loc{Path: mainPath, Line: 37, Column: 27, Offset: 535, End: file.Pos{Line: 37, Column: 28, Offset: 536}}, RefAccessRead),
+			},
+		},
+		{
+			name:              "no matching accesses",
+			loc:               file.Loc{Path: "testdata/testmodule019/main.go", Line: 9, Column: 19},
+			refAccesses:       []RefAccess{RefAccessWrite},
+			expectedRelations: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{RefAccesses: tc.refAccesses}
+			result, err := Inspect(tc.loc, "testdata/testmodule019", []RelationKind{RelationKindRef}, opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRelations, result.Relations)
+		})
+	}
+}
+
+func TestInspectSymbol(t *testing.T) {
+	testCases := []struct {
+		name           string
+		symbol         string
+		expectedName   string
+		expectedDefLoc file.Loc
+	}{
+		{
+			name:           "type by import path",
+			symbol:         "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017/base.Entity",
+			expectedName:   "Entity",
+			expectedDefLoc: file.Loc{Path: absPath(t, "testdata/testmodule017/base/base.go"), Line: 3, Column: 6, Offset: 19, End: file.Pos{Line: 3, Column: 12, Offset: 25}},
+		},
+		{
+			name:           "type by last path elements",
+			symbol:         "legacy/base.Entity",
+			expectedName:   "Entity",
+			expectedDefLoc: file.Loc{Path: absPath(t, "testdata/testmodule017/legacy/base/base.go"), Line: 3, Column: 6, Offset: 19, End: file.Pos{Line: 3, Column: 12, Offset: 25}},
+		},
+		{
+			name:           "method by package name",
+			symbol:         "main.Animal.Describe",
+			expectedName:   "Describe",
+			expectedDefLoc: file.Loc{Path: absPath(t, "testdata/testmodule017/animals.go"), Line: 10, Column: 17, Offset: 155, End: file.Pos{Line: 10, Column: 25, Offset: 163}},
+		},
+		{
+			name:           "promoted field",
+			symbol:         "main.Dog.Legs",
+			expectedName:   "Legs",
+			expectedDefLoc: file.Loc{Path: absPath(t, "testdata/testmodule017/animals.go"), Line: 7, Column: 2, Offset: 127, End: file.Pos{Line: 7, Column: 6, Offset: 131}},
+		},
+		{
+			name:           "method promoted from other package",
+			symbol:         "main.Dog.Name",
+			expectedName:   "Name",
+			expectedDefLoc: file.Loc{Path: absPath(t, "testdata/testmodule017/base/base.go"), Line: 8, Column: 17, Offset: 77, End: file.Pos{Line: 8, Column: 21, Offset: 81}},
+		},
+		{
+			name:           "interface method",
+			symbol:         "main.Pet.Describe",
+			expectedName:   "Describe",
+			expectedDefLoc: file.Loc{Path: absPath(t, "testdata/testmodule017/ifaces.go"), Line: 9, Column: 2, Offset: 90, End: file.Pos{Line: 9, Column: 10, Offset: 98}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := InspectSymbol(tc.symbol, "testdata/testmodule017", []RelationKind{RelationKindDef}, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedName, result.Name)
+			require.Len(t, result.Relations, 1)
+			assert.Equal(t, tc.expectedDefLoc, result.Relations[0].Loc)
+		})
+	}
+}
+
+func TestInspectSymbolStdlib(t *testing.T) {
+	result, err := InspectSymbol("fmt.Println", "testdata/testmodule017", []RelationKind{RelationKindDef}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "Println", result.Name)
+	require.Len(t, result.Relations, 1)
+	assert.Equal(t, "fmt", result.Relations[0].Pkg)
+}
+
+func TestInspectSymbolErrors(t *testing.T) {
+	testCases := []struct {
+		symbol      string
+		expectedErr string
+	}{
+		{
+			symbol:      "Entity",
+			expectedErr: `Invalid symbol "Entity", expected the form pkg.Name, pkg.Type.Field, or pkg.Type.Method`,
+		},
+		{
+			symbol:      "base.Entity",
+			expectedErr: `Ambiguous package "base" matches github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017/base, github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017/legacy/base`,
+		},
+		{
+			symbol:      "main.Missing",
+			expectedErr: `Could not find "Missing" in package github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017`,
+		},
+		{
+			symbol:      "main.Dog.Missing",
+			expectedErr: `Could not find field or method "Missing" of type github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017.Dog`,
+		},
+		{
+			symbol:      "missingpkg.Foo",
+			expectedErr: `Could not find package for symbol "missingpkg.Foo"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.symbol, func(t *testing.T) {
+			_, err := InspectSymbol(tc.symbol, "testdata/testmodule017", []RelationKind{RelationKindDef}, Options{})
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestInspectBatch(t *testing.T) {
+	locs := []file.Loc{
+		{Path: "testdata/testmodule017/animals.go", Line: 7, Column: 2},
+		{Path: "testdata/testmodule017/base/base.go", Line: 3, Column: 6},
+		{Path: "testdata/testmodule017/ifaces.go", Line: 9, Column: 2},
+		{Path: "testdata/testmodule017/animals.go", Line: 100, Column: 1},
+		{Path: "testdata/testmodule017/animals.go", Line: 5, Column: 6},
+	}
+	searchDir := "testdata/testmodule017"
+	relKinds := []RelationKind{RelationKindDef, RelationKindRef, RelationKindEmbeds, RelationKindEmbeddedBy}
+
+	results := InspectBatch(locs, searchDir, relKinds, Options{})
+	require.Len(t, results, len(locs))
+
+	// Each result should match inspecting the location on its own.
+	for i, loc := range locs {
+		assert.Equal(t, loc, results[i].Loc)
+
+		expected, err := Inspect(loc, searchDir, relKinds, Options{})
+		if err != nil {
+			assert.Equal(t, err.Error(), results[i].Error)
+			assert.Nil(t, results[i].Result)
+		} else {
+			assert.Empty(t, results[i].Error)
+			assert.Equal(t, expected, results[i].Result)
+		}
+	}
+}
+
+func TestInspectReferencesWithIndex(t *testing.T) {
+	loc := file.Loc{
+		Path:   "testdata/testmodule011/subpkg/def.go",
+		Line:   15,
+		Column: 20,
+	}
+	relKinds := []RelationKind{RelationKindRef}
+
+	expected, err := Inspect(loc, "testdata/testmodule011", relKinds, Options{})
+	require.NoError(t, err)
+	require.NotEmpty(t, expected.Relations)
+
+	idx, err := index.Open(t.TempDir())
+	require.NoError(t, err)
+
+	// The first call populates the index, and the second reads from it.
+	for i := 0; i < 2; i++ {
+		result, err := Inspect(loc, "testdata/testmodule011", relKinds, Options{Index: idx})
+		require.NoError(t, err)
+		assert.Equal(t, expected, result)
+	}
+}
+
+func TestInspectRelationRanges(t *testing.T) {
+	path := "testdata/testmodule020/main.go"
+	testCases := []struct {
+		name     string
+		loc      file.Loc
+		relKinds []RelationKind
+		expected []Relation
+	}{
+		{
+			name:     "definition of non-ASCII identifier",
+			loc:      file.Loc{Path: path, Line: 7, Column: 18},
+			relKinds: []RelationKind{RelationKindDef},
+			expected: []Relation{
+				{
+					Loc: file.Loc{
+						Path:   absPath(t, path),
+						Line:   6,
+						Column: 6,
+						Offset: 47,
+						End:    file.Pos{Line: 6, Column: 10, Offset: 51},
+					},
+					Kind: RelationKindDef,
+					Pkg:  "main",
+					Name: "𝜋",
+				},
+			},
+		},
+		{
+			name:     "reference to non-ASCII identifier",
+			loc:      file.Loc{Path: path, Line: 6, Column: 6},
+			relKinds: []RelationKind{RelationKindRef},
+			expected: []Relation{
+				{
+					Loc: file.Loc{
+						Path:   absPath(t, path),
+						Line:   7,
+						Column: 18,
+						Offset: 86,
+						End:    file.Pos{Line: 7, Column: 22, Offset: 90},
+					},
+					Kind:   RelationKindRef,
+					Pkg:    "main",
+					Name:   "𝜋 in main() body",
+					Access: RefAccessRead,
+				},
+			},
+		},
+		{
+			name:     "definition of import spans the import path",
+			loc:      file.Loc{Path: path, Line: 7, Column: 2},
+			relKinds: []RelationKind{RelationKindDef},
+			expected: []Relation{
+				{
+					Loc: file.Loc{
+						Path:   absPath(t, path),
+						Line:   3,
+						Column: 8,
+						Offset: 21,
+						End:    file.Pos{Line: 3, Column: 13, Offset: 26},
+					},
+					Kind: RelationKindDef,
+					Pkg:  "main",
+					Name: "fmt",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Inspect(tc.loc, "testdata/testmodule020", tc.relKinds, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Relations)
+		})
+	}
+}
+
+func TestInspectWithOverlay(t *testing.T) {
+	path := "testdata/testmodule020/main.go"
+	overlay := file.Overlay{
+		absPath(t, path): []byte(`package main
+
+import "fmt"
+
+// Added in the editor.
+func main() {
+	π, 𝜋 := 3.14, 3.14159
+	τ := 2 * π
+	fmt.Println(π, 𝜋, τ)
+}
+`),
+	}
+
+	loc := file.Loc{Path: path, Line: 8, Column: 2}
+	relKinds := []RelationKind{RelationKindDef, RelationKindRef}
+	result, err := Inspect(loc, "testdata/testmodule020", relKinds, Options{Overlay: overlay})
+	require.NoError(t, err)
+
+	assert.Equal(t, "τ", result.Name)
+	assert.Equal(t, []Relation{
+		{
+			Loc:  file.Loc{Path: absPath(t, path), Line: 8, Column: 2, Offset: 94, End: file.Pos{Line: 8, Column: 4, Offset: 96}},
+			Kind: RelationKindDef,
+			Pkg:  "main",
+			Name: "τ",
+		},
+		{
+			Loc:    file.Loc{Path: absPath(t, path), Line: 9, Column: 24, Offset: 130, End: file.Pos{Line: 9, Column: 26, Offset: 132}},
+			Kind:   RelationKindRef,
+			Pkg:    "main",
+			Name:   "τ in main() body",
+			Access: RefAccessRead,
+		},
+	}, result.Relations)
+}
+
+func TestInspectBuildConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		loc      file.Loc
+		relKinds []RelationKind
+		build    buildcfg.Config
+		expected []Relation
+	}{
+		{
+			name:     "file excluded by default platform",
+			loc:      file.Loc{Path: "testdata/testmodule021/platform_windows.go", Line: 3, Column: 6},
+			relKinds: []RelationKind{RelationKindDef, RelationKindRef},
+			expected: []Relation{
+				{
+					Loc:  file.Loc{Path: absPath(t, "testdata/testmodule021/platform_windows.go"), Line: 3, Column: 6, Offset: 19, End: file.Pos{Line: 3, Column: 18, Offset: 31}},
+					Kind: RelationKindDef,
+					Pkg:  "main",
+					Name: "platformName",
+				},
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule021/main.go"), Line: 6, Column: 14, Offset: 55, End: file.Pos{Line: 6, Column: 26, Offset: 67}},
+					Kind:   RelationKindRef,
+					Pkg:    "main",
+					Name:   "platformName in main() body",
+					Access: RefAccessCall,
+				},
+			},
+		},
+		{
+			name:     "file excluded by build tag",
+			loc:      file.Loc{Path: "testdata/testmodule021/integration.go", Line: 6, Column: 9},
+			relKinds: []RelationKind{RelationKindDef},
+			build:    buildcfg.Config{GOOS: "linux"},
+			expected: []Relation{
+				{
+					Loc:  file.Loc{Path: absPath(t, "testdata/testmodule021/platform_linux.go"), Line: 3, Column: 6, Offset: 19, End: file.Pos{Line: 3, Column: 18, Offset: 31}},
+					Kind: RelationKindDef,
+					Pkg:  "main",
+					Name: "platformName",
+				},
+			},
+		},
+		{
+			name:     "goos matching file name",
+			loc:      file.Loc{Path: "testdata/testmodule021/main.go", Line: 6, Column: 14},
+			relKinds: []RelationKind{RelationKindDef},
+			build:    buildcfg.Config{GOOS: "windows"},
+			expected: []Relation{
+				{
+					Loc:  file.Loc{Path: absPath(t, "testdata/testmodule021/platform_windows.go"), Line: 3, Column: 6, Offset: 19, End: file.Pos{Line: 3, Column: 18, Offset: 31}},
+					Kind: RelationKindDef,
+					Pkg:  "main",
+					Name: "platformName",
+				},
+			},
+		},
+		{
+			name:     "goos matching build constraint",
+			loc:      file.Loc{Path: "testdata/testmodule021/main.go", Line: 6, Column: 14},
+			relKinds: []RelationKind{RelationKindDef},
+			build:    buildcfg.Config{GOOS: "darwin", GOARCH: "arm64"},
+			expected: []Relation{
+				{
+					Loc:  file.Loc{Path: absPath(t, "testdata/testmodule021/platform_other.go"), Line: 5, Column: 6, Offset: 50, End: file.Pos{Line: 5, Column: 18, Offset: 62}},
+					Kind: RelationKindDef,
+					Pkg:  "main",
+					Name: "platformName",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Inspect(tc.loc, "testdata/testmodule021", tc.relKinds, Options{Build: tc.build})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Relations)
+		})
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	loc := file.Loc{Path: "testdata/testmodule021/main.go", Line: 6, Column: 14}
+	relKinds := []RelationKind{RelationKindDef}
+
+	var results []*Result
+	for _, goos := range []string{"windows", "linux", "windows"} {
+		result, err := Inspect(loc, "testdata/testmodule021", relKinds, Options{Build: buildcfg.Config{GOOS: goos}})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+	results = append(results, nil)
+
+	merged := MergeResults(results)
+	require.NotNil(t, merged)
+	assert.Equal(t, "platformName", merged.Name)
+	assert.Equal(t, []Relation{
+		{
+			Loc:  file.Loc{Path: absPath(t, "testdata/testmodule021/platform_linux.go"), Line: 3, Column: 6, Offset: 19, End: file.Pos{Line: 3, Column: 18, Offset: 31}},
+			Kind: RelationKindDef,
+			Pkg:  "main",
+			Name: "platformName",
+		},
+		{
+			Loc:  file.Loc{Path: absPath(t, "testdata/testmodule021/platform_windows.go"), Line: 3, Column: 6, Offset: 19, End: file.Pos{Line: 3, Column: 18, Offset: 31}},
+			Kind: RelationKindDef,
+			Pkg:  "main",
+			Name: "platformName",
+		},
+	}, merged.Relations)
+
+	assert.Same(t, results[0], MergeResults(results[:1]))
+	assert.Nil(t, MergeResults([]*Result{nil}))
+}
+
+func TestInspectWorkspace(t *testing.T) {
+	// The sandbox may set -mod, which the go command rejects in workspace mode.
+	t.Setenv("GOFLAGS", "")
+
+	testCases := []struct {
+		name     string
+		loc      file.Loc
+		relKinds []RelationKind
+		expected []Relation
+	}{
+		{
+			name:     "reference from other workspace module",
+			loc:      file.Loc{Path: "testdata/testmodule022/a/greet/greet.go", Line: 7, Column: 6},
+			relKinds: []RelationKind{RelationKindRef},
+			expected: []Relation{
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule022/b/main.go"), Line: 12, Column: 15, Offset: 177, End: file.Pos{Line: 12, Column: 20, Offset: 182}},
+					Kind:   RelationKindRef,
+					Pkg:    "main",
+					Name:   "Hello in english.Greet() body",
+					Access: RefAccessCall,
+				},
+			},
+		},
+		{
+			name:     "implementation in other workspace module",
+			loc:      file.Loc{Path: "testdata/testmodule022/a/greet/greet.go", Line: 3, Column: 6},
+			relKinds: []RelationKind{RelationKindImpl},
+			expected: []Relation{
+				{
+					Loc:  file.Loc{Path: absPath(t, "testdata/testmodule022/b/main.go"), Line: 9, Column: 6, Offset: 113, End: file.Pos{Line: 9, Column: 13, Offset: 120}},
+					Kind: RelationKindImpl,
+					Pkg:  "main",
+					Name: "english",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Inspect(tc.loc, "testdata/testmodule022", tc.relKinds, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Relations)
+		})
+	}
+}
+
+func TestInspectSearchDeps(t *testing.T) {
+	// The sandbox may set -mod, which overrides the vendor directory.
+	t.Setenv("GOFLAGS", "")
+
+	shapesPath := absPath(t, "testdata/testmodule023/vendor/example.com/shapes/shapes.go")
+	testCases := []struct {
+		name       string
+		loc        file.Loc
+		relKinds   []RelationKind
+		searchDeps bool
+		expected   []Relation
+	}{
+		{
+			name:     "interface declared in dependency without searching dependencies",
+			loc:      file.Loc{Path: "testdata/testmodule023/main.go", Line: 9, Column: 6},
+			relKinds: []RelationKind{RelationKindIface},
+			expected: nil,
+		},
+		{
+			name:       "interface declared in dependency",
+			loc:        file.Loc{Path: "testdata/testmodule023/main.go", Line: 9, Column: 6},
+			relKinds:   []RelationKind{RelationKindIface},
+			searchDeps: true,
+			expected: []Relation{
+				{
+					Loc:     file.Loc{Path: shapesPath, Line: 3, Column: 6, Offset: 21, End: file.Pos{Line: 3, Column: 11, Offset: 26}},
+					Kind:    RelationKindIface,
+					Pkg:     "shapes",
+					Name:    "Shape",
+					Module:  "example.com/shapes",
+					Version: "v1.2.0",
+				},
+			},
+		},
+		{
+			name:       "interface method declared in dependency",
+			loc:        file.Loc{Path: "testdata/testmodule023/main.go", Line: 13, Column: 17},
+			relKinds:   []RelationKind{RelationKindIface},
+			searchDeps: true,
+			expected: []Relation{
+				{
+					Loc:     file.Loc{Path: shapesPath, Line: 4, Column: 2, Offset: 40, End: file.Pos{Line: 4, Column: 6, Offset: 44}},
+					Kind:    RelationKindIface,
+					Pkg:     "shapes",
+					Name:    "Shape.Area()",
+					Module:  "example.com/shapes",
+					Version: "v1.2.0",
+				},
+			},
+		},
+		{
+			name:     "references in dependency without searching dependencies",
+			loc:      file.Loc{Path: "testdata/testmodule023/vendor/example.com/shapes/shapes.go", Line: 3, Column: 6},
+			relKinds: []RelationKind{RelationKindRef},
+			expected: nil,
+		},
+		{
+			name:       "references in dependency",
+			loc:        file.Loc{Path: "testdata/testmodule023/vendor/example.com/shapes/shapes.go", Line: 3, Column: 6},
+			relKinds:   []RelationKind{RelationKindRef},
+			searchDeps: true,
+			expected: []Relation{
+				{
+					Loc:     file.Loc{Path: shapesPath, Line: 7, Column: 22, Offset: 79, End: file.Pos{Line: 7, Column: 27, Offset: 84}},
+					Kind:    RelationKindRef,
+					Pkg:     "shapes",
+					Name:    "Shape in Total() params",
+					Access:  RefAccessType,
+					Module:  "example.com/shapes",
+					Version: "v1.2.0",
+				},
+			},
+		},
+		{
+			name:       "definition in search directory isn't labeled",
+			loc:        file.Loc{Path: "testdata/testmodule023/main.go", Line: 18, Column: 27},
+			relKinds:   []RelationKind{RelationKindDef},
+			searchDeps: true,
+			expected: []Relation{
+				{
+					Loc:  file.Loc{Path: absPath(t, "testdata/testmodule023/main.go"), Line: 9, Column: 6, Offset: 61, End: file.Pos{Line: 9, Column: 12, Offset: 67}},
+					Kind: RelationKindDef,
+					Pkg:  "main",
+					Name: "Square",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := Options{SearchDeps: tc.searchDeps}
+			result, err := Inspect(tc.loc, "testdata/testmodule023", tc.relKinds, opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Relations)
+		})
+	}
+}
+
+func TestInspectUniverse(t *testing.T) {
+	loc := file.Loc{Path: "testdata/testmodule024/buffer.go", Line: 3, Column: 6}
+	opts := Options{Universe: []string{"std", "./iface"}}
+	result, err := Inspect(loc, "testdata/testmodule024", []RelationKind{RelationKindIface}, opts)
+	require.NoError(t, err)
+
+	var names []string
+	for _, rel := range result.Relations {
+		assert.Equal(t, RelationKindIface, rel.Kind)
+		names = append(names, rel.Pkg+"."+rel.Name)
+	}
+	assert.Contains(t, names, "io.Reader")
+	assert.Contains(t, names, "io.Closer")
+	assert.Contains(t, names, "io.ReadCloser")
+	assert.NotContains(t, names, "fmt.Stringer")
+
+	nearMisses := make(map[string][]string)
+	for _, nm := range result.NearMisses {
+		nearMisses[nm.Pkg+"."+nm.Name] = nm.Missing
+	}
+	assert.Equal(t, []string{"String() string (have String() int)"}, nearMisses["fmt.Stringer"])
+	assert.Equal(t, []string{"Write(p []byte) (n int, err error)"}, nearMisses["io.ReadWriteCloser"])
+	assert.Equal(t, []string{"Reset()"}, nearMisses["iface.Resetter"])
+	assert.NotContains(t, nearMisses, "io.Writer")
+	assert.NotContains(t, nearMisses, "iface.Sizer")
+	assert.NotContains(t, nearMisses, "io.Reader")
+
+	// A method relates to the methods of the interfaces in the universe that its type implements.
+	methodLoc := file.Loc{Path: "testdata/testmodule024/buffer.go", Line: 13, Column: 18}
+	result, err = Inspect(methodLoc, "testdata/testmodule024", []RelationKind{RelationKindIface}, opts)
+	require.NoError(t, err)
+	names = names[:0]
+	for _, rel := range result.Relations {
+		names = append(names, rel.Pkg+"."+rel.Name)
+	}
+	assert.Contains(t, names, "io.Closer.Close()")
+	assert.Contains(t, names, "io.ReadCloser.Close()")
+	assert.NotContains(t, names, "iface.Resetter.Close()")
+	assert.Empty(t, result.NearMisses)
+}
+
+func TestInspectUniverseDependency(t *testing.T) {
+	// The sandbox may set -mod, which overrides the vendor directory.
+	t.Setenv("GOFLAGS", "")
+
+	loc := file.Loc{Path: "testdata/testmodule023/main.go", Line: 9, Column: 6}
+	opts := Options{Universe: []string{"example.com/shapes"}}
+	result, err := Inspect(loc, "testdata/testmodule023", []RelationKind{RelationKindIface}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []Relation{
+		{
+			Loc:     file.Loc{Path: absPath(t, "testdata/testmodule023/vendor/example.com/shapes/shapes.go"), Line: 3, Column: 6, Offset: 21, End: file.Pos{Line: 3, Column: 11, Offset: 26}},
+			Kind:    RelationKindIface,
+			Pkg:     "shapes",
+			Name:    "Shape",
+			Module:  "example.com/shapes",
+			Version: "v1.2.0",
+		},
+	}, result.Relations)
+}
+
+func TestFindLoadRootsInSearchDir(t *testing.T) {
+	workspaceDir := absPath(t, "testdata/testmodule022")
+	moduleDir := absPath(t, "testdata/testmodule001")
+
+	testCases := []struct {
+		name      string
+		searchDir string
+		env       []string
+		expected  []loadRoot
+	}{
+		{
+			name:      "module",
+			searchDir: moduleDir,
+			expected:  []loadRoot{{Dir: moduleDir, Patterns: []string{"./..."}, PkgDirs: []string{moduleDir}}},
+		},
+		{
+			name:      "workspace",
+			searchDir: workspaceDir,
+			expected: []loadRoot{
+				{
+					Dir:      workspaceDir,
+					Patterns: []string{"./a/...", "./b/..."},
+					PkgDirs:  []string{filepath.Join(workspaceDir, "a"), filepath.Join(workspaceDir, "b")},
+					WorkFile: filepath.Join(workspaceDir, "go.work"),
+				},
+			},
+		},
+		{
+			name:      "module in workspace",
+			searchDir: filepath.Join(workspaceDir, "b"),
+			expected: []loadRoot{
+				{
+					Dir:      workspaceDir,
+					Patterns: []string{"./b/..."},
+					PkgDirs:  []string{filepath.Join(workspaceDir, "b")},
+					WorkFile: filepath.Join(workspaceDir, "go.work"),
+				},
+			},
+		},
+		{
+			name:      "workspace disabled",
+			searchDir: filepath.Join(workspaceDir, "b"),
+			env:       []string{"GOWORK=off"},
+			expected: []loadRoot{
+				{Dir: filepath.Join(workspaceDir, "b"), Patterns: []string{"./..."}, PkgDirs: []string{filepath.Join(workspaceDir, "b")}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			roots, err := findLoadRootsInSearchDir(tc.searchDir, tc.env)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, roots)
+		})
+	}
+}
+
 func BenchmarkInspect(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, err := Inspect(file.Loc{
 			Path:   "testdata/testmodule003/func.go",
 			Line:   15,
 			Column: 9,
-		}, "", AllRelationKinds)
+		}, "", AllRelationKinds, Options{})
 		require.NoError(b, err)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"go/ast"
+	"go/token"
 	"io/fs"
 	"os/exec"
 	"path/filepath"
@@ -13,35 +15,71 @@ import (
 	"golang.org/x/tools/go/packages"
 
 	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
 )
 
 func isGoTestFile(path string) bool {
 	return strings.HasSuffix(filepath.Base(path), "_test.go")
 }
 
-func loadGoPackageForFileLoc(loc file.Loc) (*packages.Package, error) {
+// includeTestsForLoc checks whether searches for relations should include test packages.
+func includeTestsForLoc(loc file.Loc, opts Options) bool {
+	return opts.IncludeTests || isGoTestFile(loc.Path)
+}
+
+func loadGoPackageForFileLoc(loc file.Loc, opts Options) (*packages.Package, error) {
 	absPath, err := filepath.Abs(loc.Path)
 	if err != nil {
 		return nil, fmt.Errorf("filepath.Abs: %w", err)
 	}
 
+	var parseFile func(*token.FileSet, string, []byte) (*ast.File, error)
+	if opts.Cache == nil {
+		// Cached packages may be reused for other locations, so we can only
+		// skip function bodies when the package is loaded for this location alone.
+		parseFile = selectivelyParseFileFunc(absPath, loc.Line)
+	}
+
+	pkgs, err := loadGoPackagesInDir(filepath.Dir(absPath), isGoTestFile(loc.Path), parseFile, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return goPackageForPath(pkgs, loc.Path)
+}
+
+// loadGoPackagesInDir loads the packages in a directory with the syntax and type information needed to inspect identifiers.
+func loadGoPackagesInDir(dir string, includeTests bool, parseFile func(*token.FileSet, string, []byte) (*ast.File, error), opts Options) ([]*packages.Package, error) {
 	cfg := &packages.Config{
 		Mode: (packages.NeedName |
 			packages.NeedFiles |
 			packages.NeedSyntax |
+			packages.NeedImports |
 			packages.NeedDeps |
 			packages.NeedTypes |
 			packages.NeedTypesInfo),
-		Dir:       filepath.Dir(absPath),
-		ParseFile: selectivelyParseFileFunc(absPath, loc.Line),
-		Tests:     isGoTestFile(loc.Path),
+		Dir:       dir,
+		Tests:     includeTests,
+		ParseFile: parseFile,
+		Env:       opts.Build.Env(),
+		Overlay:   opts.Overlay,
 	}
 
-	pkgs, err := packages.Load(cfg, ".")
+	pkgs, err := opts.Cache.Load(cfg, ".")
 	if err != nil {
 		return nil, fmt.Errorf("packages.Load: %w", err)
 	}
 
+	return pkgs, nil
+}
+
+// goPackageForPath returns the loaded package containing a Go file.
+func goPackageForPath(pkgs []*packages.Package, path string) (*packages.Package, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Abs: %w", err)
+	}
+
 	// If tests are included, pkgs will include both test and non-test packages.
 	// The test packages have both test files *and* non-test Go files.
 	// For non-test files, this loop will choose whichever package comes first
@@ -54,13 +92,14 @@ func loadGoPackageForFileLoc(loc file.Loc) (*packages.Package, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("Could not find Go package for path %q", loc.Path)
+	return nil, fmt.Errorf("Could not find Go package for path %q", path)
 }
 
-func loadGoPackagesMatchingPredicate(searchDir string, mode packages.LoadMode, includeTests bool, f func(skeletonPkg) bool) ([]*packages.Package, error) {
-	// Find possible Go modules in the search directory (recursively).
+func loadGoPackagesMatchingPredicate(searchDir string, opts Options, mode packages.LoadMode, includeTests bool, f func(skeletonPkg) bool) ([]*packages.Package, error) {
+	// Find possible Go modules in the search directory (recursively), grouping modules in the same workspace.
 	// This always includes the search directory itself, which may or may not be a Go module.
-	possibleGoModDirs, err := findPossibleGoModDirsInSearchDir(searchDir)
+	env := opts.Build.Env()
+	roots, err := findLoadRootsInSearchDir(searchDir, env)
 	if err != nil {
 		return nil, err
 	}
@@ -71,52 +110,92 @@ func loadGoPackagesMatchingPredicate(searchDir string, mode packages.LoadMode, i
 		mode |= packages.NeedFiles
 	}
 
+	if opts.SearchDeps {
+		// Needed to tell dependencies apart from packages in the search directory.
+		mode |= packages.NeedModule
+	}
+
 	var resultPkgs []*packages.Package
-	for _, dir := range possibleGoModDirs {
-		// Load minimal metadata for all packages in each possible Go module,
+	for _, root := range roots {
+		// Load minimal metadata for all packages in each possible Go module or workspace,
 		// so we can quickly find packages that equal or import the target package.
-		candidatePkgs, err := goListSkeletonPkgs(dir) // Returns an empty slice if dir isn't in a Go module.
+		candidatePkgs, err := goListSkeletonPkgs(root, env, opts.SearchDeps, opts.Index) // Returns an empty slice if root isn't in a Go module.
 		if err != nil {
 			return nil, err
 		}
 
 		// Filter for pkgs that match the predicate.
-		pkgPaths := make([]string, 0, len(candidatePkgs))
+		var pkgPaths, depPkgPaths []string
+		matchedPkgPaths := make(map[string]struct{}, len(candidatePkgs))
 		for _, pkg := range candidatePkgs {
 			if f(pkg) {
-				pkgPaths = append(pkgPaths, pkg.ImportPath)
+				pkgPaths, depPkgPaths = appendSkeletonPkgPath(pkgPaths, depPkgPaths, pkg)
+				matchedPkgPaths[pkg.ImportPath] = struct{}{}
 			}
 		}
 
-		if len(pkgPaths) == 0 {
+		if len(pkgPaths) == 0 && len(depPkgPaths) == 0 {
 			continue
 		}
 
-		// Parse and typecheck packages that either equal or import the target package.
-		cfg := &packages.Config{
-			Mode:  mode,
-			Dir:   dir,
-			Tests: includeTests,
+		if opts.loadAllSearchPkgs {
+			// Load every package in the module, so the cached result can be reused
+			// for any predicate, then filter for the packages that matched.
+			pkgPaths, depPkgPaths = nil, nil
+			for _, pkg := range candidatePkgs {
+				pkgPaths, depPkgPaths = appendSkeletonPkgPath(pkgPaths, depPkgPaths, pkg)
+			}
 		}
 
-		pkgs, err := packages.Load(cfg, pkgPaths...)
-		if err != nil {
-			return nil, fmt.Errorf("packages.Load: %w", err)
-		}
+		// Parse and typecheck packages that either equal or import the target package.
+		// Dependencies are loaded without their tests, which may import packages outside the build list.
+		for _, load := range []struct {
+			pkgPaths []string
+			tests    bool
+		}{{pkgPaths, includeTests}, {depPkgPaths, false}} {
+			if len(load.pkgPaths) == 0 {
+				continue
+			}
 
-		// If tests are included, pkgs will include both test and non-test packages.
-		// The test packages have both test files *and* non-test Go files.
-		// Deduplicate these by choosing the test package over the non-test package.
-		if includeTests {
-			pkgs = deduplicateTestPkgs(pkgs)
-		}
+			cfg := &packages.Config{
+				Mode:    mode,
+				Dir:     root.Dir,
+				Tests:   load.tests,
+				Env:     env,
+				Overlay: opts.Overlay,
+			}
+
+			pkgs, err := opts.Cache.Load(cfg, load.pkgPaths...)
+			if err != nil {
+				return nil, fmt.Errorf("packages.Load: %w", err)
+			}
+
+			// If tests are included, pkgs will include both test and non-test packages.
+			// The test packages have both test files *and* non-test Go files.
+			// Deduplicate these by choosing the test package over the non-test package.
+			if load.tests {
+				pkgs = deduplicateTestPkgs(pkgs)
+			}
 
-		resultPkgs = append(resultPkgs, pkgs...)
+			if opts.loadAllSearchPkgs {
+				pkgs = filterPkgsByPath(pkgs, matchedPkgPaths)
+			}
+
+			resultPkgs = append(resultPkgs, pkgs...)
+		}
 	}
 
 	return resultPkgs, nil
 }
 
+// appendSkeletonPkgPath appends the import path of a package to depPkgPaths if it's a dependency, or to pkgPaths otherwise.
+func appendSkeletonPkgPath(pkgPaths, depPkgPaths []string, skel skeletonPkg) ([]string, []string) {
+	if skel.IsDep() {
+		return pkgPaths, append(depPkgPaths, skel.ImportPath)
+	}
+	return append(pkgPaths, skel.ImportPath), depPkgPaths
+}
+
 func deduplicateTestPkgs(pkgs []*packages.Package) []*packages.Package {
 	pkgSet := make(map[string]*packages.Package, len(pkgs))
 	for _, pkg := range pkgs {
@@ -142,6 +221,19 @@ func deduplicateTestPkgs(pkgs []*packages.Package) []*packages.Package {
 	return dedupedPkgs
 }
 
+// filterPkgsByPath returns the packages whose import path is in pkgPaths.
+// Test packages are matched by the import path of the package they test.
+func filterPkgsByPath(pkgs []*packages.Package, pkgPaths map[string]struct{}) []*packages.Package {
+	var result []*packages.Package
+	for _, pkg := range pkgs {
+		pkgPath := strings.TrimSuffix(strings.TrimSuffix(pkg.PkgPath, "_test"), ".test")
+		if _, ok := pkgPaths[pkgPath]; ok {
+			result = append(result, pkg)
+		}
+	}
+	return result
+}
+
 func findPossibleGoModDirsInSearchDir(searchDir string) ([]string, error) {
 	candidateSet := make(map[string]struct{}, 1)
 
@@ -184,7 +276,24 @@ func findPossibleGoModDirsInSearchDir(searchDir string) ([]string, error) {
 // output by the `go list` cmd (see `go help list`).
 type skeletonPkg struct {
 	ImportPath string // Equivalent to the PkgPath field in packages.Package
+	Name       string
 	Imports    []string
+	Dir        string
+	Standard   bool
+	Module     *skeletonModule
+}
+
+// skeletonModule is the module containing a skeleton pkg.
+type skeletonModule struct {
+	Path    string
+	Version string
+	Main    bool
+}
+
+// IsDep checks whether the skeleton pkg is in a dependency module (in the module cache or vendored),
+// instead of one of the modules in the search directory.
+func (skel skeletonPkg) IsDep() bool {
+	return skel.Module != nil && !skel.Module.Main
 }
 
 // ImportsPkg checks whether the skeleton pkg imports a given package.
@@ -197,14 +306,133 @@ func (skel skeletonPkg) ImportsPkg(targetPkgPath string) bool {
 	return false
 }
 
-// goListSkeletonPkgs returns skeleton pkgs for every package in a Go module.
-// If goModDir isn't in a Go module, this returns an empty slice (no error).
-func goListSkeletonPkgs(goModDir string) ([]skeletonPkg, error) {
+// SearchPkg is a package in one of the Go modules in a search directory.
+type SearchPkg struct {
+	ImportPath string
+	Name       string
+	Imports    []string
+}
+
+// ListSearchPkgs lists every package in every Go module in searchDir, sorted by import path.
+// If opts.SearchDeps is set, this includes the packages in their dependency modules.
+// Packages with errors (like import cycles) are included.
+func ListSearchPkgs(searchDir string, opts Options) ([]SearchPkg, error) {
+	env := opts.Build.Env()
+	roots, err := findLoadRootsInSearchDir(searchDir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	var result []SearchPkg
+	for _, root := range roots {
+		skeletonPkgs, err := goListSkeletonPkgs(root, env, opts.SearchDeps, opts.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, skel := range skeletonPkgs {
+			if _, ok := seen[skel.ImportPath]; ok {
+				continue
+			}
+			seen[skel.ImportPath] = struct{}{}
+			result = append(result, SearchPkg{ImportPath: skel.ImportPath, Name: skel.Name, Imports: skel.Imports})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ImportPath < result[j].ImportPath
+	})
+
+	return result, nil
+}
+
+// skeletonIndexNamespace identifies skeleton pkg records in the on-disk index.
+// Increment the version whenever the record format changes.
+const skeletonIndexNamespace = "skeleton-v4"
+
+// goListSkeletonPkgs returns skeleton pkgs for every package matching the patterns of a load root.
+// If deps is true, this includes the packages they depend on, except for the standard library.
+// If the root isn't in a Go module, this returns an empty slice (no error).
+// If idx is non-nil, the result is reused until a Go file or module file in the root changes.
+func goListSkeletonPkgs(root loadRoot, env []string, deps bool, idx *index.Index) ([]skeletonPkg, error) {
+	if idx == nil {
+		return goListSkeletonPkgsUncached(root, env, deps)
+	}
+
+	key, err := skeletonIndexKey(idx, root, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if deps {
+		// Dependencies in the module cache are identified by the versions in go.mod, but vendored dependencies
+		// aren't part of the directory key, so also use vendor/modules.txt (which changes whenever they're updated).
+		modulesTxtPath := filepath.Join(root.Dir, "vendor", "modules.txt")
+		modulesTxtHash, err := idx.HashFile(modulesTxtPath)
+		if err != nil {
+			return nil, err
+		}
+		key = index.HashStrings(key, "deps", modulesTxtPath, modulesTxtHash)
+	}
+
+	var result []skeletonPkg
+	if ok, err := idx.Get(skeletonIndexNamespace, key, &result); err != nil {
+		return nil, err
+	} else if ok {
+		return result, nil
+	}
+
+	result, err = goListSkeletonPkgsUncached(root, env, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := idx.Put(skeletonIndexNamespace, key, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// skeletonIndexKey returns the index key for the skeleton pkgs of a load root.
+// A workspace root's key covers each of its package directories and the go.work file.
+func skeletonIndexKey(idx *index.Index, root loadRoot, env []string) (string, error) {
+	salt := index.EnvSalt(env)
+	if root.WorkFile == "" {
+		return idx.DirKey(root.Dir, salt)
+	}
+
+	workFileHash, err := idx.HashFile(root.WorkFile)
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{root.WorkFile, workFileHash}
+	parts = append(parts, root.Patterns...)
+	for _, dir := range root.PkgDirs {
+		key, err := idx.DirKey(dir, salt)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, key)
+	}
+	return index.HashStrings(parts...), nil
+}
+
+func goListSkeletonPkgsUncached(root loadRoot, env []string, deps bool) ([]skeletonPkg, error) {
 	// We use the `go list` command directly instead of packages.Load
 	// because we need the Dir field, which isn't exposed by packages.Load.
 	var stdoutBuf, stderrBuf bytes.Buffer
-	cmd := exec.Command("go", "list", "-json=ImportPath,Imports", "./...")
-	cmd.Dir = goModDir
+	// The -e flag reports packages with errors (like import cycles) instead of failing.
+	args := []string{"list", "-e", "-json=ImportPath,Name,Imports,Dir,Standard,Module"}
+	if deps {
+		args = append(args, "-deps")
+	}
+	args = append(args, root.Patterns...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = root.Dir
+	cmd.Env = env
 	cmd.Stdout = &stdoutBuf
 	cmd.Stderr = &stderrBuf
 
@@ -225,6 +453,16 @@ func goListSkeletonPkgs(goModDir string) ([]skeletonPkg, error) {
 		if err := dec.Decode(&skel); err != nil {
 			return nil, fmt.Errorf("json.Unmarshal: %w", err)
 		}
+
+		if skel.Name == "" {
+			// Outside a Go module, `go list -e` reports the pattern itself as a package without a name.
+			continue
+		}
+
+		if skel.Standard {
+			// With -deps, the result includes the standard library, which is never searched.
+			continue
+		}
 		result = append(result, skel)
 	}
 
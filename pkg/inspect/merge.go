@@ -0,0 +1,55 @@
+package inspect
+
+// MergeResults combines the results of inspecting the same identifier in several build configurations.
+// The name, type, and documentation come from the first result, and relations from every result are combined
+// without duplicates, grouped by kind in the order each kind first appears. Near misses are combined the same way.
+// Nil results are skipped, and if every result is nil, this returns nil.
+func MergeResults(results []*Result) *Result {
+	var nonNilResults []*Result
+	for _, r := range results {
+		if r != nil {
+			nonNilResults = append(nonNilResults, r)
+		}
+	}
+
+	if len(nonNilResults) == 0 {
+		return nil
+	} else if len(nonNilResults) == 1 {
+		return nonNilResults[0]
+	}
+
+	var kinds []RelationKind
+	relationSets := make(map[RelationKind]map[Relation]struct{})
+	for _, r := range nonNilResults {
+		for _, rel := range r.Relations {
+			relationSet, ok := relationSets[rel.Kind]
+			if !ok {
+				relationSet = make(map[Relation]struct{})
+				relationSets[rel.Kind] = relationSet
+				kinds = append(kinds, rel.Kind)
+			}
+			relationSet[rel] = struct{}{}
+		}
+	}
+
+	merged := *nonNilResults[0]
+	merged.Relations = merged.Relations[:0:0]
+	for _, kind := range kinds {
+		merged.Relations = append(merged.Relations, relationSetToSortedSlice(relationSets[kind])...)
+	}
+
+	// The missing methods can differ between configurations, so keep the first near miss for each interface.
+	merged.NearMisses = merged.NearMisses[:0:0]
+	seenNearMisses := make(map[Relation]struct{})
+	for _, r := range nonNilResults {
+		for _, nm := range r.NearMisses {
+			if _, ok := seenNearMisses[nm.Relation]; !ok {
+				seenNearMisses[nm.Relation] = struct{}{}
+				merged.NearMisses = append(merged.NearMisses, nm)
+			}
+		}
+	}
+	sortNearMisses(merged.NearMisses)
+
+	return &merged
+}
@@ -0,0 +1,72 @@
+package inspect
+
+import (
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+// enrichResultRelationModules labels relations in dependency modules with the module path and version.
+func enrichResultRelationModules(result *Result, pkg *packages.Package, loc file.Loc, searchDir string, opts Options) error {
+	if len(result.Relations) == 0 {
+		return nil
+	}
+
+	modulesByDir, err := depModulesByPkgDir(searchDir, opts)
+	if err != nil {
+		return err
+	}
+
+	for i, rel := range result.Relations {
+		if module, ok := modulesByDir[filepath.Dir(rel.Path)]; ok {
+			result.Relations[i].Module = module.Path
+			result.Relations[i].Version = module.Version
+		}
+	}
+
+	return nil
+}
+
+// depModulesByPkgDir maps the directory of each package in a dependency of the modules in searchDir to its module.
+func depModulesByPkgDir(searchDir string, opts Options) (map[string]*skeletonModule, error) {
+	env := opts.Build.Env()
+	roots, err := findLoadRootsInSearchDir(searchDir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*skeletonModule)
+	for _, root := range roots {
+		skeletonPkgs, err := goListSkeletonPkgs(root, env, true, opts.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, skel := range skeletonPkgs {
+			if skel.IsDep() && skel.Dir != "" {
+				result[skel.Dir] = skel.Module
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// forEachDepPkg calls f once for every package in a dependency module imported (transitively) by pkgs,
+// skipping packages in visited and adding the others to it.
+// The packages must be loaded with packages.NeedModule.
+func forEachDepPkg(pkgs []*packages.Package, visited map[*packages.Package]struct{}, f func(*packages.Package)) {
+	packages.Visit(pkgs, func(pkg *packages.Package) bool {
+		if _, ok := visited[pkg]; ok {
+			return false
+		}
+		visited[pkg] = struct{}{}
+
+		if pkg.Module != nil && !pkg.Module.Main && pkg.Types != nil {
+			f(pkg)
+		}
+		return true
+	}, nil)
+}
@@ -12,6 +12,9 @@ const (
 	// The relation between a usage and its definition.
 	RelationKindDef = RelationKind("definition")
 
+	// The relation between a variable or constant and the declarations of the types in its type.
+	RelationKindTypeDef = RelationKind("type-definition")
+
 	// The relation between a definition and its references.
 	RelationKindRef = RelationKind("reference")
 
@@ -20,6 +23,21 @@ const (
 
 	// The relation between an implementation and its interface.
 	RelationKindIface = RelationKind("interface")
+
+	// The relation between a function and the call sites that call it.
+	RelationKindCaller = RelationKind("caller")
+
+	// The relation between a function and the functions it calls.
+	RelationKindCallee = RelationKind("callee")
+
+	// The relation between a struct or interface type and the types, fields, and methods it embeds or promotes.
+	RelationKindEmbeds = RelationKind("embeds")
+
+	// The relation between a type and the struct or interface types that embed it.
+	RelationKindEmbeddedBy = RelationKind("embedded-by")
+
+	// The relation between a generic function or type and the places it is instantiated.
+	RelationKindInstantiation = RelationKind("instantiation")
 )
 
 var AllRelationKinds []RelationKind
@@ -28,9 +46,15 @@ var AllRelationKindStrings []string
 func init() {
 	AllRelationKinds = []RelationKind{
 		RelationKindDef,
+		RelationKindTypeDef,
 		RelationKindRef,
 		RelationKindImpl,
 		RelationKindIface,
+		RelationKindCaller,
+		RelationKindCallee,
+		RelationKindEmbeds,
+		RelationKindEmbeddedBy,
+		RelationKindInstantiation,
 	}
 	for _, r := range AllRelationKinds {
 		AllRelationKindStrings = append(AllRelationKindStrings, string(r))
@@ -58,12 +82,90 @@ func RelationKindsFromStrings(relKindStrings []string) ([]RelationKind, error) {
 	return relKinds, nil
 }
 
+// RefAccess classifies how a reference uses the identifier it refers to.
+type RefAccess string
+
+const (
+	// The reference reads the value of a variable, field, or constant.
+	RefAccessRead = RefAccess("read")
+
+	// The reference assigns to a variable or field, including with ++, --, and assignment operators like +=,
+	// or assigns through it to an element, field, or pointer target, as in x[i] = v, x.f.g = v, and *x = v.
+	RefAccessWrite = RefAccess("write")
+
+	// The reference takes the address of a variable or field with the & operator.
+	RefAccessAddress = RefAccess("address")
+
+	// The reference calls a function or method.
+	RefAccessCall = RefAccess("call")
+
+	// The reference names a type, for example in a declaration, conversion, or composite literal.
+	RefAccessType = RefAccess("type")
+
+	// The reference is the name of an imported package, as in the "fmt" of fmt.Println.
+	RefAccessImport = RefAccess("import")
+
+	// The reference is a field name used as a key in a struct literal.
+	RefAccessFieldKey = RefAccess("field-key")
+)
+
+var AllRefAccesses []RefAccess
+var AllRefAccessStrings []string
+
+func init() {
+	AllRefAccesses = []RefAccess{
+		RefAccessRead,
+		RefAccessWrite,
+		RefAccessAddress,
+		RefAccessCall,
+		RefAccessType,
+		RefAccessImport,
+		RefAccessFieldKey,
+	}
+	for _, a := range AllRefAccesses {
+		AllRefAccessStrings = append(AllRefAccessStrings, string(a))
+	}
+}
+
+func RefAccessFromString(s string) (RefAccess, error) {
+	for _, a := range AllRefAccessStrings {
+		if s == a {
+			return RefAccess(s), nil
+		}
+	}
+	return RefAccess(""), fmt.Errorf("Invalid reference access %q", s)
+}
+
+func RefAccessesFromStrings(accessStrings []string) ([]RefAccess, error) {
+	accesses := make([]RefAccess, 0, len(accessStrings))
+	for _, s := range accessStrings {
+		a, err := RefAccessFromString(s)
+		if err != nil {
+			return nil, err
+		}
+		accesses = append(accesses, a)
+	}
+	return accesses, nil
+}
+
 // Relation represents a relationship between an identifier to some other part of the codebase.
 type Relation struct {
 	file.Loc
-	Kind RelationKind
-	Pkg  string
-	Name string
+	Kind RelationKind `json:"kind"`
+	Pkg  string       `json:"pkg"`
+	Name string       `json:"name"`
+
+	// Via is the dot-separated path of embedded types between the identifier and the related type or member,
+	// for relations through embedding. For example, a field promoted through T.Inner.Field has Via "Inner".
+	Via string `json:"via,omitempty"`
+
+	// Access is how a reference uses the identifier. It is set only for reference relations.
+	Access RefAccess `json:"access,omitempty"`
+
+	// Module and Version identify the dependency module containing the relation, when searching dependencies.
+	// They're empty for relations in the search directory and the standard library.
+	Module  string `json:"module,omitempty"`
+	Version string `json:"version,omitempty"`
 }
 
 type RelationSlice []Relation
@@ -0,0 +1,193 @@
+package inspect
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+// InspectSymbol is like Inspect, but locates the identifier by name instead of by file location.
+// See ResolveSymbol for the format of the symbol.
+func InspectSymbol(symbol string, searchDir string, includeRelKinds []RelationKind, opts Options) (*Result, error) {
+	loc, err := ResolveSymbol(symbol, searchDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return Inspect(loc, searchDir, includeRelKinds, opts)
+}
+
+// ResolveSymbol returns the location of the definition for a symbol like "pkg.Func", "pkg.Type.Field", or "pkg.Type.Method".
+// The package may be an import path, or the name or last path element of a package in searchDir.
+func ResolveSymbol(symbol string, searchDir string, opts Options) (file.Loc, error) {
+	splits := symbolSplits(symbol)
+	if len(splits) == 0 {
+		return file.Loc{}, fmt.Errorf("Invalid symbol %q, expected the form pkg.Name, pkg.Type.Field, or pkg.Type.Method", symbol)
+	}
+
+	for _, split := range splits {
+		pkg, err := loadGoPackageForSymbol(split.pkg, searchDir, opts)
+		if err != nil {
+			return file.Loc{}, err
+		}
+
+		if pkg == nil {
+			// The package might end later in the symbol, so try the next split.
+			continue
+		}
+
+		obj, err := typeObjForNames(pkg.Types, split.names)
+		if err != nil {
+			return file.Loc{}, err
+		}
+
+		if !obj.Pos().IsValid() {
+			return file.Loc{}, fmt.Errorf("Could not find location of %q", symbol)
+		}
+
+		return fileLocForTypeObj(pkg, obj), nil
+	}
+
+	return file.Loc{}, fmt.Errorf("Could not find package for symbol %q", symbol)
+}
+
+// symbolSplit is one way to divide a symbol into a package and names within the package.
+type symbolSplit struct {
+	pkg   string
+	names []string
+}
+
+// symbolSplits returns the possible ways to split a symbol into a package and one or two names.
+// Import paths can contain dots after the last slash (like "gopkg.in/yaml.v3"), so there may be more than one.
+func symbolSplits(symbol string) []symbolSplit {
+	var splits []symbolSplit
+	for i := strings.LastIndex(symbol, "/") + 1; i < len(symbol); i++ {
+		if symbol[i] != '.' || i == 0 {
+			continue
+		}
+
+		names := strings.Split(symbol[i+1:], ".")
+		if len(names) > 2 {
+			continue
+		}
+
+		valid := true
+		for _, name := range names {
+			valid = valid && token.IsIdentifier(name)
+		}
+
+		if valid {
+			splits = append(splits, symbolSplit{pkg: symbol[:i], names: names})
+		}
+	}
+	return splits
+}
+
+// loadGoPackageForSymbol loads the package referred to by the package part of a symbol.
+// Packages in searchDir are matched first, by import path, package name, or last path element.
+// Otherwise, the package is loaded by import path (for example, from the standard library).
+// This returns a nil package (and no error) if no package matches.
+func loadGoPackageForSymbol(pkgPart string, searchDir string, opts Options) (*packages.Package, error) {
+	env := opts.Build.Env()
+	roots, err := findLoadRootsInSearchDir(searchDir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map from import path to the directory it was listed from.
+	exactMatches := make(map[string]string)
+	otherMatches := make(map[string]string)
+	for _, root := range roots {
+		candidatePkgs, err := goListSkeletonPkgs(root, env, false, opts.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candidate := range candidatePkgs {
+			if candidate.ImportPath == pkgPart {
+				exactMatches[candidate.ImportPath] = root.Dir
+			} else if candidate.Name == pkgPart || strings.HasSuffix(candidate.ImportPath, "/"+pkgPart) {
+				otherMatches[candidate.ImportPath] = root.Dir
+			}
+		}
+	}
+
+	matches := exactMatches
+	if len(matches) == 0 {
+		matches = otherMatches
+	}
+
+	if len(matches) > 1 {
+		pkgPaths := make([]string, 0, len(matches))
+		for pkgPath := range matches {
+			pkgPaths = append(pkgPaths, pkgPath)
+		}
+		sort.Strings(pkgPaths)
+		return nil, fmt.Errorf("Ambiguous package %q matches %s", pkgPart, strings.Join(pkgPaths, ", "))
+	}
+
+	pattern, dir := pkgPart, searchDir
+	for pkgPath, matchDir := range matches {
+		pattern, dir = pkgPath, matchDir
+	}
+
+	// Load dependencies from source, since fields and methods may be promoted from another package,
+	// and export data doesn't record exact positions.
+	cfg := &packages.Config{
+		Mode: (packages.NeedName |
+			packages.NeedFiles |
+			packages.NeedSyntax |
+			packages.NeedImports |
+			packages.NeedDeps |
+			packages.NeedTypes |
+			packages.NeedTypesInfo),
+		Dir:     dir,
+		Env:     env,
+		Overlay: opts.Overlay,
+	}
+
+	pkgs, err := opts.Cache.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.Types != nil && len(pkg.Errors) == 0 && pkg.PkgPath == pattern {
+			return pkg, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// typeObjForNames looks up a package-level object by name, then optionally a field or method of that object.
+func typeObjForNames(pkg *types.Package, names []string) (types.Object, error) {
+	obj := pkg.Scope().Lookup(names[0])
+	if obj == nil {
+		return nil, fmt.Errorf("Could not find %q in package %s", names[0], pkg.Path())
+	}
+
+	if len(names) == 1 {
+		return obj, nil
+	}
+
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil, fmt.Errorf("Could not find field or method %q, because %s.%s is not a type", names[1], pkg.Path(), names[0])
+	}
+
+	member, index, _ := types.LookupFieldOrMethod(obj.Type(), true, pkg, names[1])
+	if member == nil {
+		if index != nil {
+			return nil, fmt.Errorf("Ambiguous selector %s.%s, because more than one embedded type has a field or method named %q", names[0], names[1], names[1])
+		}
+		return nil, fmt.Errorf("Could not find field or method %q of type %s.%s", names[1], pkg.Path(), names[0])
+	}
+
+	return member, nil
+}
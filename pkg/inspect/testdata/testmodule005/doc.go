@@ -0,0 +1,3 @@
+// Greeter prints greetings to standard output.
+// It exists to check that doc comments are surfaced.
+package main
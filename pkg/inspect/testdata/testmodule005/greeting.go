@@ -0,0 +1,14 @@
+package main
+
+import "fmt"
+
+// Greeting is the message printed by greet.
+const Greeting = "Hello"
+
+// greet prints a greeting.
+//
+// It returns the number of bytes written.
+func greet(name string) int {
+	n, _ := fmt.Printf("%s, %s!\n", Greeting, name)
+	return n
+}
@@ -0,0 +1,15 @@
+package main
+
+import "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule016/shapes"
+
+func main() {
+	describe(shapes.NewSquare(2))
+}
+
+func describe(s shapes.Shape) float64 {
+	return s.Area() + offset()
+}
+
+func offset() float64 {
+	return 1
+}
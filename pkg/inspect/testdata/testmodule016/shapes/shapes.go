@@ -0,0 +1,17 @@
+package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	side float64
+}
+
+func NewSquare(side float64) *Square {
+	return &Square{side: side}
+}
+
+func (s *Square) Area() float64 {
+	return s.side * s.side
+}
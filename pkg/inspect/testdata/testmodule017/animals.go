@@ -0,0 +1,17 @@
+package main
+
+import "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule017/base"
+
+type Animal struct {
+	base.Entity
+	Legs int
+}
+
+func (a Animal) Describe() string {
+	return a.Name()
+}
+
+type Dog struct {
+	*Animal
+	Breed string
+}
@@ -0,0 +1,10 @@
+package base
+
+type Entity struct {
+	ID   int
+	name string
+}
+
+func (e Entity) Name() string {
+	return e.name
+}
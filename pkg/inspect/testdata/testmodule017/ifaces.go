@@ -0,0 +1,15 @@
+package main
+
+type Named interface {
+	Name() string
+}
+
+type Describer interface {
+	Named
+	Describe() string
+}
+
+type Pet interface {
+	Describer
+	Owner() string
+}
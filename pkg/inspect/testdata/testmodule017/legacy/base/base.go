@@ -0,0 +1,5 @@
+package base
+
+type Entity struct {
+	Key string
+}
@@ -0,0 +1,11 @@
+package config
+
+type Config struct {
+	Name string
+}
+
+type Level int
+
+type List[T any] struct {
+	Items []T
+}
@@ -0,0 +1,18 @@
+package main
+
+import "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule018/config"
+
+const defaultLevel config.Level = 1
+
+var byName map[string][]*config.Config
+
+func main() {
+	cfg := &config.Config{Name: "main"}
+	updates := make(chan config.List[*config.Config])
+	count := len(byName)
+	println(cfg.Name, updates, count, defaultLevel, first([]config.Level{defaultLevel}))
+}
+
+func first[T any](items []T) T {
+	return items[0]
+}
@@ -0,0 +1,7 @@
+package main
+
+import "github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule018/config"
+
+var names = first([]string{"a", "b"})
+
+var levels config.List[config.Level]
@@ -0,0 +1,38 @@
+package main
+
+import "fmt"
+
+type Counter struct {
+	Total int
+}
+
+func (c *Counter) Add(n int) {
+	c.Total += n
+}
+
+func main() {
+	c := Counter{Total: 1}
+	c.Total++
+	p := &c.Total
+	c.Add(*p)
+	for _, c.Total = range []int{1, 2} {
+	}
+	fmt.Println(c.Total, (c.Total))
+}
+
+type Point struct {
+	X int
+}
+
+type Shape struct {
+	Origin Point
+}
+
+func update(shapes []Shape, counts map[string]int, s *Shape, p *int, key string) {
+	shapes[0] = Shape{}
+	counts[key]++
+	s.Origin.X = 1
+	*p = 2
+	shapes[1].Origin.X += counts[key]
+	fmt.Println(s.Origin.X, *p)
+}
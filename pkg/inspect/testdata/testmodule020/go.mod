@@ -0,0 +1,3 @@
+module github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule020
+
+go 1.19
@@ -0,0 +1,8 @@
+package main
+
+import "fmt"
+
+func main() {
+	π, 𝜋 := 3.14, 3.14159
+	fmt.Println(π, 𝜋)
+}
@@ -0,0 +1,7 @@
+//go:build integration
+
+package main
+
+func runIntegration() string {
+	return platformName()
+}
@@ -0,0 +1,5 @@
+package main
+
+func platformName() string {
+	return "windows"
+}
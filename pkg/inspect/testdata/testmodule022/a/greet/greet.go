@@ -0,0 +1,9 @@
+package greet
+
+type Greeter interface {
+	Greet() string
+}
+
+func Hello() string {
+	return "hello"
+}
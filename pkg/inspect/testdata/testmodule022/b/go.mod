@@ -0,0 +1,7 @@
+module github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule022/b
+
+go 1.19
+
+require github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule022/a v0.0.0
+
+replace github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule022/a => ../a
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule022/a/greet"
+)
+
+type english struct{}
+
+func (english) Greet() string {
+	return greet.Hello()
+}
+
+func main() {
+	var g greet.Greeter = english{}
+	fmt.Println(g.Greet())
+}
@@ -0,0 +1,5 @@
+module github.com/wedaly/gospelunk/pkg/inspect/testdata/testmodule023
+
+go 1.19
+
+require example.com/shapes v1.2.0
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/shapes"
+)
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func main() {
+	fmt.Println(shapes.Total(Square{Side: 2}))
+}
@@ -0,0 +1,22 @@
+package main
+
+type Buffer struct {
+	data []byte
+}
+
+func (b *Buffer) Read(p []byte) (int, error) {
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func (b *Buffer) Close() error {
+	b.data = nil
+	return nil
+}
+
+func (b *Buffer) String() int {
+	return len(b.data)
+}
+
+func main() {}
@@ -0,0 +1,10 @@
+package iface
+
+type Resetter interface {
+	Reset()
+	Close() error
+}
+
+type Sizer interface {
+	Size() int64
+}
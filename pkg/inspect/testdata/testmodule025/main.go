@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(describe("square", 2))
+}
+
+func describe(
+	name string,
+	sides int,
+) string {
+	return repeat(name, sides)
+}
+
+func repeat(s string, n int) string {
+	return fmt.Sprint(n, s)
+}
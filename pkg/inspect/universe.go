@@ -0,0 +1,184 @@
+package inspect
+
+import (
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// NearMiss is an interface that a type almost implements, along with the methods it's missing.
+type NearMiss struct {
+	Relation
+
+	// Missing are the interface methods the type doesn't have, like "Write(p []byte) (n int, err error)".
+	// If the type has a method with the same name but a different signature, the entry ends with the signature it has,
+	// like "String() string (have String() int)".
+	Missing []string `json:"missing"`
+}
+
+// universeIface is an exported interface in one of the packages in the universe, checked against a type.
+type universeIface struct {
+	pkg     *packages.Package
+	obj     *types.TypeName
+	iface   *types.Interface
+	missing []string // Empty if the type implements the interface.
+}
+
+// universeIfacesForType checks a type against every exported interface in the packages matching opts.Universe,
+// returning the interfaces it implements and the near misses. An interface is a near miss if the type has methods
+// with the names of at least half of its methods.
+func universeIfacesForType(implObj types.Object, pkg *packages.Package, opts Options) ([]universeIface, error) {
+	if len(opts.Universe) == 0 || len(pkg.GoFiles) == 0 {
+		return nil, nil
+	}
+
+	// Load the type's package along with the universe, so they share the types of common dependencies.
+	// Only the types are needed, which come from export data instead of parsing and typechecking every package.
+	cfg := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		Dir:     filepath.Dir(pkg.GoFiles[0]),
+		Env:     opts.Build.Env(),
+		Overlay: opts.Overlay,
+	}
+
+	pkgs, err := opts.Cache.Load(cfg, append([]string{pkg.PkgPath}, opts.Universe...)...)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	var implType types.Type
+	for _, p := range pkgs {
+		if p.PkgPath == pkg.PkgPath && p.Types != nil {
+			if obj, ok := p.Types.Scope().Lookup(implObj.Name()).(*types.TypeName); ok {
+				implType = obj.Type()
+			}
+			break
+		}
+	}
+
+	if implType == nil {
+		// The type isn't declared at package scope (or is only in a test file), so it can't be checked.
+		return nil, nil
+	}
+
+	var result []universeIface
+	seen := make(map[*types.TypeName]struct{})
+	for _, ifacePkg := range pkgs {
+		if ifacePkg.Types == nil || ifacePkg.Name == "main" || !isPublicPkgPath(ifacePkg.PkgPath) {
+			continue
+		}
+
+		scope := ifacePkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !obj.Exported() || obj.IsAlias() {
+				continue
+			}
+
+			if _, ok := seen[obj]; ok {
+				continue
+			}
+			seen[obj] = struct{}{}
+
+			// Skip empty interfaces (which every type implements), constraints, and generic interfaces.
+			iface, ok := obj.Type().Underlying().(*types.Interface)
+			if !ok || iface.Empty() || !iface.IsMethodSet() {
+				continue
+			}
+			if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				continue
+			}
+
+			if types.Identical(implType.Underlying(), iface) {
+				// Interfaces always implement themselves.
+				continue
+			}
+
+			if types.Implements(implType, iface) || types.Implements(types.NewPointer(implType), iface) {
+				result = append(result, universeIface{pkg: ifacePkg, obj: obj, iface: iface})
+			} else if missing, ok := nearMissMethods(implType, iface, pkg.Types); ok {
+				result = append(result, universeIface{pkg: ifacePkg, obj: obj, iface: iface, missing: missing})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// nearMissMethods returns the methods of an interface that a type (or a pointer to it) is missing,
+// if the type has methods with the names of at least half of the interface methods.
+func nearMissMethods(implType types.Type, iface *types.Interface, implPkg *types.Package) ([]string, bool) {
+	var missing []string
+	namesFound := 0
+	for i := 0; i < iface.NumMethods(); i++ {
+		ifaceMethod := iface.Method(i)
+		if !ifaceMethod.Exported() {
+			// Types outside the interface's package can't implement unexported methods.
+			return nil, false
+		}
+
+		want := methodString(ifaceMethod, implPkg)
+		obj, _, _ := types.LookupFieldOrMethod(implType, true, ifaceMethod.Pkg(), ifaceMethod.Name())
+		implMethod, ok := obj.(*types.Func)
+		if !ok {
+			missing = append(missing, want)
+			continue
+		}
+
+		namesFound++
+		if !types.Identical(implMethod.Type(), ifaceMethod.Type()) {
+			missing = append(missing, fmt.Sprintf("%s (have %s)", want, methodString(implMethod, implPkg)))
+		}
+	}
+
+	if namesFound == 0 || namesFound*2 < iface.NumMethods() || len(missing) == 0 {
+		return nil, false
+	}
+
+	return missing, true
+}
+
+// methodString formats a method like "Read(p []byte) (n int, err error)", qualifying types from packages other than pkg.
+func methodString(method *types.Func, pkg *types.Package) string {
+	sig := types.TypeString(method.Type(), qualifierForPkg(pkg))
+	return method.Name() + strings.TrimPrefix(sig, "func")
+}
+
+// isPublicPkgPath checks whether a package can be imported from anywhere, so it isn't internal or vendored in GOROOT.
+func isPublicPkgPath(pkgPath string) bool {
+	if strings.HasPrefix(pkgPath, "vendor/") {
+		return false
+	}
+	for _, elem := range strings.Split(pkgPath, "/") {
+		if elem == "internal" {
+			return false
+		}
+	}
+	return true
+}
+
+// universeIfaceRelation constructs a relation to an interface in the universe, labeled with its module if it's in a dependency.
+func universeIfaceRelation(ui universeIface, name string, obj types.Object) Relation {
+	r := Relation{
+		Kind: RelationKindIface,
+		Pkg:  pkgNameForTypeObj(obj),
+		Name: name,
+		Loc:  fileLocForTypeObj(ui.pkg, obj),
+	}
+	if m := ui.pkg.Module; m != nil && !m.Main {
+		r.Module = m.Path
+		r.Version = m.Version
+	}
+	return r
+}
+
+// sortNearMisses sorts near misses by location, like relations.
+func sortNearMisses(nearMisses []NearMiss) {
+	sort.SliceStable(nearMisses, func(i, j int) bool {
+		return RelationSlice{nearMisses[i].Relation, nearMisses[j].Relation}.Less(0, 1)
+	})
+}
@@ -0,0 +1,135 @@
+package inspect
+
+import (
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// UseSites records which definitions are used by identifiers in a search directory,
+// and which methods implement interface methods.
+// Definitions are identified by file path, line, and name, since that's what list.List reports.
+type UseSites struct {
+	used       map[useSiteKey]struct{}
+	ifaceImpls map[useSiteKey]struct{}
+}
+
+type useSiteKey struct {
+	Path string
+	Line int
+	Name string
+}
+
+// LoadUseSites typechecks every package (including tests) in every Go module in searchDir,
+// and records the definition of every identifier use.
+func LoadUseSites(searchDir string, opts Options) (*UseSites, error) {
+	loadMode := (packages.NeedName |
+		packages.NeedFiles |
+		packages.NeedDeps |
+		packages.NeedImports |
+		packages.NeedTypes |
+		packages.NeedTypesInfo)
+
+	searchPkgs, err := loadGoPackagesMatchingPredicate(searchDir, opts, loadMode, true, func(skeletonPkg) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	u := &UseSites{
+		used:       make(map[useSiteKey]struct{}),
+		ifaceImpls: make(map[useSiteKey]struct{}),
+	}
+
+	for _, searchPkg := range searchPkgs {
+		for _, obj := range searchPkg.TypesInfo.Uses {
+			if obj.Pkg() != nil && obj.Pos().IsValid() {
+				u.used[useSiteKeyForObj(searchPkg, obj)] = struct{}{}
+			}
+		}
+	}
+
+	u.addIfaceImpls(searchPkgs)
+	return u, nil
+}
+
+// addIfaceImpls records every method declared in searchPkgs that implements a method of an interface
+// declared in searchPkgs or their dependencies. These methods may be called through the interface,
+// so they're used even if no identifier refers to them.
+func (u *UseSites) addIfaceImpls(searchPkgs []*packages.Package) {
+	ifacesByMethodName := make(map[string][]*types.Interface)
+	seenPkgs := make(map[*types.Package]struct{})
+	packages.Visit(searchPkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil {
+			return
+		}
+		if _, ok := seenPkgs[pkg.Types]; ok {
+			return
+		}
+		seenPkgs[pkg.Types] = struct{}{}
+
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			iface, ok := scope.Lookup(name).Type().Underlying().(*types.Interface)
+			if !ok {
+				continue
+			}
+			for i := 0; i < iface.NumMethods(); i++ {
+				methodName := iface.Method(i).Name()
+				ifacesByMethodName[methodName] = append(ifacesByMethodName[methodName], iface)
+			}
+		}
+	})
+
+	for _, searchPkg := range searchPkgs {
+		scope := searchPkg.Types.Scope()
+		names := scope.Names()
+		sort.Strings(names)
+		for _, name := range names {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() || types.IsInterface(typeName.Type()) {
+				continue
+			}
+
+			named, ok := typeName.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+
+			for i := 0; i < named.NumMethods(); i++ {
+				method := named.Method(i)
+				for _, iface := range ifacesByMethodName[method.Name()] {
+					if implementsIface(named, iface) {
+						u.ifaceImpls[useSiteKeyForObj(searchPkg, method)] = struct{}{}
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func implementsIface(named *types.Named, iface *types.Interface) bool {
+	if named.TypeParams().Len() > 0 {
+		// Generic types must be instantiated before checking whether they implement an interface.
+		return false
+	}
+	return types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface)
+}
+
+// IsUsed checks whether any identifier refers to the definition with a name declared on a line of a file.
+func (u *UseSites) IsUsed(path string, line int, name string) bool {
+	_, ok := u.used[useSiteKey{Path: path, Line: line, Name: name}]
+	return ok
+}
+
+// IsIfaceImpl checks whether the method with a name declared on a line of a file implements an interface method.
+func (u *UseSites) IsIfaceImpl(path string, line int, name string) bool {
+	_, ok := u.ifaceImpls[useSiteKey{Path: path, Line: line, Name: name}]
+	return ok
+}
+
+func useSiteKeyForObj(pkg *packages.Package, obj types.Object) useSiteKey {
+	position := pkg.Fset.Position(obj.Pos())
+	return useSiteKey{Path: position.Filename, Line: position.Line, Name: obj.Name()}
+}
@@ -0,0 +1,159 @@
+package inspect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// loadRoot is a directory where packages are listed and loaded, with the patterns matching packages in the search directory.
+// Modules in the same go.work workspace share a root, so they're loaded together in a single build,
+// and references between them follow the real dependency edges instead of loading each module separately.
+type loadRoot struct {
+	Dir      string
+	Patterns []string
+
+	// PkgDirs are the directories matched by the patterns.
+	PkgDirs []string
+
+	// WorkFile is the path of the go.work file, or empty if the root isn't a workspace.
+	WorkFile string
+}
+
+// findLoadRootsInSearchDir finds the roots for loading packages in the search directory.
+// Each possible Go module in the search directory is its own root, unless it's in a workspace,
+// in which case it's part of the root for the workspace.
+func findLoadRootsInSearchDir(searchDir string, env []string) ([]loadRoot, error) {
+	possibleGoModDirs, err := findPossibleGoModDirsInSearchDir(searchDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []loadRoot
+	workspaceRootIdx := make(map[string]int)
+	workspaces := make(map[string]*workspace)
+	for _, dir := range possibleGoModDirs {
+		ws, err := workspaceForDir(dir, env, workspaces)
+		if err != nil {
+			return nil, err
+		}
+
+		if ws == nil {
+			roots = append(roots, loadRoot{Dir: dir, Patterns: []string{"./..."}, PkgDirs: []string{dir}})
+			continue
+		}
+
+		if !ws.containsDir(dir) {
+			// The workspace directory itself usually isn't a module, and its modules are found separately.
+			// Any other directory outside the workspace modules can't be loaded in workspace mode.
+			continue
+		}
+
+		i, ok := workspaceRootIdx[ws.path]
+		if !ok {
+			i = len(roots)
+			workspaceRootIdx[ws.path] = i
+			roots = append(roots, loadRoot{Dir: ws.dir(), WorkFile: ws.path})
+		}
+
+		relDir, err := filepath.Rel(ws.dir(), dir)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Rel: %w", err)
+		}
+		roots[i].Patterns = append(roots[i].Patterns, "./"+filepath.ToSlash(filepath.Join(relDir, "...")))
+		roots[i].PkgDirs = append(roots[i].PkgDirs, dir)
+	}
+
+	return roots, nil
+}
+
+// workspace is a parsed go.work file.
+type workspace struct {
+	path       string
+	moduleDirs []string
+}
+
+func (ws *workspace) dir() string {
+	return filepath.Dir(ws.path)
+}
+
+// containsDir checks whether a directory is in one of the workspace modules.
+func (ws *workspace) containsDir(dir string) bool {
+	for _, moduleDir := range ws.moduleDirs {
+		if dir == moduleDir || strings.HasPrefix(dir, moduleDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// workspaceForDir returns the workspace the go command would use in a directory, or nil if it wouldn't use one.
+// Like the go command, this uses $GOWORK if it's set, or otherwise the nearest go.work file in the directory or its parents.
+// Parsed workspaces are memoized by path in workspaces.
+func workspaceForDir(dir string, env []string, workspaces map[string]*workspace) (*workspace, error) {
+	path := envValue(env, "GOWORK")
+	if path == "off" {
+		return nil, nil
+	} else if path == "" || path == "auto" {
+		path = findWorkFile(dir)
+		if path == "" {
+			return nil, nil
+		}
+	}
+
+	if ws, ok := workspaces[path]; ok {
+		return ws, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	workFile, err := modfile.ParseWork(path, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("modfile.ParseWork: %w", err)
+	}
+
+	ws := &workspace{path: path}
+	for _, use := range workFile.Use {
+		moduleDir := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(moduleDir) {
+			moduleDir = filepath.Join(ws.dir(), moduleDir)
+		}
+		ws.moduleDirs = append(ws.moduleDirs, filepath.Clean(moduleDir))
+	}
+
+	workspaces[path] = ws
+	return ws, nil
+}
+
+func findWorkFile(dir string) string {
+	for {
+		path := filepath.Join(dir, "go.work")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return ""
+		}
+		dir = parentDir
+	}
+}
+
+// envValue returns the value of an environment variable in env, or in the current environment if env doesn't set it.
+// Like os/exec, later values in env take precedence.
+func envValue(env []string, key string) string {
+	value := os.Getenv(key)
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			value = v
+		}
+	}
+	return value
+}
@@ -0,0 +1,104 @@
+package list
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/index"
+)
+
+// indexNamespace identifies definition records in the on-disk index.
+// Increment the version whenever the record format changes.
+const indexNamespace = "list-v3"
+
+// loadIndexedPkgDefs loads definitions from the index, typechecking only the packages that aren't indexed yet.
+func loadIndexedPkgDefs(patterns []string, opts Options) ([]pkgDefs, error) {
+	// Loading metadata is much faster than parsing and typechecking,
+	// and it's enough to compute the key for each package.
+	cfg, patterns := packagesConfig(patterns, opts)
+	cfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps
+
+	pkgs, err := opts.Cache.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	if opts.OnlyImports {
+		pkgs = uniqueImports(pkgs)
+	}
+
+	pkgKeys, err := opts.Index.PackageKeys(pkgs, index.EnvSalt(cfg.Env))
+	if err != nil {
+		return nil, err
+	}
+
+	// Definitions depend on the options, so records for different options are stored separately.
+	optsSalt := fmt.Sprintf("fields=%t methods=%t private=%t", opts.IncludeStructFields, opts.IncludeInterfaceMethods, opts.IncludePrivate)
+
+	allPkgDefs := make([]pkgDefs, len(pkgs))
+	recordKeys := make(map[string]string, len(pkgs))
+	missing := make(map[string]int) // Index in allPkgDefs by package ID.
+	for i, pkg := range pkgs {
+		key := index.HashStrings(pkgKeys[pkg.ID], optsSalt)
+		ok, err := opts.Index.Get(indexNamespace, key, &allPkgDefs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			recordKeys[pkg.ID] = key
+			missing[pkg.ID] = i
+		}
+	}
+
+	if len(missing) == 0 {
+		return allPkgDefs, nil
+	}
+
+	// Typecheck only the packages that are missing from the index.
+	// Ad-hoc packages (from a list of files) can't be loaded by path, so fall back to the original patterns.
+	var missingPatterns []string
+	seenPatterns := make(map[string]struct{}, len(missing))
+	for _, pkg := range pkgs {
+		if _, ok := missing[pkg.ID]; !ok {
+			continue
+		}
+
+		if pkg.PkgPath == "command-line-arguments" {
+			missingPatterns = patterns
+			break
+		}
+
+		pattern := index.PatternForPkg(pkg)
+		if _, ok := seenPatterns[pattern]; !ok {
+			seenPatterns[pattern] = struct{}{}
+			missingPatterns = append(missingPatterns, pattern)
+		}
+	}
+
+	cfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+	loadedPkgs, err := opts.Cache.Load(cfg, missingPatterns...)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	for _, pkg := range loadedPkgs {
+		i, ok := missing[pkg.ID]
+		if !ok {
+			continue
+		}
+
+		allPkgDefs[i] = loadPkgDefs(pkg, opts)
+		if err := opts.Index.Put(indexNamespace, recordKeys[pkg.ID], allPkgDefs[i]); err != nil {
+			return nil, err
+		}
+		delete(missing, pkg.ID)
+	}
+
+	for id := range missing {
+		return nil, fmt.Errorf("Could not load package %q", id)
+	}
+
+	return allPkgDefs, nil
+}
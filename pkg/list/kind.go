@@ -0,0 +1,52 @@
+package list
+
+import "fmt"
+
+type DefKind string
+
+const (
+	DefKindConst  = DefKind("const")
+	DefKindVar    = DefKind("var")
+	DefKindFunc   = DefKind("func")
+	DefKindMethod = DefKind("method")
+	DefKindType   = DefKind("type")
+	DefKindField  = DefKind("field")
+)
+
+var AllDefKinds []DefKind
+var AllDefKindStrings []string
+
+func init() {
+	AllDefKinds = []DefKind{
+		DefKindConst,
+		DefKindVar,
+		DefKindFunc,
+		DefKindMethod,
+		DefKindType,
+		DefKindField,
+	}
+	for _, k := range AllDefKinds {
+		AllDefKindStrings = append(AllDefKindStrings, string(k))
+	}
+}
+
+func DefKindFromString(s string) (DefKind, error) {
+	for _, k := range AllDefKindStrings {
+		if s == k {
+			return DefKind(s), nil
+		}
+	}
+	return DefKind(""), fmt.Errorf("Invalid definition kind %q", s)
+}
+
+func DefKindsFromStrings(kindStrings []string) ([]DefKind, error) {
+	kinds := make([]DefKind, 0, len(kindStrings))
+	for _, s := range kindStrings {
+		k, err := DefKindFromString(s)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
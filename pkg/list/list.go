@@ -3,13 +3,18 @@ package list
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
 
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/cache"
 	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
 )
 
 type Options struct {
@@ -18,74 +23,109 @@ type Options struct {
 	IncludePrivate          bool
 	IncludeTests            bool
 	OnlyImports             bool
+
+	// Kinds restricts the result to definitions of these kinds.
+	// If empty, definitions of every kind are included.
+	Kinds []DefKind
+
+	// Dir is the directory used to resolve package patterns.
+	// If empty, patterns are resolved relative to the current working directory.
+	Dir string
+
+	// Build is the build configuration used to load packages.
+	Build buildcfg.Config
+
+	// Overlay replaces the contents of files on disk, such as files with unsaved changes in an editor.
+	// The on-disk index isn't used when there is an overlay, since it's keyed by the contents of files on disk.
+	Overlay file.Overlay
+
+	// Cache reuses loaded packages across calls to List.
+	// If nil, every call loads packages from scratch.
+	Cache *cache.Cache `json:"-"`
+
+	// Index stores definitions on disk, so unchanged packages don't need to be typechecked again.
+	// If nil, definitions are loaded from the packages on every call.
+	Index *index.Index `json:"-"`
 }
 
 type Result struct {
-	Defs []Definition
+	Defs []Definition `json:"defs"`
 }
 
 type Package struct {
-	Name string
-	ID   string
+	Name string `json:"name"`
+	ID   string `json:"id"`
 }
 
 type Definition struct {
+	// Loc is the start of the declaration (the "func" keyword for funcs and methods).
 	file.Loc
-	Name string
-	Pkg  Package
-}
+	Name string  `json:"name"`
+	Kind DefKind `json:"kind"`
+	Pkg  Package `json:"pkg"`
 
-func List(patterns []string, opts Options) (Result, error) {
-	var result Result
+	// Signature is the type of the definition as formatted by go/types,
+	// for example "func(ctx context.Context) error". For type definitions,
+	// this is the underlying type.
+	Signature string `json:"signature"`
 
-	pkgs, err := loadGoPackages(patterns, opts)
-	if err != nil {
-		return result, err
-	}
+	// Receiver is the receiver type for methods, or empty for other kinds of definitions.
+	Receiver string `json:"receiver,omitempty"`
 
-	seenFiles := make(map[string]struct{})
-	for _, pkg := range pkgs {
-		goPaths := make(map[string]struct{}, len(pkg.GoFiles))
-		for _, p := range pkg.GoFiles {
-			goPaths[p] = struct{}{}
-		}
+	Exported bool `json:"exported"`
 
-		for _, astFile := range pkg.Syntax {
-			path := pkg.Fset.Position(astFile.Pos()).Filename
+	// Decl is the full declaration, including the body of a func or the keyword of an unparenthesized const, var, or type declaration.
+	Decl file.Loc `json:"decl"`
 
-			if _, ok := goPaths[path]; !ok {
-				// Likely a compiled file from cgo. Ignore it.
-				continue
-			}
+	// NameLoc is the range of the identifier that names the definition.
+	NameLoc file.Loc `json:"nameLoc"`
+}
 
-			if _, ok := seenFiles[path]; ok {
-				// When opts.IncludeTests is true, the pkgs list will contain both the original pkg
-				// as well as the pkg compiled for tests. Deduplicate the file paths to avoid duplicating
-				// non-test definitions.
-				continue
-			} else {
-				seenFiles[path] = struct{}{}
-			}
+// pkgDefs contains the definitions from a single package.
+type pkgDefs struct {
+	GoFiles []string
+	Defs    []Definition
+}
 
-			ast.Inspect(astFile, func(node ast.Node) bool {
-				switch x := node.(type) {
-				case *ast.ValueSpec:
-					loadDefsFromValueSpec(pkg, opts, x, &result.Defs)
-					return false
+func List(patterns []string, opts Options) (Result, error) {
+	var result Result
 
-				case *ast.TypeSpec:
-					loadDefsFromTypeSpec(pkg, opts, x, &result.Defs)
-					return false
+	var allPkgDefs []pkgDefs
+	if opts.Index != nil && len(opts.Overlay) == 0 {
+		var err error
+		allPkgDefs, err = loadIndexedPkgDefs(patterns, opts)
+		if err != nil {
+			return result, err
+		}
+	} else {
+		pkgs, err := loadGoPackages(patterns, opts)
+		if err != nil {
+			return result, err
+		}
 
-				case *ast.FuncDecl:
-					loadDefsFromFuncDecl(pkg, opts, x, &result.Defs)
-					return false
+		for _, pkg := range pkgs {
+			allPkgDefs = append(allPkgDefs, loadPkgDefs(pkg, opts))
+		}
+	}
 
-				default:
-					return true
-				}
-			})
+	seenFiles := make(map[string]struct{})
+	for _, pd := range allPkgDefs {
+		for _, def := range pd.Defs {
+			// When opts.IncludeTests is true, the pkgs list will contain both the original pkg
+			// as well as the pkg compiled for tests. Deduplicate the file paths to avoid duplicating
+			// non-test definitions.
+			if _, ok := seenFiles[def.Path]; !ok {
+				result.Defs = append(result.Defs, def)
+			}
 		}
+
+		for _, path := range pd.GoFiles {
+			seenFiles[path] = struct{}{}
+		}
+	}
+
+	if len(opts.Kinds) > 0 {
+		result.Defs = filterDefsByKind(result.Defs, opts.Kinds)
 	}
 
 	sort.Slice(result.Defs, func(i, j int) bool {
@@ -104,16 +144,74 @@ func List(patterns []string, opts Options) (Result, error) {
 	return result, nil
 }
 
-func loadGoPackages(patterns []string, opts Options) ([]*packages.Package, error) {
-	cfg := &packages.Config{
-		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes,
-		Tests: opts.IncludeTests,
+func loadPkgDefs(pkg *packages.Package, opts Options) pkgDefs {
+	pd := pkgDefs{GoFiles: pkg.GoFiles}
+
+	goPaths := make(map[string]struct{}, len(pkg.GoFiles))
+	for _, p := range pkg.GoFiles {
+		goPaths[p] = struct{}{}
 	}
 
+	for _, astFile := range pkg.Syntax {
+		path := pkg.Fset.Position(astFile.Pos()).Filename
+
+		if _, ok := goPaths[path]; !ok {
+			// Likely a compiled file from cgo. Ignore it.
+			continue
+		}
+
+		visit := func(def Definition, _ ast.Node) {
+			pd.Defs = append(pd.Defs, def)
+		}
+
+		ast.Inspect(astFile, func(node ast.Node) bool {
+			switch x := node.(type) {
+			case *ast.GenDecl:
+				loadDefsFromGenDecl(pkg, opts, x, visit)
+				return false
+
+			case *ast.FuncDecl:
+				loadDefsFromFuncDecl(pkg, opts, x, visit)
+				return false
+
+			default:
+				return true
+			}
+		})
+	}
+
+	return pd
+}
+
+func loadGoPackages(patterns []string, opts Options) ([]*packages.Package, error) {
+	cfg, patterns := packagesConfig(patterns, opts)
+	cfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
 	if opts.OnlyImports {
 		cfg.Mode |= (packages.NeedImports | packages.NeedDeps)
 	}
 
+	pkgs, err := opts.Cache.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	if opts.OnlyImports {
+		pkgs = uniqueImports(pkgs)
+	}
+
+	return pkgs, nil
+}
+
+// packagesConfig returns the config and patterns for loading packages, without setting the load mode.
+func packagesConfig(patterns []string, opts Options) (*packages.Config, []string) {
+	cfg := &packages.Config{
+		Dir:     opts.Dir,
+		Tests:   opts.IncludeTests,
+		Env:     opts.Build.Env(),
+		Overlay: opts.Overlay,
+	}
+
 	// Workaround for a quirk of the Go build system.
 	// When specifying a package using "file=" syntax, the result differs depending
 	// on whether the current working directory is inside the Go module.
@@ -123,24 +221,31 @@ func loadGoPackages(patterns []string, opts Options) ([]*packages.Package, error
 	// the requested file to guarantee that the current working directory is in the module.
 	if len(patterns) == 1 && strings.HasPrefix(patterns[0], "file=") {
 		_, path, _ := strings.Cut(patterns[0], "=")
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(opts.Dir, path)
+		}
 		cfg.Dir = filepath.Dir(path)
 
 		// Since golang.org/x/tools v0.35.0 the file is resolved
 		// relative to cfg.Dir, so rewrite the query relative to cfg.Dir.
 		// https://github.com/golang/tools/commit/f0ace1320aba7feb36c16f76453de42390c9f772
-		patterns[0] = fmt.Sprintf("file=%s", filepath.Base(path))
+		patterns = []string{fmt.Sprintf("file=%s", filepath.Base(path))}
 	}
 
-	pkgs, err := packages.Load(cfg, patterns...)
-	if err != nil {
-		return nil, fmt.Errorf("packages.Load: %w", err)
-	}
+	return cfg, patterns
+}
 
-	if opts.OnlyImports {
-		pkgs = uniqueImports(pkgs)
+func filterDefsByKind(defs []Definition, kinds []DefKind) []Definition {
+	filtered := defs[:0]
+	for _, def := range defs {
+		for _, k := range kinds {
+			if def.Kind == k {
+				filtered = append(filtered, def)
+				break
+			}
+		}
 	}
-
-	return pkgs, nil
+	return filtered
 }
 
 func uniqueImports(pkgs []*packages.Package) []*packages.Package {
@@ -165,132 +270,178 @@ func uniqueImports(pkgs []*packages.Package) []*packages.Package {
 	return result
 }
 
-func loadDefsFromValueSpec(pkg *packages.Package, opts Options, valueSpec *ast.ValueSpec, defs *[]Definition) {
-	position := pkg.Fset.Position(valueSpec.Pos())
+// defVisitor receives each definition found in a file, along with the AST node that declares it.
+type defVisitor func(def Definition, node ast.Node)
+
+func loadDefsFromGenDecl(pkg *packages.Package, opts Options, genDecl *ast.GenDecl, visit defVisitor) {
+	for _, spec := range genDecl.Specs {
+		specVisit := func(def Definition, node ast.Node) {
+			// Without parentheses, the keyword is part of the declaration.
+			if node == spec && !genDecl.Lparen.IsValid() {
+				def.Decl = locForNode(pkg, genDecl)
+			}
+			visit(def, node)
+		}
+
+		switch spec := spec.(type) {
+		case *ast.ValueSpec:
+			loadDefsFromValueSpec(pkg, opts, genDecl.Tok, spec, specVisit)
+		case *ast.TypeSpec:
+			loadDefsFromTypeSpec(pkg, opts, spec, specVisit)
+		}
+	}
+}
+
+func loadDefsFromValueSpec(pkg *packages.Package, opts Options, tok token.Token, valueSpec *ast.ValueSpec, visit defVisitor) {
+	kind := DefKindVar
+	if tok == token.CONST {
+		kind = DefKindConst
+	}
+
 	for _, nameIdent := range valueSpec.Names {
 		if nameIdent != nil && (opts.IncludePrivate || nameIdent.IsExported()) {
 			valueName := nameIdent.Name
-			*defs = append(*defs, Definition{
+			visit(Definition{
 				Name: valueName,
+				Kind: kind,
 				Pkg: Package{
 					ID:   pkg.ID,
 					Name: pkg.Name,
 				},
-				Loc: file.Loc{
-					Path:   position.Filename,
-					Line:   position.Line,
-					Column: position.Column,
-				},
-			})
+				Loc:       locForPos(pkg, valueSpec.Pos()),
+				NameLoc:   locForNode(pkg, nameIdent),
+				Decl:      locForNode(pkg, valueSpec),
+				Signature: signatureForIdent(pkg, nameIdent),
+				Exported:  nameIdent.IsExported(),
+			}, valueSpec)
 		}
 	}
 }
 
-func loadDefsFromTypeSpec(pkg *packages.Package, opts Options, typeSpec *ast.TypeSpec, defs *[]Definition) {
+func loadDefsFromTypeSpec(pkg *packages.Package, opts Options, typeSpec *ast.TypeSpec, visit defVisitor) {
 	if typeSpec.Name == nil || (!opts.IncludePrivate && !typeSpec.Name.IsExported()) {
 		return
 	}
 
-	position := pkg.Fset.Position(typeSpec.Pos())
 	typeName := typeSpec.Name.Name
 
 	switch x := typeSpec.Type.(type) {
 	case *ast.StructType:
-		loadDefsFromStructType(pkg, opts, typeName, x, defs)
+		loadDefsFromStructType(pkg, opts, typeName, x, visit)
 	case *ast.InterfaceType:
-		loadDefsFromInterfaceType(pkg, opts, typeName, x, defs)
+		loadDefsFromInterfaceType(pkg, opts, typeName, x, visit)
 	}
 
-	*defs = append(*defs, Definition{
+	visit(Definition{
 		Name: typeName,
+		Kind: DefKindType,
 		Pkg: Package{
 			ID:   pkg.ID,
 			Name: pkg.Name,
 		},
-
-		Loc: file.Loc{
-			Path:   position.Filename,
-			Line:   position.Line,
-			Column: position.Column,
-		},
-	})
+		Loc:       locForPos(pkg, typeSpec.Pos()),
+		NameLoc:   locForNode(pkg, typeSpec.Name),
+		Decl:      locForNode(pkg, typeSpec),
+		Signature: signatureForIdent(pkg, typeSpec.Name),
+		Exported:  typeSpec.Name.IsExported(),
+	}, typeSpec)
 }
 
-func loadDefsFromStructType(pkg *packages.Package, opts Options, typeName string, structType *ast.StructType, defs *[]Definition) {
+func loadDefsFromStructType(pkg *packages.Package, opts Options, typeName string, structType *ast.StructType, visit defVisitor) {
 	if !opts.IncludeStructFields {
 		return
 	}
 
 	for _, field := range structType.Fields.List {
-		position := pkg.Fset.Position(field.Pos())
 		for _, nameIdent := range field.Names {
 			if nameIdent != nil && (opts.IncludePrivate || nameIdent.IsExported()) {
 				fieldName := nameIdent.Name
-				*defs = append(*defs, Definition{
+				visit(Definition{
 					Name: fmt.Sprintf("%s.%s", typeName, fieldName),
+					Kind: DefKindField,
 					Pkg: Package{
 						ID:   pkg.ID,
 						Name: pkg.Name,
 					},
-					Loc: file.Loc{
-						Path:   position.Filename,
-						Line:   position.Line,
-						Column: position.Column,
-					},
-				})
+					Loc:       locForPos(pkg, field.Pos()),
+					NameLoc:   locForNode(pkg, nameIdent),
+					Decl:      locForNode(pkg, field),
+					Signature: signatureForIdent(pkg, nameIdent),
+					Exported:  nameIdent.IsExported(),
+				}, field)
 			}
 		}
 	}
 }
 
-func loadDefsFromInterfaceType(pkg *packages.Package, opts Options, typeName string, interfaceType *ast.InterfaceType, defs *[]Definition) {
+func loadDefsFromInterfaceType(pkg *packages.Package, opts Options, typeName string, interfaceType *ast.InterfaceType, visit defVisitor) {
 	if !opts.IncludeInterfaceMethods {
 		return
 	}
 
 	for _, method := range interfaceType.Methods.List {
-		position := pkg.Fset.Position(method.Pos())
 		for _, nameIdent := range method.Names {
 			if nameIdent != nil && (opts.IncludePrivate || nameIdent.IsExported()) {
 				methodName := nameIdent.Name
-				*defs = append(*defs, Definition{
+				visit(Definition{
 					Name: fmt.Sprintf("%s.%s", typeName, methodName),
+					Kind: DefKindMethod,
 					Pkg: Package{
 						ID:   pkg.ID,
 						Name: pkg.Name,
 					},
-					Loc: file.Loc{
-						Path:   position.Filename,
-						Line:   position.Line,
-						Column: position.Column,
-					},
-				})
+					Loc:       locForPos(pkg, method.Pos()),
+					NameLoc:   locForNode(pkg, nameIdent),
+					Decl:      locForNode(pkg, method),
+					Signature: signatureForIdent(pkg, nameIdent),
+					Receiver:  typeName,
+					Exported:  nameIdent.IsExported(),
+				}, method)
 			}
 		}
 	}
 }
 
-func loadDefsFromFuncDecl(pkg *packages.Package, opts Options, funcDecl *ast.FuncDecl, defs *[]Definition) {
+func loadDefsFromFuncDecl(pkg *packages.Package, opts Options, funcDecl *ast.FuncDecl, visit defVisitor) {
 	if funcDecl.Name == nil || (!opts.IncludePrivate && !funcDecl.Name.IsExported()) {
 		return
 	}
-	position := pkg.Fset.Position(funcDecl.Pos())
 	name := funcDecl.Name.Name
+	kind := DefKindFunc
+	var receiver string
 	if funcDecl.Recv != nil {
 		name = fmt.Sprintf("%s.%s", findFuncRecvName(funcDecl), name)
+		kind = DefKindMethod
+		receiver = receiverForFuncIdent(pkg, funcDecl.Name)
 	}
-	*defs = append(*defs, Definition{
+	visit(Definition{
 		Name: name,
+		Kind: kind,
 		Pkg: Package{
 			ID:   pkg.ID,
 			Name: pkg.Name,
 		},
-		Loc: file.Loc{
-			Path:   position.Filename,
-			Line:   position.Line,
-			Column: position.Column,
-		},
-	})
+		Loc:       locForPos(pkg, funcDecl.Pos()),
+		NameLoc:   locForNode(pkg, funcDecl.Name),
+		Decl:      locForNode(pkg, funcDecl),
+		Signature: signatureForIdent(pkg, funcDecl.Name),
+		Receiver:  receiver,
+		Exported:  funcDecl.Name.IsExported(),
+	}, funcDecl)
+}
+
+// locForPos returns the location of a single position, without an end.
+func locForPos(pkg *packages.Package, pos token.Pos) file.Loc {
+	position := pkg.Fset.Position(pos)
+	return file.Loc{Path: position.Filename, Line: position.Line, Column: position.Column, Offset: position.Offset}
+}
+
+func locForRange(pkg *packages.Package, start token.Pos, end token.Pos) file.Loc {
+	return file.LocForPositions(pkg.Fset.Position(start), pkg.Fset.Position(end))
+}
+
+func locForNode(pkg *packages.Package, node ast.Node) file.Loc {
+	return locForRange(pkg, node.Pos(), node.End())
 }
 
 func findFuncRecvName(funcDecl *ast.FuncDecl) string {
@@ -306,3 +457,51 @@ func findFuncRecvName(funcDecl *ast.FuncDecl) string {
 	}
 	return typeName
 }
+
+// signatureForIdent formats the type of the object defined by an identifier.
+// It returns an empty string if type information isn't available (for example, if the package has errors).
+func signatureForIdent(pkg *packages.Package, ident *ast.Ident) string {
+	obj := typesObjForIdent(pkg, ident)
+	if obj == nil {
+		return ""
+	}
+
+	t := obj.Type()
+	if _, ok := obj.(*types.TypeName); ok {
+		t = t.Underlying()
+	}
+
+	return types.TypeString(t, qualifierForPkg(pkg.Types))
+}
+
+func receiverForFuncIdent(pkg *packages.Package, ident *ast.Ident) string {
+	obj := typesObjForIdent(pkg, ident)
+	if obj == nil {
+		return ""
+	}
+
+	sig, ok := obj.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return ""
+	}
+
+	return types.TypeString(sig.Recv().Type(), qualifierForPkg(pkg.Types))
+}
+
+func typesObjForIdent(pkg *packages.Package, ident *ast.Ident) types.Object {
+	if pkg.TypesInfo == nil {
+		return nil
+	}
+	return pkg.TypesInfo.Defs[ident]
+}
+
+// qualifierForPkg omits the package for types in the current package,
+// and uses the package name (rather than the full import path) for everything else.
+func qualifierForPkg(pkg *types.Package) types.Qualifier {
+	return func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	}
+}
@@ -1,6 +1,7 @@
 package list
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
 )
 
 func TestList(t *testing.T) {
@@ -43,11 +45,11 @@ func TestList(t *testing.T) {
 			opts:     Options{},
 			expected: Result{
 				Defs: []Definition{
-					{Name: "MyVar", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}},
-					{Name: "MyConst", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}},
-					{Name: "MyStruct", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}},
-					{Name: "MyInterface", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}},
-					{Name: "MyFunc", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}},
+					{Name: "MyVar", Kind: DefKindVar, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}, Signature: "string", Exported: true},
+					{Name: "MyConst", Kind: DefKindConst, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}, Signature: "untyped int", Exported: true},
+					{Name: "MyStruct", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}, Signature: "struct{MyField string; privateField int}", Exported: true},
+					{Name: "MyInterface", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}, Signature: "interface{String() string}", Exported: true},
+					{Name: "MyFunc", Kind: DefKindFunc, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}, Signature: "func() string", Exported: true},
 				},
 			},
 		},
@@ -58,12 +60,12 @@ func TestList(t *testing.T) {
 			opts:     Options{IncludeStructFields: true},
 			expected: Result{
 				Defs: []Definition{
-					{Name: "MyVar", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}},
-					{Name: "MyConst", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}},
-					{Name: "MyStruct", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}},
-					{Name: "MyStruct.MyField", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 8, Column: 2}},
-					{Name: "MyInterface", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}},
-					{Name: "MyFunc", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}},
+					{Name: "MyVar", Kind: DefKindVar, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}, Signature: "string", Exported: true},
+					{Name: "MyConst", Kind: DefKindConst, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}, Signature: "untyped int", Exported: true},
+					{Name: "MyStruct", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}, Signature: "struct{MyField string; privateField int}", Exported: true},
+					{Name: "MyStruct.MyField", Kind: DefKindField, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 8, Column: 2}, Signature: "string", Exported: true},
+					{Name: "MyInterface", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}, Signature: "interface{String() string}", Exported: true},
+					{Name: "MyFunc", Kind: DefKindFunc, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}, Signature: "func() string", Exported: true},
 				},
 			},
 		},
@@ -74,12 +76,12 @@ func TestList(t *testing.T) {
 			opts:     Options{IncludeInterfaceMethods: true},
 			expected: Result{
 				Defs: []Definition{
-					{Name: "MyVar", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}},
-					{Name: "MyConst", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}},
-					{Name: "MyStruct", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}},
-					{Name: "MyInterface", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}},
-					{Name: "MyInterface.String", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 18, Column: 2}},
-					{Name: "MyFunc", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}},
+					{Name: "MyVar", Kind: DefKindVar, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}, Signature: "string", Exported: true},
+					{Name: "MyConst", Kind: DefKindConst, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}, Signature: "untyped int", Exported: true},
+					{Name: "MyStruct", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}, Signature: "struct{MyField string; privateField int}", Exported: true},
+					{Name: "MyInterface", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}, Signature: "interface{String() string}", Exported: true},
+					{Name: "MyInterface.String", Kind: DefKindMethod, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 18, Column: 2}, Signature: "func() string", Receiver: "MyInterface", Exported: true},
+					{Name: "MyFunc", Kind: DefKindFunc, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}, Signature: "func() string", Exported: true},
 				},
 			},
 		},
@@ -90,17 +92,17 @@ func TestList(t *testing.T) {
 			opts:     Options{IncludePrivate: true, IncludeStructFields: true},
 			expected: Result{
 				Defs: []Definition{
-					{Name: "MyVar", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}},
-					{Name: "MyConst", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}},
-					{Name: "MyStruct", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}},
-					{Name: "MyStruct.MyField", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 8, Column: 2}},
-					{Name: "MyStruct.privateField", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 9, Column: 2}},
-					{Name: "privateStruct", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 12, Column: 6}},
-					{Name: "privateStruct.PublicField", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 13, Column: 2}},
-					{Name: "privateStruct.privateField", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 14, Column: 2}},
-					{Name: "MyInterface", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}},
-					{Name: "MyFunc", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}},
-					{Name: "privateFunc", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 25, Column: 1}},
+					{Name: "MyVar", Kind: DefKindVar, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}, Signature: "string", Exported: true},
+					{Name: "MyConst", Kind: DefKindConst, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}, Signature: "untyped int", Exported: true},
+					{Name: "MyStruct", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}, Signature: "struct{MyField string; privateField int}", Exported: true},
+					{Name: "MyStruct.MyField", Kind: DefKindField, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 8, Column: 2}, Signature: "string", Exported: true},
+					{Name: "MyStruct.privateField", Kind: DefKindField, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 9, Column: 2}, Signature: "int", Exported: false},
+					{Name: "privateStruct", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 12, Column: 6}, Signature: "struct{PublicField int; privateField int}", Exported: false},
+					{Name: "privateStruct.PublicField", Kind: DefKindField, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 13, Column: 2}, Signature: "int", Exported: true},
+					{Name: "privateStruct.privateField", Kind: DefKindField, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 14, Column: 2}, Signature: "int", Exported: false},
+					{Name: "MyInterface", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}, Signature: "interface{String() string}", Exported: true},
+					{Name: "MyFunc", Kind: DefKindFunc, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}, Signature: "func() string", Exported: true},
+					{Name: "privateFunc", Kind: DefKindFunc, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 25, Column: 1}, Signature: "func() string", Exported: false},
 				},
 			},
 		},
@@ -111,12 +113,12 @@ func TestList(t *testing.T) {
 			opts:     Options{IncludeTests: true},
 			expected: Result{
 				Defs: []Definition{
-					{Name: "MyVar", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}},
-					{Name: "MyConst", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}},
-					{Name: "MyStruct", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}},
-					{Name: "MyInterface", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}},
-					{Name: "MyFunc", Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}},
-					{Name: "TestStruct", Pkg: testPkg, Loc: file.Loc{Path: pkgPath("defs_test.go"), Line: 3, Column: 6}},
+					{Name: "MyVar", Kind: DefKindVar, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 3, Column: 5}, Signature: "string", Exported: true},
+					{Name: "MyConst", Kind: DefKindConst, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 5, Column: 7}, Signature: "untyped int", Exported: true},
+					{Name: "MyStruct", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 7, Column: 6}, Signature: "struct{MyField string; privateField int}", Exported: true},
+					{Name: "MyInterface", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 17, Column: 6}, Signature: "interface{String() string}", Exported: true},
+					{Name: "MyFunc", Kind: DefKindFunc, Pkg: pkg, Loc: file.Loc{Path: pkgPath("defs.go"), Line: 21, Column: 1}, Signature: "func() string", Exported: true},
+					{Name: "TestStruct", Kind: DefKindType, Pkg: testPkg, Loc: file.Loc{Path: pkgPath("defs_test.go"), Line: 3, Column: 6}, Signature: "struct{}", Exported: true},
 				},
 			},
 		},
@@ -127,7 +129,94 @@ func TestList(t *testing.T) {
 			withWorkingDir(t, tc.dir, func(t *testing.T) {
 				result, err := List(tc.patterns, tc.opts)
 				require.NoError(t, err)
-				assert.Equal(t, tc.expected, result)
+				assert.Equal(t, tc.expected, withoutRanges(result))
+			})
+		})
+	}
+}
+
+func TestListSignatures(t *testing.T) {
+	pkg := Package{
+		Name: "testmodule005",
+		ID:   "github.com/wedaly/gospelunk/pkg/list/testdata/testmodule005",
+	}
+
+	path, err := filepath.Abs(filepath.Join("testdata", "testmodule005", "server.go"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		opts     Options
+		expected Result
+	}{
+		{
+			name: "all kinds",
+			opts: Options{IncludeStructFields: true, IncludeInterfaceMethods: true},
+			expected: Result{
+				Defs: []Definition{
+					{Name: "Server", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: path, Line: 5, Column: 6}, Signature: "struct{Addr string}", Exported: true},
+					{Name: "Server.Addr", Kind: DefKindField, Pkg: pkg, Loc: file.Loc{Path: path, Line: 6, Column: 2}, Signature: "string", Exported: true},
+					{Name: "NewServer", Kind: DefKindFunc, Pkg: pkg, Loc: file.Loc{Path: path, Line: 9, Column: 1}, Signature: "func(addr string) *Server", Exported: true},
+					{Name: "Server.Run", Kind: DefKindMethod, Pkg: pkg, Loc: file.Loc{Path: path, Line: 13, Column: 1}, Signature: "func(ctx context.Context) error", Receiver: "*Server", Exported: true},
+					{Name: "Server.String", Kind: DefKindMethod, Pkg: pkg, Loc: file.Loc{Path: path, Line: 17, Column: 1}, Signature: "func() string", Receiver: "Server", Exported: true},
+					{Name: "Handler", Kind: DefKindType, Pkg: pkg, Loc: file.Loc{Path: path, Line: 21, Column: 6}, Signature: "interface{Handle(ctx context.Context) error}", Exported: true},
+					{Name: "Handler.Handle", Kind: DefKindMethod, Pkg: pkg, Loc: file.Loc{Path: path, Line: 22, Column: 2}, Signature: "func(ctx context.Context) error", Receiver: "Handler", Exported: true},
+					{Name: "DefaultAddr", Kind: DefKindConst, Pkg: pkg, Loc: file.Loc{Path: path, Line: 25, Column: 7}, Signature: "untyped string", Exported: true},
+				},
+			},
+		},
+		{
+			name: "filter by kind",
+			opts: Options{IncludeStructFields: true, IncludeInterfaceMethods: true, Kinds: []DefKind{DefKindMethod, DefKindConst}},
+			expected: Result{
+				Defs: []Definition{
+					{Name: "Server.Run", Kind: DefKindMethod, Pkg: pkg, Loc: file.Loc{Path: path, Line: 13, Column: 1}, Signature: "func(ctx context.Context) error", Receiver: "*Server", Exported: true},
+					{Name: "Server.String", Kind: DefKindMethod, Pkg: pkg, Loc: file.Loc{Path: path, Line: 17, Column: 1}, Signature: "func() string", Receiver: "Server", Exported: true},
+					{Name: "Handler.Handle", Kind: DefKindMethod, Pkg: pkg, Loc: file.Loc{Path: path, Line: 22, Column: 2}, Signature: "func(ctx context.Context) error", Receiver: "Handler", Exported: true},
+					{Name: "DefaultAddr", Kind: DefKindConst, Pkg: pkg, Loc: file.Loc{Path: path, Line: 25, Column: 7}, Signature: "untyped string", Exported: true},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			withWorkingDir(t, "testdata/testmodule005", func(t *testing.T) {
+				result, err := List([]string{"."}, tc.opts)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, withoutRanges(result))
+			})
+		})
+	}
+}
+
+func TestListWithIndex(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts Options
+	}{
+		{name: "public only", opts: Options{}},
+		{name: "include private", opts: Options{IncludePrivate: true, IncludeStructFields: true, IncludeInterfaceMethods: true}},
+		{name: "include tests", opts: Options{IncludeTests: true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			withWorkingDir(t, "testdata/testmodule001", func(t *testing.T) {
+				expected, err := List([]string{"./..."}, tc.opts)
+				require.NoError(t, err)
+
+				idx, err := index.Open(t.TempDir())
+				require.NoError(t, err)
+				opts := tc.opts
+				opts.Index = idx
+
+				// The first call populates the index, and the second reads from it.
+				for i := 0; i < 2; i++ {
+					result, err := List([]string{"./..."}, opts)
+					require.NoError(t, err)
+					assert.Equal(t, expected, result)
+				}
 			})
 		})
 	}
@@ -145,20 +234,30 @@ func TestListWithCGo(t *testing.T) {
 		expected := Result{
 			Defs: []Definition{
 				{
-					Loc:  file.Loc{Path: cgoPath, Line: 6, Column: 6},
+					Loc:  file.Loc{Path: cgoPath, Line: 6, Column: 6, Offset: 62},
 					Name: "MyStruct",
+					Kind: DefKindType,
 					Pkg: Package{
 						Name: "testmodule002",
 						ID:   "github.com/wedaly/gospelunk/pkg/list/testdata/testmodule002",
 					},
+					Signature: "struct{}",
+					Exported:  true,
+					Decl:      file.Loc{Path: cgoPath, Line: 6, Column: 1, Offset: 57, End: file.Pos{Line: 6, Column: 23, Offset: 79}},
+					NameLoc:   file.Loc{Path: cgoPath, Line: 6, Column: 6, Offset: 62, End: file.Pos{Line: 6, Column: 14, Offset: 70}},
 				},
 				{
-					Loc:  file.Loc{Path: cgoPath, Line: 8, Column: 1},
+					Loc:  file.Loc{Path: cgoPath, Line: 8, Column: 1, Offset: 81},
 					Name: "Random",
+					Kind: DefKindFunc,
 					Pkg: Package{
 						Name: "testmodule002",
 						ID:   "github.com/wedaly/gospelunk/pkg/list/testdata/testmodule002",
 					},
+					Signature: "func() int",
+					Exported:  true,
+					Decl:      file.Loc{Path: cgoPath, Line: 8, Column: 1, Offset: 81, End: file.Pos{Line: 10, Column: 2, Offset: 126}},
+					NameLoc:   file.Loc{Path: cgoPath, Line: 8, Column: 6, Offset: 86, End: file.Pos{Line: 8, Column: 12, Offset: 92}},
 				},
 			},
 		}
@@ -197,6 +296,85 @@ func TestListFileOutsideGoModule(t *testing.T) {
 	})
 }
 
+func TestListWithOverlay(t *testing.T) {
+	withWorkingDir(t, "testdata/testmodule004", func(t *testing.T) {
+		secondPath, err := filepath.Abs("second.go")
+		require.NoError(t, err)
+
+		idx, err := index.Open(t.TempDir())
+		require.NoError(t, err)
+
+		// Populate the index from the files on disk, which should be ignored when there is an overlay.
+		_, err = List([]string{"."}, Options{Index: idx})
+		require.NoError(t, err)
+
+		overlay := file.Overlay{
+			secondPath: []byte("package testmodule004\n\n// Unsaved change.\nfunc RenamedFunc() {}\n"),
+		}
+		result, err := List([]string{"."}, Options{Overlay: overlay, Index: idx})
+		require.NoError(t, err)
+
+		var defLocs []string
+		for _, def := range result.Defs {
+			defLocs = append(defLocs, fmt.Sprintf("%s %s:%d", def.Name, filepath.Base(def.Path), def.Line))
+		}
+		assert.Equal(t, []string{"FirstFunc first.go:5", "RenamedFunc second.go:4"}, defLocs)
+	})
+}
+
+func TestOutline(t *testing.T) {
+	result, err := Outline("testdata/testmodule006/outline.go", Options{})
+	require.NoError(t, err)
+
+	var lines []string
+	for _, node := range result.Flatten() {
+		lines = append(lines, fmt.Sprintf("%s%s %s %d:%d-%d:%d name %d:%d-%d:%d", node.Indent(), node.Name, node.Kind,
+			node.Decl.Line, node.Decl.Column, node.Decl.End.Line, node.Decl.End.Column,
+			node.NameLoc.Line, node.NameLoc.Column, node.NameLoc.End.Line, node.NameLoc.End.Column))
+	}
+
+	expected := []string{
+		"Stack type 9:1-12:2 name 9:6-9:11",
+		"  Stack.items field 10:2-10:13 name 10:2-10:7",
+		"  Stack.Name field 11:2-11:14 name 11:2-11:6",
+		"  Stack.Push method 5:1-7:2 name 5:17-5:21",
+		"  Stack.Size method 25:1-27:2 name 25:17-25:21",
+		"const const 14:1-17:2 name 14:1-14:6",
+		"  Small const 15:2-15:11 name 15:2-15:7",
+		"  Large const 16:2-16:11 name 16:2-16:7",
+		"Default var 19:1-19:37 name 19:5-19:12",
+		"Sizer type 21:1-23:2 name 21:6-21:11",
+		"  Sizer.Size method 22:2-22:12 name 22:2-22:6",
+		"Describe func 29:1-40:2 name 29:6-29:14",
+		"  summary type 30:2-32:3 name 30:7-30:14",
+		"    summary.size field 31:3-31:11 name 31:3-31:7",
+		"  format func 34:2-37:3 name 34:2-34:8",
+		"    prefix func 35:3-35:44 name 35:3-35:9",
+	}
+	assert.Equal(t, expected, lines)
+
+	// Groups, and definitions local to a func, aren't exported.
+	assert.True(t, result.Nodes[1].Group)
+	assert.False(t, result.Nodes[1].Exported)
+	assert.False(t, result.Nodes[4].Children[0].Exported)
+	assert.Equal(t, "func(sum summary) string", result.Nodes[4].Children[1].Signature)
+}
+
+func TestOutlineFileNotFound(t *testing.T) {
+	_, err := Outline("testdata/testmodule006/missing.go", Options{})
+	assert.Error(t, err)
+}
+
+// withoutRanges keeps only the start of each definition's location, for tests that don't check ranges.
+func withoutRanges(result Result) Result {
+	for i, def := range result.Defs {
+		result.Defs[i].Loc = def.Loc.Start()
+		result.Defs[i].Decl = file.Loc{}
+		result.Defs[i].NameLoc = file.Loc{}
+	}
+	return result
+}
+
 func withWorkingDir(t *testing.T, dir string, f func(t *testing.T)) {
 	oldWd, err := os.Getwd()
 	require.NoError(t, err)
@@ -0,0 +1,339 @@
+package list
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+type OutlineResult struct {
+	Nodes []OutlineNode `json:"nodes"`
+}
+
+// OutlineNode is a definition in a file, with the definitions nested inside it.
+// The Decl of each node is the range for folding.
+type OutlineNode struct {
+	Definition
+
+	// Group is true for a parenthesized const, var, or type declaration.
+	// The specs in the declaration are its children.
+	Group bool `json:"group,omitempty"`
+
+	// Depth is the number of ancestors of the node in the outline.
+	Depth int `json:"depth"`
+
+	Children []OutlineNode `json:"children,omitempty"`
+}
+
+// Indent returns two spaces for every ancestor of the node, for formatting an outline as text.
+func (n OutlineNode) Indent() string {
+	return strings.Repeat("  ", n.Depth)
+}
+
+// Flatten returns every node in the outline in pre-order.
+func (r OutlineResult) Flatten() []OutlineNode {
+	var result []OutlineNode
+	var visit func(nodes []OutlineNode)
+	visit = func(nodes []OutlineNode) {
+		for _, n := range nodes {
+			result = append(result, n)
+			visit(n.Children)
+		}
+	}
+	visit(r.Nodes)
+	return result
+}
+
+// Outline returns the definitions in a single Go file as a tree.
+// Types contain their fields, interface methods, and methods declared in the same file,
+// funcs contain their local closures and type declarations,
+// and parenthesized const, var, and type declarations contain their specs.
+// Every definition is included, so only the Dir and Cache options are used.
+func Outline(path string, opts Options) (OutlineResult, error) {
+	var result OutlineResult
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(opts.Dir, path)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return result, fmt.Errorf("filepath.Abs: %w", err)
+	}
+
+	// Outline the file even if the build configuration excludes it.
+	build, err := buildcfg.ConfigForFile(absPath, opts.Build, opts.Overlay)
+	if err != nil {
+		return result, err
+	}
+
+	cfg, patterns := packagesConfig([]string{fmt.Sprintf("file=%s", absPath)}, Options{
+		Dir:          opts.Dir,
+		IncludeTests: strings.HasSuffix(absPath, "_test.go"),
+		Build:        build,
+		Overlay:      opts.Overlay,
+	})
+	cfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+	pkgs, err := opts.Cache.Load(cfg, patterns...)
+	if err != nil {
+		return result, fmt.Errorf("packages.Load: %w", err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, astFile := range pkg.Syntax {
+			if pkg.Fset.Position(astFile.Pos()).Filename == absPath {
+				b := &outlineBuilder{pkg: pkg}
+				result.Nodes = b.fileNodes(astFile)
+				return result, nil
+			}
+		}
+	}
+
+	return result, fmt.Errorf("Could not find Go package for file %s", path)
+}
+
+// outlineOpts includes every kind of definition in the outline.
+var outlineOpts = Options{
+	IncludeStructFields:     true,
+	IncludeInterfaceMethods: true,
+	IncludePrivate:          true,
+}
+
+type outlineBuilder struct {
+	pkg *packages.Package
+}
+
+func (b *outlineBuilder) fileNodes(astFile *ast.File) []OutlineNode {
+	var nodes []OutlineNode
+
+	// Methods are grouped under their receiver type if the type is declared in the same file,
+	// even if the method is declared before the type.
+	methodsByRecv := make(map[string][]OutlineNode)
+	typeNames := make(map[string]struct{})
+	for _, decl := range astFile.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.TYPE {
+			for _, spec := range genDecl.Specs {
+				typeNames[spec.(*ast.TypeSpec).Name.Name] = struct{}{}
+			}
+		}
+	}
+
+	for _, decl := range astFile.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			nodes = append(nodes, b.genDeclNodes(decl)...)
+
+		case *ast.FuncDecl:
+			var node OutlineNode
+			loadDefsFromFuncDecl(b.pkg, outlineOpts, decl, func(def Definition, _ ast.Node) {
+				node = OutlineNode{Definition: def}
+			})
+
+			if decl.Body != nil {
+				node.Children = b.localNodes(decl.Body)
+			}
+
+			if decl.Recv != nil {
+				recvName := findFuncRecvName(decl)
+				if _, ok := typeNames[recvName]; ok {
+					methodsByRecv[recvName] = append(methodsByRecv[recvName], node)
+					continue
+				}
+			}
+
+			nodes = append(nodes, node)
+		}
+	}
+
+	attachMethods(nodes, methodsByRecv)
+	setDepth(nodes, 0)
+	return nodes
+}
+
+// genDeclNodes returns the nodes for the specs in a const, var, or type declaration.
+// Types have their fields or interface methods as children.
+// If the declaration is parenthesized, the specs are grouped under a single node.
+func (b *outlineBuilder) genDeclNodes(genDecl *ast.GenDecl) []OutlineNode {
+	var nodes, members []OutlineNode
+	loadDefsFromGenDecl(b.pkg, outlineOpts, genDecl, func(def Definition, n ast.Node) {
+		switch n.(type) {
+		case *ast.ValueSpec:
+			nodes = append(nodes, OutlineNode{Definition: def})
+		case *ast.TypeSpec:
+			// Fields and interface methods are visited before their type.
+			nodes = append(nodes, OutlineNode{Definition: def, Children: members})
+			members = nil
+		default:
+			members = append(members, OutlineNode{Definition: def})
+		}
+	})
+
+	if genDecl.Lparen.IsValid() {
+		return []OutlineNode{b.groupNode(genDecl, nodes)}
+	}
+	return nodes
+}
+
+func (b *outlineBuilder) groupNode(genDecl *ast.GenDecl, children []OutlineNode) OutlineNode {
+	kind := DefKindVar
+	switch genDecl.Tok {
+	case token.CONST:
+		kind = DefKindConst
+	case token.TYPE:
+		kind = DefKindType
+	}
+
+	keyword := genDecl.Tok.String()
+	return OutlineNode{
+		Definition: Definition{
+			Name: keyword,
+			Kind: kind,
+			Pkg: Package{
+				ID:   b.pkg.ID,
+				Name: b.pkg.Name,
+			},
+			Loc:     locForPos(b.pkg, genDecl.Pos()),
+			Decl:    locForNode(b.pkg, genDecl),
+			NameLoc: locForRange(b.pkg, genDecl.Pos(), genDecl.Pos()+token.Pos(len(keyword))),
+		},
+		Group:    true,
+		Children: children,
+	}
+}
+
+// localNodes returns the nodes for closures assigned to local variables and local type declarations in a func body.
+// Each closure contains the local definitions in its own body.
+func (b *outlineBuilder) localNodes(body *ast.BlockStmt) []OutlineNode {
+	var nodes []OutlineNode
+	closures := make(map[*ast.FuncLit]struct{})
+
+	addClosure := func(ident *ast.Ident, funcLit *ast.FuncLit, stmt ast.Node) {
+		if ident.Name == "_" {
+			return
+		}
+		closures[funcLit] = struct{}{}
+
+		nodes = append(nodes, OutlineNode{
+			Definition: Definition{
+				Name: ident.Name,
+				Kind: DefKindFunc,
+				Pkg: Package{
+					ID:   b.pkg.ID,
+					Name: b.pkg.Name,
+				},
+				Loc:       locForPos(b.pkg, ident.Pos()),
+				Signature: b.signatureForExpr(funcLit),
+				Decl:      locForNode(b.pkg, stmt),
+				NameLoc:   locForNode(b.pkg, ident),
+			},
+			Children: b.localNodes(funcLit.Body),
+		})
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Closures assigned to variables were already added with their own local definitions.
+			_, ok := closures[n]
+			return !ok
+
+		case *ast.AssignStmt:
+			for i, rhs := range n.Rhs {
+				funcLit, ok := rhs.(*ast.FuncLit)
+				if !ok || i >= len(n.Lhs) {
+					continue
+				}
+				if ident, ok := n.Lhs[i].(*ast.Ident); ok {
+					addClosure(ident, funcLit, n)
+				}
+			}
+
+		case *ast.DeclStmt:
+			genDecl, ok := n.Decl.(*ast.GenDecl)
+			if !ok {
+				return true
+			}
+
+			if genDecl.Tok == token.TYPE {
+				for _, node := range b.genDeclNodes(genDecl) {
+					nodes = append(nodes, unexported(node))
+				}
+				return true
+			}
+
+			for _, spec := range genDecl.Specs {
+				valueSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for i, value := range valueSpec.Values {
+					if funcLit, ok := value.(*ast.FuncLit); ok && i < len(valueSpec.Names) {
+						addClosure(valueSpec.Names[i], funcLit, n)
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return locLess(nodes[i].Loc, nodes[j].Loc)
+	})
+	return nodes
+}
+
+func (b *outlineBuilder) signatureForExpr(expr ast.Expr) string {
+	if b.pkg.TypesInfo == nil {
+		return ""
+	}
+
+	t := b.pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return ""
+	}
+	return types.TypeString(t, qualifierForPkg(b.pkg.Types))
+}
+
+// attachMethods appends each type's methods to its children, searching inside groups.
+func attachMethods(nodes []OutlineNode, methodsByRecv map[string][]OutlineNode) {
+	for i := range nodes {
+		if nodes[i].Group {
+			attachMethods(nodes[i].Children, methodsByRecv)
+		} else if nodes[i].Kind == DefKindType {
+			nodes[i].Children = append(nodes[i].Children, methodsByRecv[nodes[i].Name]...)
+		}
+	}
+}
+
+func setDepth(nodes []OutlineNode, depth int) {
+	for i := range nodes {
+		nodes[i].Depth = depth
+		setDepth(nodes[i].Children, depth+1)
+	}
+}
+
+// unexported marks a local definition and its children as unexported, since they aren't visible outside the func.
+func unexported(node OutlineNode) OutlineNode {
+	node.Exported = false
+	for i := range node.Children {
+		node.Children[i] = unexported(node.Children[i])
+	}
+	return node
+}
+
+func locLess(a, b file.Loc) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
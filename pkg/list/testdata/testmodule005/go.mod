@@ -0,0 +1,3 @@
+module github.com/wedaly/gospelunk/pkg/list/testdata/testmodule005
+
+go 1.18
@@ -0,0 +1,25 @@
+package testmodule005
+
+import "context"
+
+type Server struct {
+	Addr string
+}
+
+func NewServer(addr string) *Server {
+	return &Server{Addr: addr}
+}
+
+func (s *Server) Run(ctx context.Context) error {
+	return nil
+}
+
+func (s Server) String() string {
+	return s.Addr
+}
+
+type Handler interface {
+	Handle(ctx context.Context) error
+}
+
+const DefaultAddr = "localhost:8080"
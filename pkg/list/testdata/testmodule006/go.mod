@@ -0,0 +1,3 @@
+module github.com/wedaly/gospelunk/pkg/list/testdata/testmodule006
+
+go 1.19
@@ -0,0 +1,40 @@
+package outline
+
+import "fmt"
+
+func (s *Stack) Push(v int) {
+	s.items = append(s.items, v)
+}
+
+type Stack struct {
+	items []int
+	Name  string
+}
+
+const (
+	Small = 1
+	Large = 2
+)
+
+var Default = Stack{Name: "default"}
+
+type Sizer interface {
+	Size() int
+}
+
+func (s *Stack) Size() int {
+	return len(s.items)
+}
+
+func Describe(s *Stack) string {
+	type summary struct {
+		size int
+	}
+
+	format := func(sum summary) string {
+		prefix := func() string { return "size" }
+		return fmt.Sprintf("%s %d", prefix(), sum.size)
+	}
+
+	return format(summary{size: s.Size()})
+}
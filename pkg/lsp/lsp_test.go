@@ -0,0 +1,189 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	rootDir := absPath(t, "../inspect/testdata/testmodule011")
+	mainURI := uriForPath(filepath.Join(rootDir, "main.go"))
+	defURI := uriForPath(filepath.Join(rootDir, "subpkg", "def.go"))
+	refURI := uriForPath(filepath.Join(rootDir, "subpkg", "ref.go"))
+
+	responses := runServer(t, []map[string]any{
+		{"id": 1, "method": "initialize", "params": map[string]any{"rootUri": uriForPath(rootDir)}},
+		{"method": "initialized", "params": map[string]any{}},
+		{"id": 2, "method": "textDocument/definition", "params": map[string]any{
+			"textDocument": map[string]any{"uri": mainURI},
+			"position":     map[string]any{"line": 7, "character": 13},
+		}},
+		{"id": 3, "method": "textDocument/references", "params": map[string]any{
+			"textDocument": map[string]any{"uri": defURI},
+			"position":     map[string]any{"line": 6, "character": 5},
+			"context":      map[string]any{"includeDeclaration": true},
+		}},
+		{"id": 4, "method": "textDocument/implementation", "params": map[string]any{
+			"textDocument": map[string]any{"uri": defURI},
+			"position":     map[string]any{"line": 10, "character": 5},
+		}},
+		{"id": 5, "method": "textDocument/hover", "params": map[string]any{
+			"textDocument": map[string]any{"uri": defURI},
+			"position":     map[string]any{"line": 6, "character": 5},
+		}},
+		{"id": 6, "method": "workspace/symbol", "params": map[string]any{"query": "myfunc"}},
+		{"id": 7, "method": "textDocument/unsupported", "params": map[string]any{}},
+		{"id": 8, "method": "shutdown"},
+		{"method": "exit"},
+	})
+
+	require.Equal(t, 8, len(responses))
+
+	var initResult initializeResult
+	unmarshalResult(t, responses[1], &initResult)
+	assert.True(t, initResult.Capabilities.DefinitionProvider)
+
+	var defLocations []location
+	unmarshalResult(t, responses[2], &defLocations)
+	assert.Equal(t, []location{
+		{URI: defURI, Range: lspRange{Start: position{Line: 2, Character: 5}, End: position{Line: 2, Character: 13}}},
+	}, defLocations)
+
+	var refLocations []location
+	unmarshalResult(t, responses[3], &refLocations)
+	assert.Equal(t, []location{
+		{URI: refURI, Range: lspRange{Start: position{Line: 12, Character: 6}, End: position{Line: 12, Character: 12}}},
+		{URI: defURI, Range: lspRange{Start: position{Line: 6, Character: 5}, End: position{Line: 6, Character: 11}}},
+	}, refLocations)
+
+	var implLocations []location
+	unmarshalResult(t, responses[4], &implLocations)
+	assert.Equal(t, []location{
+		{URI: defURI, Range: lspRange{Start: position{Line: 2, Character: 5}, End: position{Line: 2, Character: 13}}},
+	}, implLocations)
+
+	var hoverResult hover
+	unmarshalResult(t, responses[5], &hoverResult)
+	assert.Equal(t, "```go\nMyFunc func() string\n```", hoverResult.Contents.Value)
+
+	var symbols []symbolInformation
+	unmarshalResult(t, responses[6], &symbols)
+	var symbolNames []string
+	for _, sym := range symbols {
+		symbolNames = append(symbolNames, sym.Name)
+		assert.Equal(t, symbolKindFunction, sym.Kind)
+	}
+	assert.Equal(t, []string{"MyFunc", "CallMyFunc"}, symbolNames)
+
+	require.NotNil(t, responses[7].Error)
+	assert.Equal(t, codeMethodNotFound, responses[7].Error.Code)
+
+	assert.Nil(t, responses[8].Error)
+	assert.Equal(t, "null", string(responses[8].Result))
+}
+
+func TestServerOpenDocuments(t *testing.T) {
+	rootDir := absPath(t, "../inspect/testdata/testmodule011")
+	mainPath := filepath.Join(rootDir, "main.go")
+	mainURI := uriForPath(mainPath)
+	defURI := uriForPath(filepath.Join(rootDir, "subpkg", "def.go"))
+
+	contents, err := os.ReadFile(mainPath)
+	require.NoError(t, err)
+
+	definitionParams := func(line int) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": mainURI},
+			"position":     map[string]any{"line": line, "character": 13},
+		}
+	}
+
+	responses := runServer(t, []map[string]any{
+		{"id": 1, "method": "initialize", "params": map[string]any{"rootUri": uriForPath(rootDir)}},
+		{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": mainURI, "languageId": "go", "version": 1, "text": "// Unsaved\n" + string(contents)},
+		}},
+		{"id": 2, "method": "textDocument/definition", "params": definitionParams(8)},
+		{"id": 3, "method": "textDocument/hover", "params": map[string]any{
+			"textDocument": map[string]any{"uri": mainURI},
+			"position":     map[string]any{"line": 0, "character": 4},
+		}},
+		{"method": "textDocument/didChange", "params": map[string]any{
+			"textDocument":   map[string]any{"uri": mainURI, "version": 2},
+			"contentChanges": []map[string]any{{"text": "// Unsaved\n\n" + string(contents)}},
+		}},
+		{"id": 4, "method": "textDocument/definition", "params": definitionParams(9)},
+		{"method": "textDocument/didClose", "params": map[string]any{
+			"textDocument": map[string]any{"uri": mainURI},
+		}},
+		{"id": 5, "method": "textDocument/definition", "params": definitionParams(7)},
+		{"method": "exit"},
+	})
+
+	require.Equal(t, 5, len(responses))
+
+	var initResult initializeResult
+	unmarshalResult(t, responses[1], &initResult)
+	assert.Equal(t, textDocumentSyncFull, initResult.Capabilities.TextDocumentSync)
+
+	// Every definition request is at the same identifier, wherever it is in the current contents of the document.
+	expected := []location{
+		{URI: defURI, Range: lspRange{Start: position{Line: 2, Character: 5}, End: position{Line: 2, Character: 13}}},
+	}
+	for _, id := range []int{2, 4, 5} {
+		var defLocations []location
+		unmarshalResult(t, responses[id], &defLocations)
+		assert.Equal(t, expected, defLocations, "response %d", id)
+	}
+
+	// Hover on a comment has no result.
+	assert.Nil(t, responses[3].Error)
+	assert.Equal(t, "null", string(responses[3].Result))
+}
+
+func runServer(t *testing.T, requests []map[string]any) map[int]*message {
+	var input bytes.Buffer
+	for _, req := range requests {
+		req["jsonrpc"] = "2.0"
+		content, err := json.Marshal(req)
+		require.NoError(t, err)
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	}
+
+	var output bytes.Buffer
+	err := NewServer().Serve(&input, &output)
+	require.NoError(t, err)
+
+	responses := make(map[int]*message)
+	reader := bufio.NewReader(&output)
+	for reader.Buffered() > 0 || output.Len() > 0 {
+		msg, err := readMessage(reader)
+		require.NoError(t, err)
+		require.NotNil(t, msg.ID)
+
+		var id int
+		require.NoError(t, json.Unmarshal(*msg.ID, &id))
+		responses[id] = msg
+	}
+
+	return responses
+}
+
+func unmarshalResult(t *testing.T, msg *message, v any) {
+	require.Nil(t, msg.Error)
+	require.NoError(t, json.Unmarshal(msg.Result, v))
+}
+
+func absPath(t *testing.T, path string) string {
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+	return absPath
+}
@@ -0,0 +1,125 @@
+package lsp
+
+// This file defines the subset of the language server protocol used by gospelunk.
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/
+
+type initializeParams struct {
+	RootURI          string            `json:"rootUri"`
+	RootPath         string            `json:"rootPath"`
+	WorkspaceFolders []workspaceFolder `json:"workspaceFolders"`
+}
+
+type workspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+type serverCapabilities struct {
+	PositionEncoding        string `json:"positionEncoding"`
+	TextDocumentSync        int    `json:"textDocumentSync"`
+	DefinitionProvider      bool   `json:"definitionProvider"`
+	TypeDefinitionProvider  bool   `json:"typeDefinitionProvider"`
+	ReferencesProvider      bool   `json:"referencesProvider"`
+	ImplementationProvider  bool   `json:"implementationProvider"`
+	HoverProvider           bool   `json:"hoverProvider"`
+	WorkspaceSymbolProvider bool   `json:"workspaceSymbolProvider"`
+}
+
+type serverInfo struct {
+	Name string `json:"name"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// position is zero-indexed, and the character offset is measured in UTF-16 code units.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type didOpenTextDocumentParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeTextDocumentParams struct {
+	TextDocument   textDocumentIdentifier           `json:"textDocument"`
+	ContentChanges []textDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// textDocumentContentChangeEvent has the full contents of the document, since the server only supports full sync.
+type textDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type didCloseTextDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type referenceParams struct {
+	textDocumentPositionParams
+	Context referenceContext `json:"context"`
+}
+
+type referenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type workspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type symbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+// textDocumentSyncFull means the client sends the full contents of a document whenever it changes.
+const textDocumentSyncFull = 1
+
+// Symbol kinds defined by the protocol.
+const (
+	symbolKindClass    = 5
+	symbolKindMethod   = 6
+	symbolKindField    = 8
+	symbolKindFunction = 12
+	symbolKindVariable = 13
+	symbolKindConstant = 14
+)
@@ -0,0 +1,440 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wedaly/gospelunk/pkg/cache"
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+	"github.com/wedaly/gospelunk/pkg/list"
+	"github.com/wedaly/gospelunk/pkg/search"
+)
+
+// Server is a language server that answers requests using the inspect and list packages.
+type Server struct {
+	rootDir string
+	cache   *cache.Cache
+
+	// docs are the contents of documents opened in the client, keyed by absolute path.
+	docs map[string][]byte
+}
+
+func NewServer() *Server {
+	return &Server{cache: cache.New(), docs: make(map[string][]byte)}
+}
+
+// Serve reads messages from r and writes responses to w.
+// It returns when the client sends an exit notification or closes r.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		msg, err := readMessage(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			var respErr *responseError
+			if errors.As(err, &respErr) {
+				// The message was framed correctly but had invalid content, so we can keep going.
+				nullID := json.RawMessage("null")
+				if err := writeMessage(w, &message{ID: &nullID, Error: respErr}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		if msg.ID == nil {
+			// Notifications don't have a response, so there's nowhere to report an error.
+			s.handleNotification(msg.Method, msg.Params)
+			continue
+		}
+
+		resp := &message{ID: msg.ID}
+		result, err := s.handleRequest(msg.Method, msg.Params)
+		if err != nil {
+			if !errors.As(err, &resp.Error) {
+				resp.Error = &responseError{Code: codeRequestFailed, Message: err.Error()}
+			}
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+
+		if err := writeMessage(w, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handleRequest(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "shutdown":
+		return nil, nil
+	case "textDocument/definition":
+		return s.relationLocations(params, []inspect.RelationKind{inspect.RelationKindDef})
+	case "textDocument/typeDefinition":
+		return s.relationLocations(params, []inspect.RelationKind{inspect.RelationKindTypeDef})
+	case "textDocument/references":
+		return s.references(params)
+	case "textDocument/implementation":
+		return s.relationLocations(params, []inspect.RelationKind{inspect.RelationKindImpl, inspect.RelationKindIface})
+	case "textDocument/hover":
+		return s.hover(params)
+	case "workspace/symbol":
+		return s.workspaceSymbol(params)
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("Unsupported method %q", method)}
+	}
+}
+
+func (s *Server) handleNotification(method string, params json.RawMessage) {
+	switch method {
+	case "textDocument/didOpen":
+		var p didOpenTextDocumentParams
+		if err := unmarshalParams(params, &p); err == nil {
+			s.setDoc(p.TextDocument.URI, []byte(p.TextDocument.Text))
+		}
+	case "textDocument/didChange":
+		var p didChangeTextDocumentParams
+		if err := unmarshalParams(params, &p); err == nil && len(p.ContentChanges) > 0 {
+			// With full sync, the last change has the current contents of the document.
+			s.setDoc(p.TextDocument.URI, []byte(p.ContentChanges[len(p.ContentChanges)-1].Text))
+		}
+	case "textDocument/didClose":
+		var p didCloseTextDocumentParams
+		if err := unmarshalParams(params, &p); err == nil {
+			if path, err := pathForURI(p.TextDocument.URI); err == nil {
+				delete(s.docs, path)
+			}
+		}
+	}
+}
+
+func (s *Server) setDoc(uri string, contents []byte) {
+	path, err := pathForURI(uri)
+	if err != nil {
+		return
+	}
+	s.docs[path] = contents
+}
+
+// overlay returns the contents of open documents that differ from the files on disk.
+// Documents that match the files on disk are left out, so packages loaded from them can still be cached.
+func (s *Server) overlay() file.Overlay {
+	var overlay file.Overlay
+	for path, contents := range s.docs {
+		if data, err := os.ReadFile(path); err == nil && bytes.Equal(data, contents) {
+			continue
+		}
+		if overlay == nil {
+			overlay = make(file.Overlay)
+		}
+		overlay[path] = contents
+	}
+	return overlay
+}
+
+func (s *Server) initialize(rawParams json.RawMessage) (any, error) {
+	var params initializeParams
+	if err := unmarshalParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	rootURI := params.RootURI
+	if rootURI == "" && len(params.WorkspaceFolders) > 0 {
+		rootURI = params.WorkspaceFolders[0].URI
+	}
+
+	switch {
+	case rootURI != "":
+		rootDir, err := pathForURI(rootURI)
+		if err != nil {
+			return nil, err
+		}
+		s.rootDir = rootDir
+	case params.RootPath != "":
+		s.rootDir = params.RootPath
+	default:
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("os.Getwd: %w", err)
+		}
+		s.rootDir = cwd
+	}
+
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			PositionEncoding:        "utf-16",
+			TextDocumentSync:        textDocumentSyncFull,
+			DefinitionProvider:      true,
+			TypeDefinitionProvider:  true,
+			ReferencesProvider:      true,
+			ImplementationProvider:  true,
+			HoverProvider:           true,
+			WorkspaceSymbolProvider: true,
+		},
+		ServerInfo: serverInfo{Name: "gospelunk"},
+	}, nil
+}
+
+func (s *Server) references(rawParams json.RawMessage) (any, error) {
+	var params referenceParams
+	if err := unmarshalParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	relKinds := []inspect.RelationKind{inspect.RelationKindRef}
+	if params.Context.IncludeDeclaration {
+		relKinds = append(relKinds, inspect.RelationKindDef)
+	}
+
+	return s.locationsForPosition(params.textDocumentPositionParams, relKinds)
+}
+
+func (s *Server) relationLocations(rawParams json.RawMessage, relKinds []inspect.RelationKind) (any, error) {
+	var params textDocumentPositionParams
+	if err := unmarshalParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+	return s.locationsForPosition(params, relKinds)
+}
+
+func (s *Server) locationsForPosition(params textDocumentPositionParams, relKinds []inspect.RelationKind) ([]location, error) {
+	overlay := s.overlay()
+	result, err := s.inspect(params, relKinds, inspect.Options{Overlay: overlay})
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make([]location, 0, len(result.Relations))
+	for _, r := range result.Relations {
+		if r.Path == "" {
+			// Some definitions (like builtin types) don't have a location.
+			continue
+		}
+
+		loc, err := locationForFileLoc(overlay, r.Loc)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, loc)
+	}
+
+	return locations, nil
+}
+
+func (s *Server) hover(rawParams json.RawMessage) (any, error) {
+	var params textDocumentPositionParams
+	if err := unmarshalParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	result, err := s.inspect(params, nil, inspect.Options{IncludeDoc: true, Overlay: s.overlay()})
+	var noIdentErr *inspect.NoIdentifierError
+	if errors.As(err, &noIdentErr) {
+		// There's nothing to show, which isn't an error.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if result.Type == "" {
+		return nil, nil
+	}
+
+	value := fmt.Sprintf("```go\n%s %s\n```", result.Name, result.Type)
+	if result.Doc != "" {
+		value = fmt.Sprintf("%s\n\n%s", value, result.Doc)
+	}
+
+	return hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: value,
+		},
+	}, nil
+}
+
+func (s *Server) inspect(params textDocumentPositionParams, relKinds []inspect.RelationKind, opts inspect.Options) (*inspect.Result, error) {
+	loc, err := fileLocForPosition(opts.Overlay, params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Cache = s.cache
+	return inspect.Inspect(loc, s.rootDir, relKinds, opts)
+}
+
+func (s *Server) workspaceSymbol(rawParams json.RawMessage) (any, error) {
+	var params workspaceSymbolParams
+	if err := unmarshalParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	overlay := s.overlay()
+	listResult, err := list.List([]string{"./..."}, list.Options{
+		IncludeStructFields:     true,
+		IncludeInterfaceMethods: true,
+		IncludePrivate:          true,
+		Dir:                     s.rootDir,
+		Cache:                   s.cache,
+		Overlay:                 overlay,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	searchResult := search.Search(params.Query, listResult.Defs, search.Options{})
+	symbols := make([]symbolInformation, 0, len(searchResult.Matches))
+	for _, match := range searchResult.Matches {
+		loc, err := locationForFileLoc(overlay, match.NameLoc)
+		if err != nil {
+			return nil, err
+		}
+
+		symbols = append(symbols, symbolInformation{
+			Name:          match.Name,
+			Kind:          symbolKindForDefKind(match.Kind),
+			Location:      loc,
+			ContainerName: match.Pkg.Name,
+		})
+	}
+
+	return symbols, nil
+}
+
+func symbolKindForDefKind(kind list.DefKind) int {
+	switch kind {
+	case list.DefKindConst:
+		return symbolKindConstant
+	case list.DefKindFunc:
+		return symbolKindFunction
+	case list.DefKindMethod:
+		return symbolKindMethod
+	case list.DefKindType:
+		return symbolKindClass
+	case list.DefKindField:
+		return symbolKindField
+	default:
+		return symbolKindVariable
+	}
+}
+
+func unmarshalParams(rawParams json.RawMessage, params any) error {
+	if err := json.Unmarshal(rawParams, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("json.Unmarshal: %s", err)}
+	}
+	return nil
+}
+
+// fileLocForPosition converts an LSP position (zero-indexed, UTF-16 columns)
+// to a file location (one-indexed, byte columns).
+func fileLocForPosition(overlay file.Overlay, uri string, pos position) (file.Loc, error) {
+	path, err := pathForURI(uri)
+	if err != nil {
+		return file.Loc{}, err
+	}
+
+	line, err := readLine(overlay, path, pos.Line+1)
+	if err != nil {
+		return file.Loc{}, err
+	}
+
+	return file.Loc{
+		Path:   path,
+		Line:   pos.Line + 1,
+		Column: file.ByteOffsetForUTF16Offset(line, pos.Character) + 1,
+	}, nil
+}
+
+// locationForFileLoc converts a file location (one-indexed, byte columns)
+// to an LSP location (zero-indexed, UTF-16 columns) spanning the location.
+// If the location doesn't have an end position, the range spans the identifier at the start.
+func locationForFileLoc(overlay file.Overlay, loc file.Loc) (location, error) {
+	line, err := readLine(overlay, loc.Path, loc.Line)
+	if err != nil {
+		return location{}, err
+	}
+
+	startOffset := min(max(loc.Column-1, 0), len(line))
+	start := position{Line: loc.Line - 1, Character: file.UTF16OffsetForByteOffset(line, startOffset)}
+	end := position{Line: loc.Line - 1, Character: file.UTF16OffsetForByteOffset(line, identifierEndOffset(line, startOffset))}
+
+	if loc.End != (file.Pos{}) {
+		endLine, err := readLine(overlay, loc.Path, loc.End.Line)
+		if err != nil {
+			return location{}, err
+		}
+		end = position{Line: loc.End.Line - 1, Character: file.UTF16OffsetForByteOffset(endLine, loc.End.Column-1)}
+	}
+
+	return location{
+		URI:   uriForPath(loc.Path),
+		Range: lspRange{Start: start, End: end},
+	}, nil
+}
+
+func pathForURI(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("url.Parse: %w", err)
+	}
+
+	if u.Scheme != "file" {
+		return "", &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("Unsupported URI scheme %q", u.Scheme)}
+	}
+
+	return filepath.FromSlash(u.Path), nil
+}
+
+func uriForPath(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+// readLine returns the contents of a one-indexed line in a file, without the line ending.
+func readLine(overlay file.Overlay, path string, lineNum int) (string, error) {
+	data, err := overlay.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if lineNum < 1 || lineNum > len(lines) {
+		return "", fmt.Errorf("Line %d out of range for %q", lineNum, path)
+	}
+
+	return strings.TrimSuffix(lines[lineNum-1], "\r"), nil
+}
+
+func identifierEndOffset(line string, startOffset int) int {
+	offset := startOffset
+	for offset < len(line) {
+		r, size := utf8.DecodeRuneInString(line[offset:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		offset += size
+	}
+	return offset
+}
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SchemaVersion is included in every JSON object we output, so consumers can detect
+// incompatible changes to field names. Increment it whenever a field is renamed or removed.
+const SchemaVersion = 1
+
+type Format string
+
+const (
+	FormatTemplate  = Format("template")
+	FormatJSON      = Format("json")
+	FormatJSONLines = Format("jsonl")
+)
+
+var AllFormatStrings = []string{
+	string(FormatTemplate),
+	string(FormatJSON),
+	string(FormatJSONLines),
+}
+
+func FormatFromString(s string) (Format, error) {
+	for _, f := range AllFormatStrings {
+		if s == f {
+			return Format(s), nil
+		}
+	}
+	return Format(""), fmt.Errorf("Invalid format %q, allowed values are [%s]", s, strings.Join(AllFormatStrings, ", "))
+}
+
+// JSON writes v as a single JSON object, followed by a newline.
+func JSON(w io.Writer, v any) error {
+	data, err := marshalWithSchemaVersion(v)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return fmt.Errorf("json.Indent: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// JSONLines writes each item as a JSON object on its own line.
+func JSONLines[T any](w io.Writer, items []T) error {
+	for _, item := range items {
+		data, err := marshalWithSchemaVersion(item)
+		if err != nil {
+			return err
+		}
+
+		data = append(data, '\n')
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalWithSchemaVersion encodes v as a JSON object with a "schemaVersion" field prepended.
+func marshalWithSchemaVersion(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("Cannot output %T as a JSON object", v)
+	}
+
+	prefix := fmt.Sprintf(`{"schemaVersion":%d`, SchemaVersion)
+	if len(data) > 2 {
+		prefix += ","
+	}
+
+	return append([]byte(prefix), data[1:]...), nil
+}
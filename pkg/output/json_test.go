@@ -0,0 +1,73 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		val      any
+		expected string
+	}{
+		{
+			name:     "object with fields",
+			val:      testItem{Name: "foo", Count: 2},
+			expected: "{\n  \"schemaVersion\": 1,\n  \"name\": \"foo\",\n  \"count\": 2\n}\n",
+		},
+		{
+			name:     "empty object",
+			val:      struct{}{},
+			expected: "{\n  \"schemaVersion\": 1\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := JSON(&sb, tc.val)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, sb.String())
+		})
+	}
+}
+
+func TestJSONNotObject(t *testing.T) {
+	var sb strings.Builder
+	err := JSON(&sb, []string{"foo"})
+	assert.Error(t, err)
+}
+
+func TestJSONLines(t *testing.T) {
+	items := []testItem{
+		{Name: "foo", Count: 1},
+		{Name: "bar", Count: 2},
+	}
+
+	var sb strings.Builder
+	err := JSONLines(&sb, items)
+	require.NoError(t, err)
+
+	expected := `{"schemaVersion":1,"name":"foo","count":1}
+{"schemaVersion":1,"name":"bar","count":2}
+`
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestFormatFromString(t *testing.T) {
+	format, err := FormatFromString("jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSONLines, format)
+
+	_, err = FormatFromString("xml")
+	assert.Error(t, err)
+}
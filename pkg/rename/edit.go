@@ -0,0 +1,249 @@
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change in a diff.
+const diffContextLines = 3
+
+// editedFile contains the contents of a file before and after applying edits.
+type editedFile struct {
+	Path   string
+	Before []byte
+	After  []byte
+}
+
+// Diff writes a unified diff of the edits to w.
+// The displayPath function converts each absolute path to the path shown in the diff header.
+func (r *Result) Diff(w io.Writer, displayPath func(string) string) error {
+	files, err := r.editedFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		path := displayPath(f.Path)
+		if _, err := fmt.Fprintf(w, "--- a/%s\n+++ b/%s\n", path, path); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+
+		if err := writeHunks(w, splitLines(f.Before), splitLines(f.After)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply writes the edits to disk.
+// Every edited file is written to a temporary file first, then renamed over the original,
+// so if any write fails the original files are left unchanged.
+func (r *Result) Apply() error {
+	if len(r.Conflicts) > 0 {
+		return fmt.Errorf("Cannot apply edits with %d conflicts", len(r.Conflicts))
+	}
+
+	files, err := r.editedFiles()
+	if err != nil {
+		return err
+	}
+
+	tmpPaths := make([]string, 0, len(files))
+	defer func() {
+		// After a successful rename, the temporary file no longer exists, so this is a no-op.
+		for _, tmpPath := range tmpPaths {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	for _, f := range files {
+		tmpPath, err := writeTempFile(f.Path, f.After)
+		if err != nil {
+			return err
+		}
+		tmpPaths = append(tmpPaths, tmpPath)
+	}
+
+	for i, f := range files {
+		if err := os.Rename(tmpPaths[i], f.Path); err != nil {
+			return fmt.Errorf("os.Rename: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// editedFiles reads every file with edits and applies the edits in memory.
+// It returns an error if a file no longer contains the old name at an edit location,
+// which usually means the file changed after the edits were computed.
+func (r *Result) editedFiles() ([]editedFile, error) {
+	editsByPath := make(map[string][]Edit)
+	for _, edit := range r.Edits {
+		editsByPath[edit.Path] = append(editsByPath[edit.Path], edit)
+	}
+
+	paths := make([]string, 0, len(editsByPath))
+	for path := range editsByPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	files := make([]editedFile, 0, len(paths))
+	for _, path := range paths {
+		before, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		edits := editsByPath[path]
+		sort.Slice(edits, func(i, j int) bool {
+			return locLess(edits[i].Loc, edits[j].Loc)
+		})
+
+		lineOffsets := lineStartOffsets(before)
+		var after bytes.Buffer
+		prevEnd := 0
+		for _, edit := range edits {
+			if edit.Line < 1 || edit.Line > len(lineOffsets) {
+				return nil, fmt.Errorf("Invalid line %d in %s", edit.Line, path)
+			}
+
+			start := lineOffsets[edit.Line-1] + edit.Column - 1
+			end := start + len(edit.OldName)
+			if start < prevEnd || end > len(before) || string(before[start:end]) != edit.OldName {
+				return nil, fmt.Errorf("Expected %q at %s, but the file has changed", edit.OldName, edit.Loc)
+			}
+
+			after.Write(before[prevEnd:start])
+			after.WriteString(edit.NewName)
+			prevEnd = end
+		}
+		after.Write(before[prevEnd:])
+
+		files = append(files, editedFile{Path: path, Before: before, After: after.Bytes()})
+	}
+
+	return files, nil
+}
+
+// writeTempFile writes data to a temporary file in the same directory as path,
+// with the same permissions, so it can be renamed over path.
+func writeTempFile(path string, data []byte) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("os.Stat: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".gospelunk-rename-*")
+	if err != nil {
+		return "", fmt.Errorf("os.CreateTemp: %w", err)
+	}
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("tmpFile.Write: %w", err)
+	}
+
+	if err := tmpFile.Chmod(info.Mode().Perm()); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("tmpFile.Chmod: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("tmpFile.Close: %w", err)
+	}
+
+	return tmpFile.Name(), nil
+}
+
+// writeHunks writes unified diff hunks for two versions of a file.
+// Renaming never adds or removes lines, so line i in before corresponds to line i in after.
+func writeHunks(w io.Writer, before []string, after []string) error {
+	var changed []int
+	for i := range before {
+		if before[i] != after[i] {
+			changed = append(changed, i)
+		}
+	}
+
+	for len(changed) > 0 {
+		// Group changes whose context would overlap into a single hunk.
+		n := 1
+		for n < len(changed) && changed[n]-changed[n-1] <= 2*diffContextLines+1 {
+			n++
+		}
+
+		start := max(changed[0]-diffContextLines, 0)
+		end := min(changed[n-1]+diffContextLines+1, len(before))
+		if _, err := fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+
+		for i := start; i < end; {
+			if before[i] == after[i] {
+				writeDiffLine(w, " ", before[i])
+				i++
+				continue
+			}
+
+			// Show each run of changed lines as removals followed by additions.
+			runEnd := i
+			for runEnd < end && before[runEnd] != after[runEnd] {
+				runEnd++
+			}
+			for j := i; j < runEnd; j++ {
+				writeDiffLine(w, "-", before[j])
+			}
+			for j := i; j < runEnd; j++ {
+				writeDiffLine(w, "+", after[j])
+			}
+			i = runEnd
+		}
+
+		changed = changed[n:]
+	}
+
+	return nil
+}
+
+func writeDiffLine(w io.Writer, prefix string, line string) {
+	fmt.Fprintf(w, "%s%s", prefix, line)
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		fmt.Fprint(w, "\n\\ No newline at end of file\n")
+	}
+}
+
+// splitLines splits data into lines, keeping the newline at the end of each line.
+func splitLines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			lines = append(lines, string(data))
+			break
+		}
+		lines = append(lines, string(data[:i+1]))
+		data = data[i+1:]
+	}
+	return lines
+}
+
+// lineStartOffsets returns the byte offset of the start of each line.
+func lineStartOffsets(data []byte) []int {
+	offsets := []int{0}
+	for i, b := range data {
+		if b == '\n' && i+1 < len(data) {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,583 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/cache"
+	"github.com/wedaly/gospelunk/pkg/file"
+	"github.com/wedaly/gospelunk/pkg/index"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+)
+
+type Options struct {
+	// Build is the build configuration used to load packages.
+	// If it excludes the file being renamed, Rename chooses a configuration that includes it.
+	Build buildcfg.Config
+
+	// Cache reuses loaded packages across calls to Rename.
+	// If nil, packages are cached only for the duration of a single call.
+	Cache *cache.Cache `json:"-"`
+
+	// Index stores package metadata and identifier uses on disk,
+	// so searches for references don't need to typecheck unchanged packages again.
+	Index *index.Index `json:"-"`
+}
+
+// Edit replaces an identifier at a location.
+type Edit struct {
+	file.Loc
+	OldName string `json:"oldName"`
+	NewName string `json:"newName"`
+}
+
+// Conflict describes why an edit would change the meaning of the program or stop it from compiling.
+type Conflict struct {
+	file.Loc
+	Reason string `json:"reason"`
+}
+
+type Result struct {
+	Edits     []Edit     `json:"edits"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// Rename finds the edits needed to rename the identifier at loc, along with its definition and every reference in searchDir.
+// Renaming a method also renames the interface methods it implements and the other implementations of those interface methods.
+// If the new name conflicts with an existing name, the result includes conflicts, and the edits should not be applied.
+func Rename(loc file.Loc, searchDir string, newName string, opts Options) (*Result, error) {
+	if !token.IsIdentifier(newName) || newName == "_" {
+		return nil, fmt.Errorf("Invalid identifier %q", newName)
+	}
+
+	absSearchDir, err := filepath.Abs(searchDir)
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Abs: %w", err)
+	}
+
+	build, err := buildcfg.ConfigForFile(loc.Path, opts.Build, nil)
+	if err != nil {
+		return nil, err
+	}
+	opts.Build = build
+
+	if opts.Cache == nil {
+		// Renaming searches for references from several definitions, so reuse packages between searches.
+		opts.Cache = cache.New()
+	}
+
+	r := &renamer{
+		searchDir: absSearchDir,
+		newName:   newName,
+		opts:      opts,
+		pkgsByDir: make(map[string][]*packages.Package),
+		inspectOpts: inspect.Options{
+			IncludeTests: true,
+			Build:        opts.Build,
+			Cache:        opts.Cache,
+			Index:        opts.Index,
+		},
+	}
+
+	defLoc, err := r.definitionLoc(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	defPkg, defIdent, err := r.identAtLoc(defLoc)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := defPkg.TypesInfo.Defs[defIdent]
+	if obj == nil {
+		return nil, fmt.Errorf("Could not find definition for identifier at %s", loc)
+	}
+
+	if _, ok := obj.(*types.PkgName); ok {
+		return nil, fmt.Errorf("Renaming imports is not supported")
+	}
+
+	r.oldName = obj.Name()
+	if r.oldName == newName {
+		return &Result{}, nil
+	}
+
+	defLocs := []file.Loc{defLoc}
+	if isMethod(obj) {
+		defLocs, err = r.linkedMethodLocs(defLoc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.findEdits(defLocs); err != nil {
+		return nil, err
+	}
+
+	if err := r.checkConflicts(); err != nil {
+		return nil, err
+	}
+
+	return r.result(), nil
+}
+
+type renamer struct {
+	searchDir   string
+	oldName     string
+	newName     string
+	opts        Options
+	inspectOpts inspect.Options
+	pkgsByDir   map[string][]*packages.Package
+	defLocs     map[file.Loc]struct{}
+	editLocs    map[file.Loc]struct{}
+	conflicts   map[Conflict]struct{}
+}
+
+// definitionLoc returns the location of the definition for the identifier at loc.
+func (r *renamer) definitionLoc(loc file.Loc) (file.Loc, error) {
+	result, err := inspect.Inspect(loc, r.searchDir, []inspect.RelationKind{inspect.RelationKindDef}, r.inspectOpts)
+	if err != nil {
+		return file.Loc{}, err
+	}
+
+	for _, rel := range result.Relations {
+		if rel.Kind == inspect.RelationKindDef {
+			return rel.Loc, nil
+		}
+	}
+
+	return file.Loc{}, fmt.Errorf("Could not find definition for identifier at %s", loc)
+}
+
+// linkedMethodLocs returns the locations of every method that must be renamed along with the method at loc,
+// so that types continue to implement the same interfaces.
+func (r *renamer) linkedMethodLocs(loc file.Loc) ([]file.Loc, error) {
+	relKinds := []inspect.RelationKind{inspect.RelationKindImpl, inspect.RelationKindIface}
+	seen := map[file.Loc]struct{}{loc: {}}
+	queue := []file.Loc{loc}
+	for i := 0; i < len(queue); i++ {
+		if !r.inSearchDir(queue[i]) {
+			// This will be reported as a conflict, so don't search further.
+			continue
+		}
+
+		result, err := inspect.Inspect(queue[i], r.searchDir, relKinds, r.inspectOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rel := range result.Relations {
+			if _, ok := seen[rel.Loc]; !ok {
+				seen[rel.Loc] = struct{}{}
+				queue = append(queue, rel.Loc)
+			}
+		}
+	}
+	return queue, nil
+}
+
+// findEdits finds the definitions and references to rename.
+func (r *renamer) findEdits(defLocs []file.Loc) error {
+	r.defLocs = make(map[file.Loc]struct{})
+	r.editLocs = make(map[file.Loc]struct{})
+	r.conflicts = make(map[Conflict]struct{})
+
+	queue := defLocs
+	for i := 0; i < len(queue); i++ {
+		defLoc := queue[i]
+		r.defLocs[defLoc] = struct{}{}
+
+		if !r.inSearchDir(defLoc) {
+			r.addConflict(defLoc, fmt.Sprintf("%s is declared outside the search directory %s", r.oldName, r.searchDir))
+			continue
+		}
+
+		result, err := inspect.Inspect(defLoc, r.searchDir, []inspect.RelationKind{inspect.RelationKindRef}, r.inspectOpts)
+		if err != nil {
+			return err
+		}
+
+		locs := []file.Loc{defLoc}
+		for _, rel := range result.Relations {
+			locs = append(locs, rel.Loc)
+		}
+
+		for _, loc := range locs {
+			if _, ok := r.editLocs[loc]; ok {
+				continue
+			}
+			r.editLocs[loc] = struct{}{}
+
+			// Renaming a type also renames the fields that embed it,
+			// so rename the references to those fields as well.
+			pkg, ident, err := r.identAtLoc(loc)
+			if err != nil {
+				return err
+			}
+
+			if fieldObj, ok := pkg.TypesInfo.Defs[ident].(*types.Var); ok && fieldObj.Embedded() && loc != defLoc {
+				queue = append(queue, loc)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkConflicts checks whether the new name conflicts with an existing name at any edit location.
+func (r *renamer) checkConflicts() error {
+	for loc := range r.editLocs {
+		if _, ok := r.defLocs[loc]; ok {
+			continue
+		}
+
+		if !r.inSearchDir(loc) {
+			continue
+		}
+
+		pkg, ident, err := r.identAtLoc(loc)
+		if err != nil {
+			return err
+		}
+
+		r.checkRefConflicts(pkg, ident, loc)
+	}
+
+	for loc := range r.defLocs {
+		if !r.inSearchDir(loc) {
+			continue
+		}
+
+		pkg, ident, err := r.identAtLoc(loc)
+		if err != nil {
+			return err
+		}
+
+		r.checkDefConflicts(pkg, ident, loc)
+	}
+
+	return nil
+}
+
+// checkDefConflicts checks whether the new name conflicts with another declaration in the same scope,
+// or would shadow a declaration in an enclosing scope.
+func (r *renamer) checkDefConflicts(pkg *packages.Package, ident *ast.Ident, loc file.Loc) {
+	obj := pkg.TypesInfo.Defs[ident]
+	if obj == nil {
+		return
+	}
+
+	if isMethod(obj) {
+		recvType := obj.Type().(*types.Signature).Recv().Type()
+		if existing, _, _ := types.LookupFieldOrMethod(recvType, true, pkg.Types, r.newName); existing != nil {
+			r.addConflict(loc, fmt.Sprintf("%s already has a field or method named %s", types.TypeString(recvType, types.RelativeTo(pkg.Types)), r.newName))
+		}
+		return
+	}
+
+	if fieldObj, ok := obj.(*types.Var); ok && fieldObj.IsField() {
+		r.checkFieldDefConflicts(pkg, ident, loc)
+		return
+	}
+
+	scope := obj.Parent()
+	if scope == nil {
+		return
+	}
+
+	if existing := scope.Lookup(r.newName); existing != nil {
+		r.addConflict(loc, fmt.Sprintf("%s is already declared in this scope at %s", r.newName, pkg.Fset.Position(existing.Pos())))
+	}
+
+	if scope == pkg.Types.Scope() {
+		// Imports are declared in file scopes, which are nested in the package scope.
+		for _, astFile := range pkg.Syntax {
+			if fileScope := pkg.TypesInfo.Scopes[astFile]; fileScope != nil {
+				if existing := fileScope.Lookup(r.newName); existing != nil {
+					r.addConflict(loc, fmt.Sprintf("%s conflicts with the import at %s", r.newName, pkg.Fset.Position(existing.Pos())))
+				}
+			}
+		}
+	}
+
+	// Uses of the new name within the scope that refer to an enclosing scope would refer to the renamed object instead.
+	for useIdent, useObj := range pkg.TypesInfo.Uses {
+		if useIdent.Name != r.newName || useObj.Parent() == nil || !isStrictAncestorScope(useObj.Parent(), scope) {
+			continue
+		}
+
+		if scope != pkg.Types.Scope() && useIdent.Pos() < obj.Pos() {
+			// Local declarations are visible only after they are declared.
+			continue
+		}
+
+		if useScope := pkg.Types.Scope().Innermost(useIdent.Pos()); useScope != nil && isAncestorScope(scope, useScope) {
+			r.addConflict(fileLocForIdent(pkg, useIdent), fmt.Sprintf("renamed %s would shadow the reference to %s", r.oldName, r.newName))
+		}
+	}
+}
+
+// checkFieldDefConflicts checks whether the new name conflicts with another field or method of the struct.
+func (r *renamer) checkFieldDefConflicts(pkg *packages.Package, ident *ast.Ident, loc file.Loc) {
+	astFile := astFileForLoc(pkg, loc)
+	if astFile == nil {
+		return
+	}
+
+	path, _ := astutil.PathEnclosingInterval(astFile, ident.Pos(), ident.End())
+	for _, node := range path {
+		switch node := node.(type) {
+		case *ast.TypeSpec:
+			// Methods on the named type can conflict with fields.
+			if typeObj := pkg.TypesInfo.Defs[node.Name]; typeObj != nil {
+				if existing, _, _ := types.LookupFieldOrMethod(typeObj.Type(), true, pkg.Types, r.newName); existing != nil {
+					r.addConflict(loc, fmt.Sprintf("%s already has a field or method named %s", typeObj.Name(), r.newName))
+				}
+			}
+			return
+
+		case *ast.StructType:
+			structType, ok := pkg.TypesInfo.TypeOf(node).(*types.Struct)
+			if !ok {
+				continue
+			}
+
+			for i := 0; i < structType.NumFields(); i++ {
+				if field := structType.Field(i); field.Name() == r.newName {
+					r.addConflict(loc, fmt.Sprintf("struct already has a field named %s at %s", r.newName, pkg.Fset.Position(field.Pos())))
+					return
+				}
+			}
+		}
+	}
+}
+
+// checkRefConflicts checks whether a reference would refer to a different object after the rename.
+func (r *renamer) checkRefConflicts(pkg *packages.Package, ident *ast.Ident, loc file.Loc) {
+	obj := pkg.TypesInfo.Uses[ident]
+	if obj == nil || obj.Pkg() == nil {
+		return
+	}
+
+	if !token.IsExported(r.newName) && obj.Pkg().Path() != pkg.Types.Path() {
+		r.addConflict(loc, fmt.Sprintf("%s is used outside package %s, so it must be exported", r.oldName, obj.Pkg().Name()))
+		return
+	}
+
+	astFile := astFileForLoc(pkg, loc)
+	if astFile == nil {
+		return
+	}
+
+	path, _ := astutil.PathEnclosingInterval(astFile, ident.Pos(), ident.End())
+	if len(path) > 1 {
+		if selExpr, ok := path[1].(*ast.SelectorExpr); ok && selExpr.Sel == ident {
+			if selection, ok := pkg.TypesInfo.Selections[selExpr]; ok {
+				// Renaming a field or method could select a different field or method with the new name.
+				existing, _, _ := types.LookupFieldOrMethod(selection.Recv(), true, pkg.Types, r.newName)
+				if existing != nil && !r.isRenamed(pkg, existing) {
+					r.addConflict(loc, fmt.Sprintf("%s already has a field or method named %s", types.TypeString(selection.Recv(), types.RelativeTo(pkg.Types)), r.newName))
+				}
+			}
+
+			// Qualified identifiers can't be shadowed.
+			return
+		}
+	}
+
+	scope := pkg.Types.Scope().Innermost(ident.Pos())
+	if scope == nil || obj.Parent() == nil {
+		return
+	}
+
+	if _, existing := scope.LookupParent(r.newName, ident.Pos()); existing != nil && existing.Parent() != nil && !isAncestorScope(existing.Parent(), obj.Parent()) {
+		r.addConflict(loc, fmt.Sprintf("reference to %s would refer to the %s declared at %s", r.oldName, r.newName, pkg.Fset.Position(existing.Pos())))
+	}
+}
+
+// isRenamed checks whether an object is declared at one of the renamed definitions.
+func (r *renamer) isRenamed(pkg *packages.Package, obj types.Object) bool {
+	if !obj.Pos().IsValid() {
+		return false
+	}
+
+	// Definition locations span the identifier, so compare only their starts.
+	start := fileLocForPos(pkg, obj.Pos())
+	for loc := range r.defLocs {
+		if loc.Start() == start {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *renamer) inSearchDir(loc file.Loc) bool {
+	return strings.HasPrefix(loc.Path, r.searchDir+string(filepath.Separator))
+}
+
+func (r *renamer) addConflict(loc file.Loc, reason string) {
+	r.conflicts[Conflict{Loc: loc, Reason: reason}] = struct{}{}
+}
+
+func (r *renamer) result() *Result {
+	result := &Result{
+		Edits:     make([]Edit, 0, len(r.editLocs)),
+		Conflicts: make([]Conflict, 0, len(r.conflicts)),
+	}
+
+	for loc := range r.editLocs {
+		result.Edits = append(result.Edits, Edit{Loc: loc, OldName: r.oldName, NewName: r.newName})
+	}
+
+	for c := range r.conflicts {
+		result.Conflicts = append(result.Conflicts, c)
+	}
+
+	sort.Slice(result.Edits, func(i, j int) bool {
+		return locLess(result.Edits[i].Loc, result.Edits[j].Loc)
+	})
+
+	sort.Slice(result.Conflicts, func(i, j int) bool {
+		if result.Conflicts[i].Loc != result.Conflicts[j].Loc {
+			return locLess(result.Conflicts[i].Loc, result.Conflicts[j].Loc)
+		}
+		return result.Conflicts[i].Reason < result.Conflicts[j].Reason
+	})
+
+	return result
+}
+
+// identAtLoc loads the package containing loc and finds the identifier that starts at loc.
+func (r *renamer) identAtLoc(loc file.Loc) (*packages.Package, *ast.Ident, error) {
+	pkg, err := r.loadPkgForPath(loc.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	astFile := astFileForLoc(pkg, loc)
+	if astFile == nil {
+		return nil, nil, fmt.Errorf("Could not find ast.File for %q", loc.Path)
+	}
+
+	var foundIdent *ast.Ident
+	ast.Inspect(astFile, func(node ast.Node) bool {
+		if foundIdent != nil || node == nil {
+			return false
+		}
+
+		if ident, ok := node.(*ast.Ident); ok && fileLocForPos(pkg, ident.Pos()) == loc.Start() {
+			foundIdent = ident
+		}
+
+		return true
+	})
+
+	if foundIdent == nil {
+		return nil, nil, fmt.Errorf("Could not find identifier at %s", loc)
+	}
+
+	return pkg, foundIdent, nil
+}
+
+// loadPkgForPath loads the package containing a Go file.
+// Packages are loaded with tests, and the test variant is preferred, since it can see declarations in test files.
+func (r *renamer) loadPkgForPath(path string) (*packages.Package, error) {
+	dir := filepath.Dir(path)
+	pkgs, ok := r.pkgsByDir[dir]
+	if !ok {
+		cfg := &packages.Config{
+			Mode: (packages.NeedName |
+				packages.NeedFiles |
+				packages.NeedSyntax |
+				packages.NeedImports |
+				packages.NeedTypes |
+				packages.NeedTypesInfo),
+			Dir:   dir,
+			Tests: true,
+			Env:   r.opts.Build.Env(),
+		}
+
+		var err error
+		pkgs, err = r.opts.Cache.Load(cfg, ".")
+		if err != nil {
+			return nil, fmt.Errorf("packages.Load: %w", err)
+		}
+		r.pkgsByDir[dir] = pkgs
+	}
+
+	var foundPkg *packages.Package
+	for _, pkg := range pkgs {
+		for _, goFilePath := range pkg.GoFiles {
+			if goFilePath == path && (foundPkg == nil || len(pkg.GoFiles) > len(foundPkg.GoFiles)) {
+				foundPkg = pkg
+			}
+		}
+	}
+
+	if foundPkg == nil {
+		return nil, fmt.Errorf("Could not find Go package for path %q", path)
+	}
+
+	return foundPkg, nil
+}
+
+func isMethod(obj types.Object) bool {
+	funcObj, ok := obj.(*types.Func)
+	return ok && funcObj.Type().(*types.Signature).Recv() != nil
+}
+
+// isAncestorScope checks whether a scope is the same as or encloses another scope.
+func isAncestorScope(ancestor *types.Scope, scope *types.Scope) bool {
+	for s := scope; s != nil; s = s.Parent() {
+		if s == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func isStrictAncestorScope(ancestor *types.Scope, scope *types.Scope) bool {
+	return ancestor != scope && isAncestorScope(ancestor, scope)
+}
+
+func astFileForLoc(pkg *packages.Package, loc file.Loc) *ast.File {
+	for _, astFile := range pkg.Syntax {
+		if pkg.Fset.Position(astFile.Pos()).Filename == loc.Path {
+			return astFile
+		}
+	}
+	return nil
+}
+
+func fileLocForPos(pkg *packages.Package, pos token.Pos) file.Loc {
+	position := pkg.Fset.Position(pos)
+	return file.Loc{
+		Path:   position.Filename,
+		Line:   position.Line,
+		Column: position.Column,
+	}
+}
+
+// fileLocForIdent returns the range of an identifier, like the locations of relations.
+func fileLocForIdent(pkg *packages.Package, ident *ast.Ident) file.Loc {
+	return file.LocForPositions(pkg.Fset.Position(ident.Pos()), pkg.Fset.Position(ident.End()))
+}
+
+func locLess(a, b file.Loc) bool {
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	} else if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
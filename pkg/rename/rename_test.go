@@ -0,0 +1,238 @@
+package rename
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/wedaly/gospelunk/pkg/file"
+)
+
+func TestRename(t *testing.T) {
+	testCases := []struct {
+		name              string
+		loc               file.Loc
+		newName           string
+		expectedEdits     []file.Loc
+		expectedConflicts []Conflict
+	}{
+		{
+			name:    "method with interface and other implementations",
+			loc:     file.Loc{Path: "testdata/testmodule001/shapes/shapes.go", Line: 11, Column: 17},
+			newName: "Size",
+			expectedEdits: []file.Loc{
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 13, Column: 34, Offset: 202, End: file.Pos{Line: 13, Column: 38, Offset: 206}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 4, Column: 2, Offset: 40, End: file.Pos{Line: 4, Column: 6, Offset: 44}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 11, Column: 17, Offset: 112, End: file.Pos{Line: 11, Column: 21, Offset: 116}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 19, Column: 18, Offset: 213, End: file.Pos{Line: 19, Column: 22, Offset: 217}},
+			},
+		},
+		{
+			name:    "method conflicts with method on another implementation",
+			loc:     file.Loc{Path: "testdata/testmodule001/main.go", Line: 13, Column: 34},
+			newName: "Perimeter",
+			expectedEdits: []file.Loc{
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 13, Column: 34, Offset: 202, End: file.Pos{Line: 13, Column: 38, Offset: 206}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 4, Column: 2, Offset: 40, End: file.Pos{Line: 4, Column: 6, Offset: 44}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 11, Column: 17, Offset: 112, End: file.Pos{Line: 11, Column: 21, Offset: 116}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 19, Column: 18, Offset: 213, End: file.Pos{Line: 19, Column: 22, Offset: 217}},
+			},
+			expectedConflicts: []Conflict{
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 19, Column: 18, Offset: 213, End: file.Pos{Line: 19, Column: 22, Offset: 217}},
+					Reason: "*Circle already has a field or method named Perimeter",
+				},
+			},
+		},
+		{
+			name:    "package variable referenced in test",
+			loc:     file.Loc{Path: "testdata/testmodule001/main.go", Line: 12, Column: 2},
+			newName: "total",
+			expectedEdits: []file.Loc{
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 9, Column: 5, Offset: 110, End: file.Pos{Line: 9, Column: 10, Offset: 115}},
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 12, Column: 2, Offset: 161, End: file.Pos{Line: 12, Column: 7, Offset: 166}},
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 19, Column: 28, Offset: 300, End: file.Pos{Line: 19, Column: 33, Offset: 305}},
+				{Path: absPath(t, "testdata/testmodule001/main_test.go"), Line: 10, Column: 2, Offset: 145, End: file.Pos{Line: 10, Column: 7, Offset: 150}},
+				{Path: absPath(t, "testdata/testmodule001/main_test.go"), Line: 12, Column: 5, Offset: 193, End: file.Pos{Line: 12, Column: 10, Offset: 198}},
+			},
+		},
+		{
+			name:    "package variable shadowed by local variable",
+			loc:     file.Loc{Path: "testdata/testmodule001/main.go", Line: 9, Column: 5},
+			newName: "size",
+			expectedEdits: []file.Loc{
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 9, Column: 5, Offset: 110, End: file.Pos{Line: 9, Column: 10, Offset: 115}},
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 12, Column: 2, Offset: 161, End: file.Pos{Line: 12, Column: 7, Offset: 166}},
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 19, Column: 28, Offset: 300, End: file.Pos{Line: 19, Column: 33, Offset: 305}},
+				{Path: absPath(t, "testdata/testmodule001/main_test.go"), Line: 10, Column: 2, Offset: 145, End: file.Pos{Line: 10, Column: 7, Offset: 150}},
+				{Path: absPath(t, "testdata/testmodule001/main_test.go"), Line: 12, Column: 5, Offset: 193, End: file.Pos{Line: 12, Column: 10, Offset: 198}},
+			},
+			expectedConflicts: []Conflict{
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 19, Column: 28, Offset: 300, End: file.Pos{Line: 19, Column: 33, Offset: 305}},
+					Reason: "reference to count would refer to the size declared at " + absPath(t, "testdata/testmodule001/main.go") + ":17:2",
+				},
+			},
+		},
+		{
+			name:    "local variable shadows import",
+			loc:     file.Loc{Path: "testdata/testmodule001/main.go", Line: 17, Column: 2},
+			newName: "fmt",
+			expectedEdits: []file.Loc{
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 17, Column: 2, Offset: 228, End: file.Pos{Line: 17, Column: 6, Offset: 232}},
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 18, Column: 28, Offset: 267, End: file.Pos{Line: 18, Column: 32, Offset: 271}},
+			},
+			expectedConflicts: []Conflict{
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 19, Column: 2, Offset: 274, End: file.Pos{Line: 19, Column: 5, Offset: 277}},
+					Reason: "renamed size would shadow the reference to fmt",
+				},
+			},
+		},
+		{
+			name:    "function conflicts with package declaration",
+			loc:     file.Loc{Path: "testdata/testmodule001/main.go", Line: 11, Column: 6},
+			newName: "main",
+			expectedEdits: []file.Loc{
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 11, Column: 6, Offset: 126, End: file.Pos{Line: 11, Column: 14, Offset: 134}},
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 19, Column: 14, Offset: 286, End: file.Pos{Line: 19, Column: 22, Offset: 294}},
+				{Path: absPath(t, "testdata/testmodule001/main_test.go"), Line: 11, Column: 2, Offset: 156, End: file.Pos{Line: 11, Column: 10, Offset: 164}},
+			},
+			expectedConflicts: []Conflict{
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 11, Column: 6, Offset: 126, End: file.Pos{Line: 11, Column: 14, Offset: 134}},
+					Reason: "main is already declared in this scope at " + absPath(t, "testdata/testmodule001/main.go") + ":16:6",
+				},
+			},
+		},
+		{
+			name:    "unexported field used in other package",
+			loc:     file.Loc{Path: "testdata/testmodule001/shapes/shapes.go", Line: 8, Column: 2},
+			newName: "side",
+			expectedEdits: []file.Loc{
+				{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 18, Column: 22, Offset: 261, End: file.Pos{Line: 18, Column: 26, Offset: 265}},
+				{Path: absPath(t, "testdata/testmodule001/main_test.go"), Line: 11, Column: 25, Offset: 179, End: file.Pos{Line: 11, Column: 29, Offset: 183}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 8, Column: 2, Offset: 80, End: file.Pos{Line: 8, Column: 6, Offset: 84}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 12, Column: 11, Offset: 139, End: file.Pos{Line: 12, Column: 15, Offset: 143}},
+				{Path: absPath(t, "testdata/testmodule001/shapes/shapes.go"), Line: 12, Column: 20, Offset: 148, End: file.Pos{Line: 12, Column: 24, Offset: 152}},
+			},
+			expectedConflicts: []Conflict{
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule001/main.go"), Line: 18, Column: 22, Offset: 261, End: file.Pos{Line: 18, Column: 26, Offset: 265}},
+					Reason: "Side is used outside package shapes, so it must be exported",
+				},
+				{
+					Loc:    file.Loc{Path: absPath(t, "testdata/testmodule001/main_test.go"), Line: 11, Column: 25, Offset: 179, End: file.Pos{Line: 11, Column: 29, Offset: 183}},
+					Reason: "Side is used outside package shapes, so it must be exported",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Rename(tc.loc, "testdata/testmodule001", tc.newName, Options{})
+			require.NoError(t, err)
+
+			editLocs := make([]file.Loc, 0, len(result.Edits))
+			for _, edit := range result.Edits {
+				editLocs = append(editLocs, edit.Loc)
+			}
+			assert.Equal(t, tc.expectedEdits, editLocs)
+
+			if tc.expectedConflicts == nil {
+				tc.expectedConflicts = []Conflict{}
+			}
+			assert.Equal(t, tc.expectedConflicts, result.Conflicts)
+		})
+	}
+}
+
+func TestRenameInvalidIdentifier(t *testing.T) {
+	loc := file.Loc{Path: "testdata/testmodule001/main.go", Line: 9, Column: 5}
+	_, err := Rename(loc, "testdata/testmodule001", "func", Options{})
+	assert.EqualError(t, err, `Invalid identifier "func"`)
+}
+
+func TestRenameDiff(t *testing.T) {
+	loc := file.Loc{Path: "testdata/testmodule001/main.go", Line: 17, Column: 2}
+	result, err := Rename(loc, "testdata/testmodule001", "length", Options{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = result.Diff(&buf, func(path string) string {
+		relPath, err := filepath.Rel(absPath(t, "testdata/testmodule001"), path)
+		require.NoError(t, err)
+		return relPath
+	})
+	require.NoError(t, err)
+
+	expected := `--- a/main.go
++++ b/main.go
+@@ -14,7 +14,7 @@
+ }
+ 
+ func main() {
+-	size := 2.0
+-	sq := shapes.Square{Side: size}
++	length := 2.0
++	sq := shapes.Square{Side: length}
+ 	fmt.Println(describe(sq), count)
+ }
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestRenameApply(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.CopyFS(dir, os.DirFS("testdata/testmodule001")))
+
+	loc := file.Loc{Path: filepath.Join(dir, "main.go"), Line: 9, Column: 5}
+	result, err := Rename(loc, dir, "total", Options{})
+	require.NoError(t, err)
+	require.Empty(t, result.Conflicts)
+	require.NoError(t, result.Apply())
+
+	mainData, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(mainData), "var total = 0\n")
+	assert.Contains(t, string(mainData), "fmt.Println(describe(sq), total)\n")
+	assert.NotContains(t, string(mainData), "count")
+
+	testData, err := os.ReadFile(filepath.Join(dir, "main_test.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(testData), "if total != 1 {\n")
+
+	// Applying the same edits again fails, since the files no longer contain the old name.
+	assert.Error(t, result.Apply())
+}
+
+func TestRenameIsRenamed(t *testing.T) {
+	path := absPath(t, "testdata/testmodule001/shapes/shapes.go")
+	r := &renamer{pkgsByDir: make(map[string][]*packages.Package)}
+	pkg, ident, err := r.identAtLoc(file.Loc{Path: path, Line: 11, Column: 17})
+	require.NoError(t, err)
+
+	obj := pkg.TypesInfo.Defs[ident]
+	require.NotNil(t, obj)
+
+	r.defLocs = map[file.Loc]struct{}{
+		{Path: path, Line: 11, Column: 17, Offset: 112, End: file.Pos{Line: 11, Column: 21, Offset: 116}}: {},
+	}
+	assert.True(t, r.isRenamed(pkg, obj))
+
+	otherObj := pkg.Types.Scope().Lookup("Circle")
+	require.NotNil(t, otherObj)
+	assert.False(t, r.isRenamed(pkg, otherObj))
+}
+
+func absPath(t *testing.T, p string) string {
+	absPath, err := filepath.Abs(p)
+	require.NoError(t, err)
+	return absPath
+}
@@ -0,0 +1,3 @@
+module github.com/wedaly/gospelunk/pkg/rename/testdata/testmodule001
+
+go 1.19
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wedaly/gospelunk/pkg/rename/testdata/testmodule001/shapes"
+)
+
+var count = 0
+
+func describe(s shapes.Shape) string {
+	count++
+	return fmt.Sprintf("area %f", s.Area())
+}
+
+func main() {
+	size := 2.0
+	sq := shapes.Square{Side: size}
+	fmt.Println(describe(sq), count)
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/wedaly/gospelunk/pkg/rename/testdata/testmodule001/shapes"
+)
+
+func TestDescribe(t *testing.T) {
+	count = 0
+	describe(shapes.Square{Side: 1})
+	if count != 1 {
+		t.Fail()
+	}
+}
@@ -0,0 +1,218 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/wedaly/gospelunk/pkg/list"
+)
+
+type Options struct {
+	// Kinds restricts matches to definitions of these kinds. If empty, all kinds match.
+	Kinds []list.DefKind
+
+	// Pkg restricts matches to a package, specified by either its name or import path.
+	// If empty, definitions from every package match.
+	Pkg string
+
+	// Limit is the maximum number of matches to return. If zero, all matches are returned.
+	Limit int
+}
+
+type Result struct {
+	Matches []Match `json:"matches"`
+}
+
+type Match struct {
+	list.Definition
+	Score int `json:"score"`
+}
+
+// Search ranks definitions by how closely their names match a fuzzy query.
+// Definitions that don't match every character of the query are excluded.
+func Search(query string, defs []list.Definition, opts Options) Result {
+	var result Result
+	for _, def := range defs {
+		if !matchesKinds(def, opts.Kinds) || !matchesPkg(def, opts.Pkg) {
+			continue
+		}
+
+		if score, ok := Score(query, def.Name); ok {
+			result.Matches = append(result.Matches, Match{Definition: def, Score: score})
+		}
+	}
+
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		} else if len(a.Name) != len(b.Name) {
+			return len(a.Name) < len(b.Name)
+		} else if a.Name != b.Name {
+			return a.Name < b.Name
+		} else if a.Path != b.Path {
+			return a.Path < b.Path
+		} else {
+			return a.Line < b.Line
+		}
+	})
+
+	if opts.Limit > 0 && len(result.Matches) > opts.Limit {
+		result.Matches = result.Matches[:opts.Limit]
+	}
+
+	return result
+}
+
+func matchesKinds(def list.Definition, kinds []list.DefKind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if def.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPkg(def list.Definition, pkg string) bool {
+	if pkg == "" {
+		return true
+	}
+
+	// Test packages have IDs like "example.com/foo [example.com/foo.test]", so ignore the suffix.
+	pkgPath, _, _ := strings.Cut(def.Pkg.ID, " ")
+	return def.Pkg.Name == pkg || pkgPath == pkg || strings.HasSuffix(pkgPath, "/"+pkg)
+}
+
+// Scoring weights for fuzzy matching.
+const (
+	scoreMatch            = 1
+	scoreCaseMatch        = 1
+	scoreSegmentStart     = 8
+	scoreFirstChar        = 4
+	scoreConsecutive      = 4
+	scoreExactNameMatch   = 20
+	penaltyPerSkippedChar = 2
+)
+
+// noMatch marks positions in the scoring table where the query can't match.
+const noMatch = math.MinInt32
+
+// Score computes how well a fuzzy query matches a definition name.
+// Every character of the query must appear in the name in order (ignoring case),
+// but the characters do not need to be adjacent. Matches at the start of segments
+// (camel-case words, or the parts of a name separated by "." or "_") score higher,
+// so a query like "srvCfg" matches "ServerConfig".
+// The second return value is false if the query doesn't match.
+func Score(query string, name string) (int, bool) {
+	q := []rune(query)
+	n := []rune(name)
+
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	if len(q) > len(n) {
+		return 0, false
+	}
+
+	segmentStarts := findSegmentStarts(n)
+
+	// best[i][j] is the best score for matching q[:i+1] where q[i] matches n[j],
+	// or noMatch if there is no such match.
+	best := make([][]int, len(q))
+	for i := range q {
+		best[i] = make([]int, len(n))
+		for j := range n {
+			best[i][j] = noMatch
+			if !runesEqualFold(q[i], n[j]) {
+				continue
+			}
+
+			charScore := scoreMatch
+			if q[i] == n[j] {
+				charScore += scoreCaseMatch
+			}
+			if segmentStarts[j] {
+				charScore += scoreSegmentStart
+			}
+
+			if i == 0 {
+				if j == 0 {
+					charScore += scoreFirstChar
+				}
+				best[i][j] = charScore
+				continue
+			}
+
+			for k := i - 1; k < j; k++ {
+				if best[i-1][k] == noMatch {
+					continue
+				}
+
+				s := best[i-1][k] + charScore
+				if k == j-1 {
+					s += scoreConsecutive
+				} else {
+					s -= penaltyPerSkippedChar * (j - k - 1)
+				}
+
+				if s > best[i][j] {
+					best[i][j] = s
+				}
+			}
+		}
+	}
+
+	score := noMatch
+	for _, s := range best[len(q)-1] {
+		if s > score {
+			score = s
+		}
+	}
+
+	if score == noMatch {
+		return 0, false
+	}
+
+	lastSegment := name[strings.LastIndex(name, ".")+1:]
+	if strings.EqualFold(query, name) || strings.EqualFold(query, lastSegment) {
+		score += scoreExactNameMatch
+	}
+
+	return score, true
+}
+
+// findSegmentStarts marks the first rune of each segment in a name.
+// Segments start at the beginning of the name, after "." or "_", at camel-case humps ("fooBar"),
+// at the end of an acronym ("HTTPServer"), and at the first digit in a run of digits.
+func findSegmentStarts(name []rune) []bool {
+	starts := make([]bool, len(name))
+	for i, r := range name {
+		if i == 0 {
+			starts[i] = true
+			continue
+		}
+
+		prev := name[i-1]
+		switch {
+		case prev == '.' || prev == '_':
+			starts[i] = true
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			starts[i] = true
+		case unicode.IsUpper(r) && unicode.IsUpper(prev) && i+1 < len(name) && unicode.IsLower(name[i+1]):
+			starts[i] = true
+		case unicode.IsDigit(r) && !unicode.IsDigit(prev):
+			starts[i] = true
+		}
+	}
+	return starts
+}
+
+func runesEqualFold(a, b rune) bool {
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
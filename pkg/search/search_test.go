@@ -0,0 +1,129 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wedaly/gospelunk/pkg/list"
+)
+
+func TestScore(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       string
+		defName     string
+		expectMatch bool
+	}{
+		{name: "empty query", query: "", defName: "ServerConfig", expectMatch: true},
+		{name: "exact match", query: "ServerConfig", defName: "ServerConfig", expectMatch: true},
+		{name: "camel case segments", query: "srvCfg", defName: "ServerConfig", expectMatch: true},
+		{name: "case insensitive", query: "SERVERCONFIG", defName: "ServerConfig", expectMatch: true},
+		{name: "dotted name", query: "cfgaddr", defName: "Config.Addr", expectMatch: true},
+		{name: "out of order", query: "cfgSrv", defName: "ServerConfig", expectMatch: false},
+		{name: "missing char", query: "srvx", defName: "ServerConfig", expectMatch: false},
+		{name: "query longer than name", query: "ServerConfigs", defName: "ServerConfig", expectMatch: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := Score(tc.query, tc.defName)
+			assert.Equal(t, tc.expectMatch, ok)
+		})
+	}
+}
+
+func TestScorePrefersSegmentMatches(t *testing.T) {
+	segmentScore, ok := Score("srvCfg", "ServerConfig")
+	require.True(t, ok)
+
+	scatteredScore, ok := Score("srvCfg", "SurveyOfCountingFrogs")
+	require.True(t, ok)
+
+	assert.Greater(t, segmentScore, scatteredScore)
+}
+
+func TestScorePrefersExactMatches(t *testing.T) {
+	exactScore, ok := Score("config", "Server.Config")
+	require.True(t, ok)
+
+	prefixScore, ok := Score("config", "Server.ConfigLoader")
+	require.True(t, ok)
+
+	assert.Greater(t, exactScore, prefixScore)
+}
+
+func TestSearch(t *testing.T) {
+	serverPkg := list.Package{Name: "server", ID: "example.com/app/server"}
+	clientPkg := list.Package{Name: "client", ID: "example.com/app/client"}
+	testPkg := list.Package{Name: "client", ID: "example.com/app/client [example.com/app/client.test]"}
+
+	defs := []list.Definition{
+		{Name: "ServerConfig", Kind: list.DefKindType, Pkg: serverPkg},
+		{Name: "ServerConfig.Addr", Kind: list.DefKindField, Pkg: serverPkg},
+		{Name: "NewServerConfig", Kind: list.DefKindFunc, Pkg: serverPkg},
+		{Name: "SurveyOfCountingFrogs", Kind: list.DefKindVar, Pkg: clientPkg},
+		{Name: "ClientConfig", Kind: list.DefKindType, Pkg: clientPkg},
+		{Name: "ServerConfigForTest", Kind: list.DefKindVar, Pkg: testPkg},
+	}
+
+	matchNames := func(result Result) []string {
+		var names []string
+		for _, m := range result.Matches {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "rank all matches",
+			query:    "srvCfg",
+			opts:     Options{},
+			expected: []string{"ServerConfig", "ServerConfig.Addr", "ServerConfigForTest", "NewServerConfig", "SurveyOfCountingFrogs"},
+		},
+		{
+			name:     "filter by kind",
+			query:    "srvCfg",
+			opts:     Options{Kinds: []list.DefKind{list.DefKindFunc, list.DefKindField}},
+			expected: []string{"ServerConfig.Addr", "NewServerConfig"},
+		},
+		{
+			name:     "filter by package name",
+			query:    "cfg",
+			opts:     Options{Pkg: "client"},
+			expected: []string{"ClientConfig", "ServerConfigForTest", "SurveyOfCountingFrogs"},
+		},
+		{
+			name:     "filter by package path",
+			query:    "cfg",
+			opts:     Options{Pkg: "example.com/app/server"},
+			expected: []string{"ServerConfig", "NewServerConfig", "ServerConfig.Addr"},
+		},
+		{
+			name:     "limit",
+			query:    "srvCfg",
+			opts:     Options{Limit: 2},
+			expected: []string{"ServerConfig", "ServerConfig.Addr"},
+		},
+		{
+			name:     "no matches",
+			query:    "xyz",
+			opts:     Options{},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Search(tc.query, defs, tc.opts)
+			assert.Equal(t, tc.expected, matchNames(result))
+		})
+	}
+}
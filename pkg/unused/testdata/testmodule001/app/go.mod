@@ -0,0 +1,7 @@
+module github.com/wedaly/gospelunk/pkg/unused/testdata/testmodule001/app
+
+go 1.19
+
+require github.com/wedaly/gospelunk/pkg/unused/testdata/testmodule001 v0.0.0
+
+replace github.com/wedaly/gospelunk/pkg/unused/testdata/testmodule001 => ../
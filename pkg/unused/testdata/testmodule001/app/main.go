@@ -0,0 +1,11 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wedaly/gospelunk/pkg/unused/testdata/testmodule001/shapes"
+)
+
+func main() {
+	fmt.Println(shapes.UsedByApp().Area())
+}
@@ -0,0 +1,3 @@
+module github.com/wedaly/gospelunk/pkg/unused/testdata/testmodule001
+
+go 1.19
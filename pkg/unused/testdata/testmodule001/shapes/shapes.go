@@ -0,0 +1,40 @@
+package shapes
+
+import "fmt"
+
+type Shape interface {
+	Area() int
+}
+
+type Square struct {
+	Side  int
+	Color string
+}
+
+func NewSquare(side int) Square {
+	return Square{Side: side}
+}
+
+func (s Square) Area() int {
+	return s.Side * s.Side
+}
+
+func (s Square) String() string {
+	return fmt.Sprintf("square with side %d", s.Side)
+}
+
+func (s Square) Scale(n int) Square {
+	return Square{Side: s.Side * n}
+}
+
+func Legacy() {}
+
+func UsedByApp() Shape {
+	return NewSquare(1)
+}
+
+func describe(s Square) string {
+	return s.String()
+}
+
+func init() {}
@@ -0,0 +1,15 @@
+package shapes
+
+import "testing"
+
+func TestNewSquare(t *testing.T) {
+	assertSide(t, NewSquare(2), 2)
+}
+
+func assertSide(t *testing.T, s Square, side int) {
+	if s.Side != side {
+		t.Fail()
+	}
+}
+
+func unusedHelper() {}
@@ -0,0 +1,199 @@
+package unused
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wedaly/gospelunk/pkg/buildcfg"
+	"github.com/wedaly/gospelunk/pkg/cache"
+	"github.com/wedaly/gospelunk/pkg/index"
+	"github.com/wedaly/gospelunk/pkg/inspect"
+	"github.com/wedaly/gospelunk/pkg/list"
+)
+
+type Options struct {
+	IncludeStructFields bool
+	IncludePrivate      bool
+
+	// ExcludeTestHelpers skips definitions in test files.
+	// Test, benchmark, fuzz, and example functions are always skipped, since `go test` calls them.
+	ExcludeTestHelpers bool
+
+	// Allowlist contains patterns for definitions that are part of a public API, so they're never reported.
+	// Each pattern is matched against the import path and name of a definition, like "example.com/pkg.Type.Method",
+	// using the syntax of path.Match.
+	Allowlist []string
+
+	// Dir is the directory used to resolve package patterns.
+	// If empty, patterns are resolved relative to the current working directory.
+	Dir string
+
+	// Build is the build configuration used to load packages.
+	Build buildcfg.Config
+
+	// Cache reuses loaded packages across the list and use site searches.
+	// If nil, a new cache is used for each call to Unused.
+	Cache *cache.Cache `json:"-"`
+
+	// Index stores package metadata on disk.
+	// If nil, package metadata is loaded from scratch.
+	Index *index.Index `json:"-"`
+}
+
+type Result struct {
+	Defs []list.Definition `json:"defs"`
+}
+
+// Unused finds definitions in packages matching patterns that aren't referenced anywhere in searchDir (including tests).
+// Methods that implement an interface, main and init functions, and definitions matching the allowlist are skipped.
+func Unused(patterns []string, searchDir string, opts Options) (Result, error) {
+	var result Result
+
+	for _, pattern := range opts.Allowlist {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return result, fmt.Errorf("Invalid allowlist pattern %q", pattern)
+		}
+	}
+
+	if opts.Cache == nil {
+		opts.Cache = cache.New()
+	}
+
+	listResult, err := list.List(patterns, list.Options{
+		IncludeStructFields: opts.IncludeStructFields,
+		IncludePrivate:      opts.IncludePrivate,
+		IncludeTests:        !opts.ExcludeTestHelpers,
+		Dir:                 opts.Dir,
+		Build:               opts.Build,
+		Cache:               opts.Cache,
+		Index:               opts.Index,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	useSites, err := inspect.LoadUseSites(searchDir, inspect.Options{
+		Build: opts.Build,
+		Cache: opts.Cache,
+		Index: opts.Index,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, def := range listResult.Defs {
+		if isTestFile(def.Path) && (opts.ExcludeTestHelpers || isTestFunc(def)) {
+			continue
+		}
+
+		if isEntryPoint(def) || def.Name == "_" || isAllowlisted(def, opts.Allowlist) {
+			continue
+		}
+
+		// List reports the position of the declaration, which is on the same line as the name.
+		name := shortName(def.Name)
+		if useSites.IsUsed(def.Path, def.Line, name) {
+			continue
+		}
+
+		if def.Kind == list.DefKindMethod && useSites.IsIfaceImpl(def.Path, def.Line, name) {
+			continue
+		}
+
+		result.Defs = append(result.Defs, def)
+	}
+
+	return result, nil
+}
+
+// ReadAllowlist reads allowlist patterns from a file, one per line.
+// Blank lines and lines starting with "#" are ignored.
+func ReadAllowlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err: %w", err)
+	}
+
+	return patterns, nil
+}
+
+func isAllowlisted(def list.Definition, allowlist []string) bool {
+	qualifiedName := fmt.Sprintf("%s.%s", pkgPathForDef(def), def.Name)
+	for _, pattern := range allowlist {
+		if ok, _ := path.Match(pattern, qualifiedName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pkgPathForDef returns the import path of the package containing a definition.
+// Packages compiled for tests have IDs like "example.com/pkg [example.com/pkg.test]" or "example.com/pkg_test [example.com/pkg.test]".
+func pkgPathForDef(def list.Definition) string {
+	pkgPath, _, _ := strings.Cut(def.Pkg.ID, " ")
+	return strings.TrimSuffix(pkgPath, "_test")
+}
+
+// isEntryPoint checks whether a definition is called by the Go runtime.
+func isEntryPoint(def list.Definition) bool {
+	if def.Kind != list.DefKindFunc {
+		return false
+	}
+	return def.Name == "init" || (def.Name == "main" && def.Pkg.Name == "main")
+}
+
+func isTestFile(path string) bool {
+	return strings.HasSuffix(filepath.Base(path), "_test.go")
+}
+
+// isTestFunc checks whether a definition is a function called by `go test`,
+// like TestFoo, BenchmarkFoo, FuzzFoo, or ExampleFoo (but not Testfoo).
+func isTestFunc(def list.Definition) bool {
+	if def.Kind != list.DefKindFunc {
+		return false
+	}
+
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+		suffix, ok := strings.CutPrefix(def.Name, prefix)
+		if !ok {
+			continue
+		}
+
+		if suffix == "" {
+			return true
+		}
+
+		r, _ := utf8.DecodeRuneInString(suffix)
+		return !unicode.IsLower(r)
+	}
+
+	return false
+}
+
+func shortName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
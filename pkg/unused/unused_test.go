@@ -0,0 +1,73 @@
+package unused
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnused(t *testing.T) {
+	testCases := []struct {
+		name          string
+		opts          Options
+		expectedNames []string
+	}{
+		{
+			name:          "exported definitions",
+			opts:          Options{},
+			expectedNames: []string{"Square.Scale", "Legacy"},
+		},
+		{
+			name: "allowlist",
+			opts: Options{
+				Allowlist: []string{"github.com/wedaly/gospelunk/pkg/unused/testdata/testmodule001/shapes.Legacy"},
+			},
+			expectedNames: []string{"Square.Scale"},
+		},
+		{
+			name: "allowlist glob",
+			opts: Options{
+				Allowlist: []string{"github.com/wedaly/gospelunk/pkg/unused/testdata/testmodule001/shapes.*"},
+			},
+			expectedNames: nil,
+		},
+		{
+			name:          "include private",
+			opts:          Options{IncludePrivate: true},
+			expectedNames: []string{"Square.Scale", "Legacy", "describe", "unusedHelper"},
+		},
+		{
+			name:          "exclude test helpers",
+			opts:          Options{IncludePrivate: true, ExcludeTestHelpers: true},
+			expectedNames: []string{"Square.Scale", "Legacy", "describe"},
+		},
+		{
+			name:          "include struct fields",
+			opts:          Options{IncludeStructFields: true},
+			expectedNames: []string{"Square.Color", "Square.Scale", "Legacy"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Dir = "testdata/testmodule001"
+			result, err := Unused([]string{"./..."}, "testdata/testmodule001", tc.opts)
+			require.NoError(t, err)
+
+			var names []string
+			for _, def := range result.Defs {
+				names = append(names, def.Name)
+			}
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestUnusedInvalidAllowlistPattern(t *testing.T) {
+	_, err := Unused([]string{"./..."}, "testdata/testmodule001", Options{
+		Dir:       "testdata/testmodule001",
+		Allowlist: []string{"["},
+	})
+	assert.EqualError(t, err, `Invalid allowlist pattern "["`)
+}